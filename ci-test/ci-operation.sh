@@ -15,8 +15,10 @@
 
 usage() {
     echo "usage: ./ci-operation.sh [action] [target]"
-    echo "  - pull: remove the existed free5gc repo under base/ and clone a new free5gc with its NFs"
-    echo "  - fetch [NF] [PR#]: fetch the target NF's PR"
+    echo "  - pull [REF]: remove the existed free5gc repo under base/ and clone a new free5gc with its NFs,"
+    echo "                then check out REF (a release tag, branch or commit) if given"
+    echo "  - fetch [NF] [PR#] [SHA]: fetch the target NF's PR, and check out exactly SHA if given"
+    echo "                            (exits 2 if SHA cannot be fetched)"
     echo "  - testAll: run all free5gc tests"
     echo "  - build: build the necessary images"
     echo "  - up <ulcl-ti | ulcl-mp>: bring up the compose"
@@ -26,7 +28,7 @@ usage() {
 }
 
 main() {
-    if [ $# -ne 1 ] && [ $# -ne 2 ] && [ $# -ne 3 ]; then
+    if [ $# -lt 1 ] || [ $# -gt 4 ]; then
         usage
     fi
 
@@ -34,13 +36,32 @@ main() {
         "pull")
             cd base
             rm -rf free5gc
-            git clone -j `nproc` --recursive https://github.com/free5gc/free5gc
+            git clone -j `nproc` --recursive https://github.com/free5gc/free5gc || exit 1
+            if [ -n "$2" ]; then
+                # NFs are submodules, so update them to the versions pinned by REF
+                cd free5gc
+                git -c advice.detachedHead=false checkout "$2" || { echo "Error: unknown ref $2"; exit 1; }
+                git submodule update --init --recursive -j `nproc` || exit 1
+                cd ..
+            fi
             cd ..
         ;;
         "fetch")
             cd base/free5gc/NFs/$2
-            git fetch origin pull/$3/head:pr-$3
-            git checkout pr-$3
+            git fetch origin +pull/$3/head:pr-$3 || exit 1
+            git checkout pr-$3 || exit 1
+            if [ -n "$4" ]; then
+                # the PR may have moved on since the task was queued, test the queued commit;
+                # after a force-push it is no longer in pull/N/head, so fetch it by SHA
+                if ! git cat-file -e "$4^{commit}" 2>/dev/null; then
+                    git fetch origin "$4" || { echo "Error: commit $4 is no longer available"; exit 2; }
+                fi
+                git -c advice.detachedHead=false checkout --detach "$4" || exit 1
+                if [ "$(git rev-parse HEAD)" != "$(git rev-parse "$4^{commit}")" ]; then
+                    echo "Error: checked out $(git rev-parse HEAD), expected $4"
+                    exit 1
+                fi
+            fi
             cd ../../../../
         ;;
         "testAll")
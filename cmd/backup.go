@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"web_test/internal/logger"
+	"web_test/pkg/backup"
+)
+
+// runExport 實作 `web_test export`：將所有資料匯出成一個備份檔
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	configPath := fs.String("c", "config.yml", "path to config file")
+	output := fs.String("o", fmt.Sprintf("web_test-backup-%s.zip", time.Now().Format("20060102-150405")), "archive file to write")
+	_, store, ok := openStoreFlags(fs, configPath, args)
+	if !ok {
+		return 1
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		logger.MainLog.Errorf("Failed to create %s: %v", *output, err)
+		return 1
+	}
+	defer file.Close()
+
+	manifest, err := backup.Export(context.Background(), store, file)
+	if err != nil {
+		logger.MainLog.Errorf("Export failed: %v", err)
+		os.Remove(*output)
+		return 1
+	}
+	logger.MainLog.Infof("Exported %d results, %d history records (task ID %d) to %s",
+		manifest.Results, manifest.History, manifest.TaskIDCounter, *output)
+	return 0
+}
+
+// runImport 實作 `web_test import <archive>`：還原備份檔，可重複執行
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	configPath := fs.String("c", "config.yml", "path to config file")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s import [flags] <archive.zip>\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_, store, ok := openStoreFlags(fs, configPath, args)
+	if !ok {
+		return 1
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	file, err := os.Open(fs.Arg(0))
+	if err != nil {
+		logger.MainLog.Errorf("Failed to open %s: %v", fs.Arg(0), err)
+		return 1
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		logger.MainLog.Errorf("Failed to stat %s: %v", fs.Arg(0), err)
+		return 1
+	}
+
+	manifest, err := backup.Import(context.Background(), store, file, info.Size())
+	if err != nil {
+		logger.MainLog.Errorf("Import failed: %v", err)
+		return 1
+	}
+	logger.MainLog.Infof("Imported %d results, %d history records (task ID %d) from %s",
+		manifest.Results, manifest.History, manifest.TaskIDCounter, fs.Arg(0))
+	return 0
+}
@@ -2,70 +2,71 @@ package main
 
 import (
 	"context"
-	"flag"
-	"net/http"
+	"fmt"
 	"os"
 	"os/signal"
-	"sync"
+	"strings"
 	"syscall"
 
-	"web_test/internal/logger"
-	"web_test/pkg/factory"
+	"web_test/internal/client"
 )
 
-func main() {
-	configPath := flag.String("c", "config.yml", "path to config file")
-	flag.Parse()
-
-	cfg, err := factory.ReadConfig(*configPath)
-	if err != nil {
-		logger.MainLog.Fatalf("Failed to load config: %+v", err)
-	}
-
-	f := factory.NewFactory(cfg)
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+const usage = `usage: web_test <command> [flags]
 
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+Service commands (share -c config.yml):
+  all               run the web server and the executor in one process (default)
+  serve             run only the web server
+  work              run only the executor
+  config validate   check the config file
+  migrate           run data migrations
+  gc                remove old task results, history and PR cache
+  export, import    back up and restore all data
+  version           print version information
 
-	// 初始化依賴
-	database := f.NewDB()
-	taskQueue := f.NewTaskQueue()
-	logger.MainLog.Info("Dependencies initialized")
+Client commands (talk to a running server, see --server and --token):
+  ` + "submit, queue, status, logs, cancel, download, history" + `
 
-	var wg sync.WaitGroup
+Run "web_test <command> -h" for the flags of a command.
+`
 
-	go func() {
-		sig := <-sigChan
-		logger.MainLog.Warnf("Received signal: %v, initiating shutdown...", sig)
-		cancel()
-	}()
-
-	// 啟動 Executor
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		exec := f.NewTaskExecutor(database, taskQueue)
-		logger.MainLog.Info("Executor started")
-		if err := exec.Start(ctx); err != nil && err != context.Canceled {
-			logger.MainLog.Errorf("Executor error: %v", err)
-		}
-		logger.MainLog.Info("Executor stopped")
-	}()
+func main() {
+	// 沒有子命令（或直接以 -c 開頭）時與舊版相同，同時執行 web server 與 executor
+	cmd, args := "all", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
 
-	// 啟動 Web Server
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		webServer := f.NewWebServer(database, taskQueue)
-		if err := webServer.Start(ctx); err != nil && err != http.ErrServerClosed {
-			logger.MainLog.Errorf("Server error: %v", err)
-		}
-		logger.MainLog.Info("Web server stopped")
-	}()
+	// 透過 REST API 操作任務的命令列工具
+	if client.IsCommand(cmd) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		code := client.Run(ctx, append([]string{cmd}, args...), os.Stdout, os.Stderr)
+		stop()
+		os.Exit(code)
+	}
 
-	wg.Wait()
-	logger.MainLog.Info("Application shutdown complete")
+	switch cmd {
+	case "all":
+		os.Exit(runRoles(cmd, args, roleWeb|roleWork))
+	case "serve":
+		os.Exit(runRoles(cmd, args, roleWeb))
+	case "work":
+		os.Exit(runRoles(cmd, args, roleWork))
+	case "config":
+		os.Exit(runConfig(args))
+	case "migrate":
+		os.Exit(runMigrate(args))
+	case "gc":
+		os.Exit(runGC(args))
+	case "export":
+		os.Exit(runExport(args))
+	case "import":
+		os.Exit(runImport(args))
+	case "version":
+		os.Exit(runVersion(args))
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
 }
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"web_test/internal/logger"
+	"web_test/pkg/database"
+	"web_test/pkg/factory"
+)
+
+// runConfig 實作 `web_test config validate`：檢查設定檔，有錯誤時逐項列出並回傳 1
+func runConfig(args []string) int {
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprintf(os.Stderr, "Usage: %s config validate [-c config.yml]\n", os.Args[0])
+		return 2
+	}
+	fs := flag.NewFlagSet("config validate", flag.ExitOnError)
+	configPath := fs.String("c", "config.yml", "path to config file")
+	fs.Parse(args[1:])
+
+	cfg, err := factory.ReadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *configPath, err)
+		return 1
+	}
+	errs := cfg.Validate()
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *configPath, err)
+	}
+	if len(errs) > 0 {
+		return 1
+	}
+	if cfg.WebServer.Auth.Enabled && cfg.WebServer.Auth.AdminToken == "" {
+		fmt.Fprintf(os.Stderr, "%s: warning: webserver.auth is enabled without admin_token\n", *configPath)
+	}
+	// 設定本身有效，但只能以 all 執行
+	for _, err := range cfg.ValidateSplit() {
+		fmt.Fprintf(os.Stderr, "%s: warning: only `all` can run with this config: %v\n", *configPath, err)
+	}
+	fmt.Printf("%s is valid\n", *configPath)
+	return 0
+}
+
+// openStore 依設定開啟 ResultStore 供維護指令使用；memory 後端只存在於服務行程內，無法從外部維護
+func openStore(name string, args []string) (*factory.Factory, database.ResultStore, bool) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	configPath := fs.String("c", "config.yml", "path to config file")
+	return openStoreFlags(fs, configPath, args)
+}
+
+func openStoreFlags(fs *flag.FlagSet, configPath *string, args []string) (*factory.Factory, database.ResultStore, bool) {
+	fs.Parse(args)
+	cfg, err := factory.ReadConfig(*configPath)
+	if err != nil {
+		logger.MainLog.Errorf("Failed to load config: %+v", err)
+		return nil, nil, false
+	}
+	if cfg.Database.Backend == factory.BackendMemory {
+		logger.MainLog.Errorf("`%s` needs a shared store, database.backend %s only lives inside the server process", fs.Name(), factory.BackendMemory)
+		return nil, nil, false
+	}
+	f := factory.NewFactory(cfg)
+	store, err := f.NewDBBackend(cfg.Database.Backend)
+	if err != nil {
+		logger.MainLog.Errorf("%v", err)
+		return nil, nil, false
+	}
+	return f, store, true
+}
+
+// runMigrate 實作 `web_test migrate`：執行所有資料遷移，可重複執行；serve 與 all 啟動時也會執行
+func runMigrate(args []string) int {
+	f, store, ok := openStore("migrate", args)
+	if !ok {
+		return 1
+	}
+	if err := f.MigrateStore(context.Background(), store); err != nil {
+		logger.MainLog.Errorf("Data migration failed: %v", err)
+		return 1
+	}
+	logger.MainLog.Info("Data migration complete")
+	return 0
+}
+
+// runGC 實作 `web_test gc`：清除超過保留期限的任務結果、歷史紀錄、PR 快取與稽核紀錄
+func runGC(args []string) int {
+	fs := flag.NewFlagSet("gc", flag.ExitOnError)
+	configPath := fs.String("c", "config.yml", "path to config file")
+	olderThan := fs.Duration("older-than", 90*24*time.Hour, "remove tasks that finished longer ago than this")
+	auditOlderThan := fs.Duration("audit-older-than", 365*24*time.Hour, "remove audit entries older than this, 0 keeps all")
+	dryRun := fs.Bool("dry-run", false, "only report what would be removed")
+	_, store, ok := openStoreFlags(fs, configPath, args)
+	if !ok {
+		return 1
+	}
+	if *olderThan <= 0 || *auditOlderThan < 0 {
+		logger.MainLog.Errorf("-older-than must be positive and -audit-older-than not negative, got %s and %s", *olderThan, *auditOlderThan)
+		return 2
+	}
+
+	now := time.Now()
+	before := now.Add(-*olderThan)
+	var auditBefore int64
+	if *auditOlderThan > 0 {
+		auditBefore = now.Add(-*auditOlderThan).Unix()
+	}
+	stats, err := database.CollectGarbage(context.Background(), store, before.Unix(), auditBefore, *dryRun)
+	if err != nil {
+		logger.MainLog.Errorf("gc failed: %v", err)
+		return 1
+	}
+	verb := "Removed"
+	if *dryRun {
+		verb = "Would remove"
+	}
+	logger.MainLog.Infof("%s %d results, %d history records and %d PR cache entries finished before %s, and %d audit entries",
+		verb, stats.Results, stats.History, stats.PrCache, before.Format(time.RFC3339), stats.Audit)
+	return 0
+}
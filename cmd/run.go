@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"web_test/internal/logger"
+	"web_test/pkg/factory"
+)
+
+// 行程要執行的角色
+const (
+	roleWeb  = 1 << iota // web server 與 REST API
+	roleWork             // executor：從佇列取出任務並執行 run_task.sh
+)
+
+// runRoles 實作 `web_test all|serve|work`：依角色啟動 web server 與 executor，收到 SIGINT/SIGTERM 時結束
+func runRoles(name string, args []string, roles int) int {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	configPath := fs.String("c", "config.yml", "path to config file")
+	fs.Parse(args)
+
+	cfg, err := factory.ReadConfig(*configPath)
+	if err != nil {
+		logger.MainLog.Errorf("Failed to load config: %+v", err)
+		return 1
+	}
+	f := factory.NewFactory(cfg)
+	// web server 與 executor 分開執行時只能經由 Redis 共用佇列與 ResultStore
+	if roles != roleWeb|roleWork {
+		if errs := cfg.ValidateSplit(); len(errs) > 0 {
+			for _, err := range errs {
+				logger.MainLog.Errorf("`%s`: %v", name, err)
+			}
+			return 1
+		}
+		logger.MainLog.Infof("Running only the %s role, queue, results and events are shared through Redis at %s", name, cfg.Redis.Addr)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	// 初始化依賴
+	database := f.NewDB()
+	taskQueue := f.NewTaskQueue()
+	if roles&roleWeb != 0 {
+		if err := f.MigrateStore(ctx, database); err != nil {
+			logger.MainLog.Errorf("Data migration failed: %v", err)
+		}
+	}
+	logger.MainLog.Info("Dependencies initialized")
+
+	var wg sync.WaitGroup
+
+	// queue.backend 為 redis 時經由 Redis 轉送事件，serve 與 work 分開執行時 /api/events 才收得到 executor 的事件
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		f.RunEventRelay(ctx)
+	}()
+
+	go func() {
+		sig := <-sigChan
+		logger.MainLog.Warnf("Received signal: %v, initiating shutdown...", sig)
+		cancel()
+	}()
+
+	if roles&roleWork != 0 {
+		// 先建立 executor，web server 的 /readyz 才能檢查它
+		exec := f.NewTaskExecutor(database, taskQueue)
+
+		// 啟動 Executor
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.MainLog.Info("Executor started")
+			if err := exec.Start(ctx); err != nil && err != context.Canceled {
+				logger.MainLog.Errorf("Executor error: %v", err)
+			}
+			logger.MainLog.Info("Executor stopped")
+		}()
+
+		// 只執行 executor 時另外提供健康檢查與指標
+		if roles&roleWeb == 0 {
+			if probe := f.NewProbeServer(database); probe != nil {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := probe.Start(ctx); err != nil && err != http.ErrServerClosed {
+						logger.MainLog.Errorf("Probe server error: %v", err)
+					}
+				}()
+			}
+		}
+	}
+
+	if roles&roleWeb != 0 {
+		// 啟動 Web Server
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			webServer := f.NewWebServer(database, taskQueue)
+			if err := webServer.Start(ctx); err != nil && err != http.ErrServerClosed {
+				logger.MainLog.Errorf("Server error: %v", err)
+			}
+			logger.MainLog.Info("Web server stopped")
+		}()
+	}
+
+	wg.Wait()
+	logger.MainLog.Info("Application shutdown complete")
+	return 0
+}
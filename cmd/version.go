@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// version 於建置時設定：go build -ldflags "-X main.version=v1.2.0" ./cmd
+var version = "dev"
+
+// runVersion 實作 `web_test version`：印出版本、建置時的 commit 與 Go 版本
+func runVersion(args []string) int {
+	commit := "unknown"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		var revision, modified string
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				revision = setting.Value
+			case "vcs.modified":
+				modified = setting.Value
+			}
+		}
+		if revision != "" {
+			commit = revision
+			if len(commit) > 12 {
+				commit = commit[:12]
+			}
+			if modified == "true" {
+				commit += "-dirty"
+			}
+		}
+	}
+	fmt.Printf("web_test %s (commit %s, %s %s/%s)\n", version, commit, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	return 0
+}
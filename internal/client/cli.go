@@ -0,0 +1,525 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"web_test/pkg/models"
+)
+
+// 命令列工具的結束碼；status、logs 與 submit --wait 依任務結果回傳
+const (
+	ExitOK         = 0 // 指令成功，或任務通過
+	ExitTaskFailed = 1 // 任務失敗（PR 造成或無法判斷）
+	ExitError      = 2 // 參數錯誤或 API 呼叫失敗
+	ExitInfra      = 3 // 任務失敗，release 版本也失敗（CI 環境問題）
+	ExitCancelled  = 4 // 任務被取消
+	ExitPending    = 5 // 任務尚未結束（排隊或執行中）
+)
+
+// 連線設定的環境變數，命令列參數優先
+const (
+	ServerEnv = "WEB_TEST_SERVER"
+	TokenEnv  = "WEB_TEST_TOKEN"
+)
+
+const defaultServer = "http://localhost:8080"
+
+// pollInterval 為等待任務結束時查詢的間隔
+var pollInterval = 3 * time.Second
+
+// Commands 為命令列工具的子命令名稱
+var Commands = []string{"submit", "queue", "status", "logs", "cancel", "download", "history"}
+
+// IsCommand 回報 name 是否為命令列工具的子命令
+func IsCommand(name string) bool {
+	for _, cmd := range Commands {
+		if cmd == name {
+			return true
+		}
+	}
+	return false
+}
+
+// cli 保存單次指令的輸出對象與共用參數
+type cli struct {
+	stdout, stderr io.Writer
+	server, token  string
+	json           bool
+}
+
+// Run 執行命令列子命令，args[0] 為子命令名稱，回傳結束碼
+func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || !IsCommand(args[0]) {
+		fmt.Fprintf(stderr, "usage: web_test <%s> [flags]\n", strings.Join(Commands, "|"))
+		return ExitError
+	}
+	c := &cli{stdout: stdout, stderr: stderr}
+	switch args[0] {
+	case "submit":
+		return c.submit(ctx, args[1:])
+	case "queue":
+		return c.queue(ctx, args[1:])
+	case "status":
+		return c.status(ctx, args[1:])
+	case "logs":
+		return c.logs(ctx, args[1:])
+	case "cancel":
+		return c.cancel(ctx, args[1:])
+	case "download":
+		return c.download(ctx, args[1:])
+	default:
+		return c.history(ctx, args[1:])
+	}
+}
+
+// flagSet 建立帶有 --server、--token 與 --json 的 FlagSet
+func (c *cli) flagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.StringVar(&c.server, "server", envOr(ServerEnv, defaultServer), "web_test server URL (env "+ServerEnv+")")
+	fs.StringVar(&c.token, "token", os.Getenv(TokenEnv), "API token (env "+TokenEnv+")")
+	fs.BoolVar(&c.json, "json", false, "print JSON instead of text")
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "usage: web_test %s %s\n", name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse 解析參數並回傳位置參數；flag 可以放在位置參數之後（例如 logs 12 --follow）
+func (c *cli) parse(fs *flag.FlagSet, args []string, positional int) ([]string, bool) {
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, false
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		rest = append(rest, args[0])
+		args = args[1:]
+	}
+	if len(rest) != positional {
+		fs.Usage()
+		return nil, false
+	}
+	return rest, true
+}
+
+func (c *cli) client() *Client {
+	return New(c.server, c.token)
+}
+
+// fail 印出錯誤並回傳 ExitError
+func (c *cli) fail(err error) int {
+	fmt.Fprintf(c.stderr, "error: %v\n", err)
+	return ExitError
+}
+
+func (c *cli) printJSON(v any) {
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// paramFlags 收集重複的 -p nf:pr
+type paramFlags [][]string
+
+func (p *paramFlags) String() string {
+	return fmt.Sprint(*p)
+}
+
+func (p *paramFlags) Set(value string) error {
+	nf, pr, ok := strings.Cut(value, ":")
+	if !ok || nf == "" || pr == "" {
+		return fmt.Errorf("want nf:pr, got %q", value)
+	}
+	*p = append(*p, []string{nf, pr})
+	return nil
+}
+
+func (c *cli) submit(ctx context.Context, args []string) int {
+	fs := c.flagSet("submit", "-p nf:pr [-p nf:pr ...] [--base ref] [--wait]")
+	var params paramFlags
+	fs.Var(&params, "p", "NF and PR number to test, e.g. amf:12 (repeatable)")
+	base := fs.String("base", "", "free5gc release tag, branch or commit to test against")
+	wait := fs.Bool("wait", false, "wait for the task to finish and exit with its result")
+	if _, ok := c.parse(fs, args, 0); !ok {
+		return ExitError
+	}
+	if len(params) == 0 {
+		fs.Usage()
+		return ExitError
+	}
+
+	resp, err := c.client().Submit(ctx, &models.RunPRRequest{Params: params, BaseRef: *base})
+	if err != nil {
+		return c.fail(err)
+	}
+	if !*wait {
+		if c.json {
+			c.printJSON(resp)
+		} else {
+			fmt.Fprintf(c.stdout, "Task %s queued (%s)\n", resp.TaskID, describeSelection(resp.Selection))
+		}
+		return ExitOK
+	}
+	fmt.Fprintf(c.stderr, "Task %s queued (%s), waiting for it to finish...\n", resp.TaskID, describeSelection(resp.Selection))
+	view, err := c.client().Wait(ctx, resp.TaskID, pollInterval, c.progressPrinter())
+	if err != nil {
+		return c.fail(err)
+	}
+	c.printTask(view)
+	return ExitCode(&view.TaskResult)
+}
+
+func (c *cli) queue(ctx context.Context, args []string) int {
+	fs := c.flagSet("queue", "")
+	if _, ok := c.parse(fs, args, 0); !ok {
+		return ExitError
+	}
+	tasks, err := c.client().Queue(ctx)
+	if err != nil {
+		return c.fail(err)
+	}
+	if c.json {
+		if tasks == nil {
+			tasks = []models.TaskResult{}
+		}
+		c.printJSON(tasks)
+		return ExitOK
+	}
+	if len(tasks) == 0 {
+		fmt.Fprintln(c.stdout, "Queue is empty")
+		return ExitOK
+	}
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tPARAMS\tPROGRESS\tSUBMITTED BY")
+	for _, task := range tasks {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", task.TaskID, task.Status, describeParams(task.Params),
+			describeProgress(task.Progress), task.SubmittedBy)
+	}
+	w.Flush()
+	return ExitOK
+}
+
+func (c *cli) status(ctx context.Context, args []string) int {
+	fs := c.flagSet("status", "<task-id> [--wait]")
+	wait := fs.Bool("wait", false, "wait for the task to finish")
+	pos, ok := c.parse(fs, args, 1)
+	if !ok {
+		return ExitError
+	}
+	var view *TaskView
+	var err error
+	if *wait {
+		view, err = c.client().Wait(ctx, pos[0], pollInterval, c.progressPrinter())
+	} else {
+		view, err = c.client().Task(ctx, pos[0])
+	}
+	if err != nil {
+		return c.fail(err)
+	}
+	c.printTask(view)
+	return ExitCode(&view.TaskResult)
+}
+
+// testLog 是 logs --json 輸出的單一失敗測試
+type testLog struct {
+	Test string `json:"test"`
+	Log  string `json:"log"`
+}
+
+func (c *cli) logs(ctx context.Context, args []string) int {
+	fs := c.flagSet("logs", "<task-id> [--follow] [--test name]")
+	follow := fs.Bool("follow", false, "show progress until the task finishes, then print its logs")
+	test := fs.String("test", "", "only print the log of this failed test")
+	pos, ok := c.parse(fs, args, 1)
+	if !ok {
+		return ExitError
+	}
+	var view *TaskView
+	var err error
+	if *follow {
+		view, err = c.client().Wait(ctx, pos[0], pollInterval, c.progressPrinter())
+	} else {
+		view, err = c.client().Task(ctx, pos[0])
+	}
+	if err != nil {
+		return c.fail(err)
+	}
+	if !models.IsTerminalStatus(view.Status) {
+		fmt.Fprintf(c.stderr, "Task %s is %s, logs are available after it finishes (use --follow to wait)\n", view.TaskID, view.Status)
+		return ExitPending
+	}
+
+	// Logs 與 FailedTests 依索引對應；找不到 failures.json 等情況只有 Logs
+	var logs []testLog
+	for i, content := range view.Logs {
+		name := ""
+		if i < len(view.FailedTests) {
+			name = view.FailedTests[i]
+		}
+		if *test != "" && name != *test {
+			continue
+		}
+		logs = append(logs, testLog{Test: name, Log: content})
+	}
+	if *test != "" && len(logs) == 0 {
+		return c.fail(fmt.Errorf("task %s has no log for test %q", view.TaskID, *test))
+	}
+	if c.json {
+		if logs == nil {
+			logs = []testLog{}
+		}
+		c.printJSON(logs)
+		return ExitCode(&view.TaskResult)
+	}
+	if len(logs) == 0 {
+		fmt.Fprintf(c.stderr, "Task %s %s, no failure logs\n", view.TaskID, view.Status)
+	}
+	for _, l := range logs {
+		if l.Test != "" {
+			fmt.Fprintf(c.stdout, "===== %s =====\n", l.Test)
+		}
+		fmt.Fprintln(c.stdout, strings.TrimRight(l.Log, "\n"))
+	}
+	return ExitCode(&view.TaskResult)
+}
+
+func (c *cli) cancel(ctx context.Context, args []string) int {
+	fs := c.flagSet("cancel", "<task-id>")
+	pos, ok := c.parse(fs, args, 1)
+	if !ok {
+		return ExitError
+	}
+	status, err := c.client().Cancel(ctx, pos[0])
+	if err != nil {
+		return c.fail(err)
+	}
+	if c.json {
+		c.printJSON(StatusResponse{Status: status})
+		return ExitOK
+	}
+	if status == "removed" {
+		fmt.Fprintf(c.stdout, "Task %s removed from the queue\n", pos[0])
+	} else {
+		fmt.Fprintf(c.stdout, "Cancel requested, task %s will stop shortly\n", pos[0])
+	}
+	return ExitOK
+}
+
+func (c *cli) download(ctx context.Context, args []string) int {
+	fs := c.flagSet("download", "<task-id> [-o file]")
+	output := fs.String("o", "", "file to write (default task_<id>_logs.zip, - for stdout)")
+	pos, ok := c.parse(fs, args, 1)
+	if !ok {
+		return ExitError
+	}
+	if *output == "-" {
+		if err := c.client().Download(ctx, pos[0], c.stdout); err != nil {
+			return c.fail(err)
+		}
+		return ExitOK
+	}
+	if *output == "" {
+		*output = fmt.Sprintf("task_%s_logs.zip", pos[0])
+	}
+	file, err := os.Create(*output)
+	if err != nil {
+		return c.fail(err)
+	}
+	err = c.client().Download(ctx, pos[0], file)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(*output)
+		return c.fail(err)
+	}
+	if c.json {
+		c.printJSON(map[string]string{"task_id": pos[0], "file": *output})
+	} else {
+		fmt.Fprintf(c.stdout, "Saved logs of task %s to %s\n", pos[0], *output)
+	}
+	return ExitOK
+}
+
+func (c *cli) history(ctx context.Context, args []string) int {
+	fs := c.flagSet("history", "[--nf nf] [--limit n]")
+	nf := fs.String("nf", "", "only show tasks that tested a PR of this NF")
+	limit := fs.Int("limit", 20, "maximum number of records to show (0 for all)")
+	if _, ok := c.parse(fs, args, 0); !ok {
+		return ExitError
+	}
+	records, err := c.client().History(ctx)
+	if err != nil {
+		return c.fail(err)
+	}
+	filtered := make([]models.HistoryRecord, 0, len(records))
+	for _, rec := range records {
+		if *nf != "" && !hasNF(rec.Params, *nf) {
+			continue
+		}
+		if *limit > 0 && len(filtered) == *limit {
+			break
+		}
+		filtered = append(filtered, rec)
+	}
+	if c.json {
+		c.printJSON(filtered)
+		return ExitOK
+	}
+	if len(filtered) == 0 {
+		fmt.Fprintln(c.stdout, "No history")
+		return ExitOK
+	}
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tRESULT\tPARAMS\tFINISHED\tDURATION\tSUBMITTED BY")
+	for _, rec := range filtered {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", rec.TaskID, rec.Result, describeParams(rec.Params), rec.Time,
+			formatSeconds(rec.Duration), rec.SubmittedBy)
+	}
+	w.Flush()
+	return ExitOK
+}
+
+// progressPrinter 回傳等待任務時的回呼，狀態或進度改變時印到 stderr
+func (c *cli) progressPrinter() func(*TaskView) {
+	last := ""
+	return func(view *TaskView) {
+		line := view.Status
+		if view.Progress != nil {
+			line += " " + describeProgress(view.Progress)
+		}
+		if line != last {
+			fmt.Fprintf(c.stderr, "[%s] task %s %s\n", time.Now().Format("15:04:05"), view.TaskID, line)
+			last = line
+		}
+	}
+}
+
+// printTask 印出任務狀態；--json 時印出完整結果
+func (c *cli) printTask(view *TaskView) {
+	if c.json {
+		c.printJSON(view)
+		return
+	}
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Task:\t%s\n", view.TaskID)
+	fmt.Fprintf(w, "Status:\t%s\n", view.Status)
+	if view.Verdict != "" {
+		fmt.Fprintf(w, "Verdict:\t%s\n", view.Verdict)
+	}
+	fmt.Fprintf(w, "Params:\t%s\n", describeParams(view.Params))
+	if view.BaseRef != "" {
+		fmt.Fprintf(w, "Base:\t%s\n", view.BaseRef)
+	}
+	fmt.Fprintf(w, "Tests:\t%s\n", describeSelection(view.Selection))
+	if view.SubmittedBy != "" {
+		fmt.Fprintf(w, "Submitted by:\t%s\n", view.SubmittedBy)
+	}
+	if view.StartedTime != "" {
+		fmt.Fprintf(w, "Started:\t%s\n", view.StartedTime)
+	}
+	if view.FinishedTime != "" {
+		fmt.Fprintf(w, "Finished:\t%s (%s)\n", view.FinishedTime, formatSeconds(view.Duration))
+	}
+	if view.Progress != nil {
+		fmt.Fprintf(w, "Progress:\t%s\n", describeProgress(view.Progress))
+	}
+	if len(view.FailedTests) > 0 {
+		fmt.Fprintf(w, "Failed tests:\t%s\n", strings.Join(view.FailedTests, ", "))
+	}
+	if len(view.FlakyTests) > 0 {
+		fmt.Fprintf(w, "Flaky tests:\t%s\n", strings.Join(view.FlakyTests, ", "))
+	}
+	if view.Stale {
+		fmt.Fprintf(w, "Note:\ta tested PR has new commits since this run\n")
+	}
+	w.Flush()
+}
+
+// ExitCode 依任務結果決定結束碼
+func ExitCode(result *models.TaskResult) int {
+	switch {
+	case result.Status == models.StatusSuccess:
+		return ExitOK
+	case !models.IsTerminalStatus(result.Status):
+		return ExitPending
+	case result.Verdict == models.VerdictInfra:
+		return ExitInfra
+	case result.Verdict == models.VerdictCancelled:
+		return ExitCancelled
+	default:
+		return ExitTaskFailed
+	}
+}
+
+func describeParams(params []models.TaskParams) string {
+	parts := make([]string, 0, len(params))
+	for _, p := range params {
+		parts = append(parts, p.NF+"#"+p.PRVersion)
+	}
+	return strings.Join(parts, " ")
+}
+
+func describeSelection(sel *models.TestSelection) string {
+	if sel == nil || sel.Full {
+		return "full test set"
+	}
+	s := fmt.Sprintf("%d tests", len(sel.Tests))
+	if len(sel.Envs) > 0 {
+		s += fmt.Sprintf(", ULCL %s", strings.Join(sel.Envs, " "))
+	}
+	return s
+}
+
+func describeProgress(p *models.ProgressInfo) string {
+	if p == nil {
+		return "-"
+	}
+	s := fmt.Sprintf("%d/%d (%d%%)", p.Completed, p.Total, p.Percent)
+	if p.Stage != "" {
+		s += " " + p.Stage
+	}
+	if p.CurrentTest != "" {
+		s += " " + p.CurrentTest
+	}
+	if p.Remaining >= 0 {
+		s += ", about " + formatSeconds(p.Remaining) + " left"
+	}
+	return s
+}
+
+func formatSeconds(seconds int64) string {
+	if seconds <= 0 {
+		return "-"
+	}
+	return (time.Duration(seconds) * time.Second).String()
+}
+
+func hasNF(params []models.TaskParams, nf string) bool {
+	for _, p := range params {
+		if strings.EqualFold(p.NF, nf) {
+			return true
+		}
+	}
+	return false
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
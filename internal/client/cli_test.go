@@ -0,0 +1,219 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"web_test/pkg/models"
+)
+
+// fakeAPI 模擬 /api/v1 中命令列工具用到的端點
+type fakeAPI struct {
+	mu      sync.Mutex
+	queued  []models.TaskResult
+	results map[string]*models.TaskResult
+	history []models.HistoryRecord
+	// polls 為任務 7 被查詢的次數，第二次查詢後變成失敗
+	polls     int
+	submitted models.RunPRRequest
+	source    string
+}
+
+func newFakeAPI(t *testing.T) (*fakeAPI, *httptest.Server) {
+	t.Helper()
+	api := &fakeAPI{results: map[string]*models.TaskResult{
+		"3": {TaskID: "3", Status: models.StatusFailed, Verdict: models.VerdictPR,
+			FailedTests: []string{"TestA", "TestB"}, Logs: []string{"log of A\n", "log of B\n"}},
+		"4": {TaskID: "4", Status: models.StatusFailed, Verdict: models.VerdictInfra},
+		"5": {TaskID: "5", Status: models.StatusSuccess, Verdict: models.VerdictPass},
+		"7": {TaskID: "7", Status: models.StatusRunning},
+	}}
+	api.queued = []models.TaskResult{{TaskID: "9", Status: models.StatusQueueing, Params: []models.TaskParams{{NF: "smf", PRVersion: "34"}}}}
+	api.history = []models.HistoryRecord{
+		{TaskID: "5", Result: models.StatusSuccess, Params: []models.TaskParams{{NF: "amf", PRVersion: "1"}}},
+		{TaskID: "4", Result: models.StatusFailed, Params: []models.TaskParams{{NF: "smf", PRVersion: "2"}}},
+		{TaskID: "3", Result: models.StatusFailed, Params: []models.TaskParams{{NF: "SMF", PRVersion: "3"}, {NF: "amf", PRVersion: "4"}}},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/queue/run-pr", func(w http.ResponseWriter, r *http.Request) {
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		json.NewDecoder(r.Body).Decode(&api.submitted)
+		api.source = r.Header.Get(sourceHeader)
+		json.NewEncoder(w).Encode(SubmitResponse{TaskID: "10"})
+	})
+	mux.HandleFunc("GET /api/v1/queue/list", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(api.queued)
+	})
+	mux.HandleFunc("GET /api/v1/download/task/{id}", func(w http.ResponseWriter, r *http.Request) {
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		id := r.PathValue("id")
+		if id == "7" {
+			if api.polls++; api.polls > 2 {
+				api.results["7"] = &models.TaskResult{TaskID: "7", Status: models.StatusFailed, Verdict: models.VerdictCancelled,
+					Logs: []string{"Task cancelled by alice"}}
+			}
+		}
+		result, ok := api.results[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":"not_found","message":"Task result for ID ` + id + ` not found"}}`))
+			return
+		}
+		json.NewEncoder(w).Encode(TaskView{TaskResult: *result})
+	})
+	mux.HandleFunc("GET /api/v1/queue/progress/{id}", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(models.ProgressInfo{TaskID: r.PathValue("id"), Stage: models.StageTestAll, Completed: 1, Total: 4, Percent: 25, Remaining: 90})
+	})
+	mux.HandleFunc("POST /api/v1/queue/cancel/{id}", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(StatusResponse{Status: "cancelling"})
+	})
+	mux.HandleFunc("GET /api/v1/download/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("zip of " + r.PathValue("id")))
+	})
+	mux.HandleFunc("GET /api/v1/history/", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(api.history)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return api, srv
+}
+
+// run 執行子命令並回傳結束碼、stdout 與 stderr
+func run(t *testing.T, srv *httptest.Server, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	args = append(args, "--server", srv.URL)
+	code := Run(context.Background(), args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestSubmit(t *testing.T) {
+	api, srv := newFakeAPI(t)
+	code, out, errOut := run(t, srv, "submit", "-p", "amf:12", "-p", "smf:34", "--base", "v4.0.0")
+	if code != ExitOK || !strings.Contains(out, "Task 10 queued") {
+		t.Fatalf("submit = %d %q %q", code, out, errOut)
+	}
+	want := models.RunPRRequest{Params: [][]string{{"amf", "12"}, {"smf", "34"}}, BaseRef: "v4.0.0"}
+	if got, _ := json.Marshal(api.submitted); string(got) != string(must(json.Marshal(want))) {
+		t.Errorf("submitted %s, want %s", got, must(json.Marshal(want)))
+	}
+	if api.source != models.SourceCLI {
+		t.Errorf("source header = %q, want cli", api.source)
+	}
+
+	if code, _, _ := run(t, srv, "submit"); code != ExitError {
+		t.Errorf("submit without params = %d, want %d", code, ExitError)
+	}
+	if code, _, _ := run(t, srv, "submit", "-p", "amf"); code != ExitError {
+		t.Errorf("submit with a bad param = %d, want %d", code, ExitError)
+	}
+}
+
+func TestStatusExitCodes(t *testing.T) {
+	_, srv := newFakeAPI(t)
+	tests := []struct {
+		id   string
+		code int
+		want string
+	}{
+		{"3", ExitTaskFailed, "Failed tests: TestA, TestB"},
+		{"4", ExitInfra, "Verdict: infra"},
+		{"5", ExitOK, "Status: Success"},
+		{"9", ExitPending, "Status: queueing Params: smf#34"},
+	}
+	for _, tt := range tests {
+		code, out, errOut := run(t, srv, "status", tt.id)
+		// 忽略 tabwriter 對齊的空白
+		if code != tt.code || !strings.Contains(strings.Join(strings.Fields(out), " "), tt.want) {
+			t.Errorf("status %s = %d %q %q, want %d containing %q", tt.id, code, out, errOut, tt.code, tt.want)
+		}
+	}
+
+	code, _, errOut := run(t, srv, "status", "404")
+	if code != ExitError || !strings.Contains(errOut, "Task result for ID 404 not found") {
+		t.Errorf("status of an unknown task = %d %q", code, errOut)
+	}
+
+	var view TaskView
+	code, out, _ := run(t, srv, "status", "5", "--json")
+	if err := json.Unmarshal([]byte(out), &view); err != nil || code != ExitOK || view.Verdict != models.VerdictPass {
+		t.Errorf("status --json = %d %q", code, out)
+	}
+}
+
+func TestLogs(t *testing.T) {
+	_, srv := newFakeAPI(t)
+	code, out, _ := run(t, srv, "logs", "3")
+	if code != ExitTaskFailed || out != "===== TestA =====\nlog of A\n===== TestB =====\nlog of B\n" {
+		t.Errorf("logs = %d %q", code, out)
+	}
+
+	var logs []testLog
+	code, out, _ = run(t, srv, "logs", "3", "--test", "TestB", "--json")
+	if err := json.Unmarshal([]byte(out), &logs); err != nil || len(logs) != 1 || logs[0].Log != "log of B\n" {
+		t.Errorf("logs --test TestB --json = %d %q", code, out)
+	}
+
+	if code, _, _ := run(t, srv, "logs", "7"); code != ExitPending {
+		t.Errorf("logs of a running task = %d, want %d", code, ExitPending)
+	}
+}
+
+func TestLogsFollow(t *testing.T) {
+	old := pollInterval
+	pollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { pollInterval = old })
+
+	_, srv := newFakeAPI(t)
+	code, out, errOut := run(t, srv, "logs", "7", "--follow")
+	if code != ExitCancelled || out != "Task cancelled by alice\n" {
+		t.Errorf("logs --follow = %d %q", code, out)
+	}
+	if !strings.Contains(errOut, "task 7 running 1/4 (25%) test_all, about 1m30s left") {
+		t.Errorf("progress output = %q", errOut)
+	}
+}
+
+func TestCancelDownloadHistory(t *testing.T) {
+	_, srv := newFakeAPI(t)
+	if code, out, _ := run(t, srv, "cancel", "7"); code != ExitOK || !strings.Contains(out, "Cancel requested") {
+		t.Errorf("cancel = %d %q", code, out)
+	}
+
+	path := filepath.Join(t.TempDir(), "logs.zip")
+	if code, _, errOut := run(t, srv, "download", "3", "-o", path); code != ExitOK {
+		t.Fatalf("download = %d %q", code, errOut)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "zip of 3" {
+		t.Errorf("downloaded %q, %v", data, err)
+	}
+
+	var records []models.HistoryRecord
+	code, out, _ := run(t, srv, "history", "--nf", "smf", "--json")
+	if err := json.Unmarshal([]byte(out), &records); err != nil || code != ExitOK || len(records) != 2 ||
+		records[0].TaskID != "4" || records[1].TaskID != "3" {
+		t.Errorf("history --nf smf = %d %q", code, out)
+	}
+	if code, out, _ := run(t, srv, "history", "--limit", "1"); code != ExitOK || strings.Count(out, "\n") != 2 {
+		t.Errorf("history --limit 1 = %d %q", code, out)
+	}
+}
+
+func must(data []byte, err error) []byte {
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
@@ -0,0 +1,252 @@
+// Package client 是 web_test REST API (/api/v1) 的客戶端，供命令列工具使用
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"web_test/pkg/models"
+)
+
+const (
+	apiPrefix = "/api/v1"
+	// sourceHeader 與 server 的 X-Web-Test-Source 相同，稽核紀錄會標示來源為 cli
+	sourceHeader = "X-Web-Test-Source"
+)
+
+// APIError 是 API 回傳的錯誤，Code 為 server 的錯誤代碼（例如 not_found）
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("HTTP %d", e.Status)
+	}
+	return fmt.Sprintf("%s (HTTP %d)", e.Message, e.Status)
+}
+
+// IsNotFound 回報 err 是否為 404
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound
+}
+
+// Client 呼叫 web_test 的 REST API
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// New 建立 Client；token 為空時不帶 Authorization
+func New(baseURL, token string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: time.Minute},
+	}
+}
+
+// TaskView 是 GET /download/task/:taskID 的回應，附加依時區格式化的時間
+type TaskView struct {
+	models.TaskResult
+	StartedTime  string `json:"started_time,omitempty"`
+	FinishedTime string `json:"finished_time,omitempty"`
+	Stale        bool   `json:"stale,omitempty"`
+}
+
+// SubmitResponse 是 POST /queue/run-pr 的回應
+type SubmitResponse struct {
+	TaskID    string                `json:"task_id"`
+	Status    string                `json:"status"`
+	Selection *models.TestSelection `json:"selection,omitempty"`
+}
+
+// StatusResponse 是只回傳狀態的 API 回應
+type StatusResponse struct {
+	Status string `json:"status"`
+}
+
+// Submit 將 PR 任務加入佇列
+func (c *Client) Submit(ctx context.Context, req *models.RunPRRequest) (*SubmitResponse, error) {
+	var resp SubmitResponse
+	if err := c.do(ctx, http.MethodPost, "/queue/run-pr", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Queue 回傳執行中與排隊中的任務
+func (c *Client) Queue(ctx context.Context) ([]models.TaskResult, error) {
+	var tasks []models.TaskResult
+	err := c.do(ctx, http.MethodGet, "/queue/list", nil, &tasks)
+	return tasks, err
+}
+
+// Result 回傳已開始執行的任務結果，排隊中的任務回傳 404
+func (c *Client) Result(ctx context.Context, taskID string) (*TaskView, error) {
+	var view TaskView
+	if err := c.do(ctx, http.MethodGet, "/download/task/"+url.PathEscape(taskID), nil, &view); err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+// Progress 回傳執行中任務的進度
+func (c *Client) Progress(ctx context.Context, taskID string) (*models.ProgressInfo, error) {
+	var progress models.ProgressInfo
+	if err := c.do(ctx, http.MethodGet, "/queue/progress/"+url.PathEscape(taskID), nil, &progress); err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+// Task 回傳任務目前的狀態：已開始的任務取結果（執行中時附上進度），否則到佇列中尋找
+func (c *Client) Task(ctx context.Context, taskID string) (*TaskView, error) {
+	view, err := c.Result(ctx, taskID)
+	if err == nil {
+		if view.Status == models.StatusRunning {
+			if progress, err := c.Progress(ctx, taskID); err == nil {
+				view.Progress = progress
+			}
+		}
+		return view, nil
+	}
+	if !IsNotFound(err) {
+		return nil, err
+	}
+	tasks, qerr := c.Queue(ctx)
+	if qerr != nil {
+		return nil, qerr
+	}
+	for _, task := range tasks {
+		if task.TaskID == taskID {
+			return &TaskView{TaskResult: task}, nil
+		}
+	}
+	return nil, err
+}
+
+// Wait 每隔 interval 查詢任務直到結束，每次查詢後呼叫 update（可為 nil）
+func (c *Client) Wait(ctx context.Context, taskID string, interval time.Duration, update func(*TaskView)) (*TaskView, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	seen := false
+	for {
+		view, err := c.Task(ctx, taskID)
+		switch {
+		case err == nil:
+			seen = true
+			if update != nil {
+				update(view)
+			}
+			if models.IsTerminalStatus(view.Status) {
+				return view, nil
+			}
+		case seen && IsNotFound(err):
+			// executor 剛取出任務、尚未寫入執行中狀態時，任務短暫不在佇列也沒有結果
+		default:
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Cancel 取消任務，回傳 removed（已移出佇列）或 cancelling（等待 executor 中止）
+func (c *Client) Cancel(ctx context.Context, taskID string) (string, error) {
+	var resp StatusResponse
+	err := c.do(ctx, http.MethodPost, "/queue/cancel/"+url.PathEscape(taskID), nil, &resp)
+	return resp.Status, err
+}
+
+// Download 將任務所有 log 的 zip 寫入 w
+func (c *Client) Download(ctx context.Context, taskID string, w io.Writer) error {
+	resp, err := c.send(ctx, http.MethodGet, "/download/"+url.PathEscape(taskID), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// History 回傳最近的任務歷史紀錄，最新在前
+func (c *Client) History(ctx context.Context) ([]models.HistoryRecord, error) {
+	var records []models.HistoryRecord
+	err := c.do(ctx, http.MethodGet, "/history/", nil, &records)
+	return records, err
+}
+
+// do 送出 JSON 請求並將回應解析到 out
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	resp, err := c.send(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response of %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// send 送出請求，非 2xx 的回應轉成 *APIError
+func (c *Client) send(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+apiPrefix+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	req.Header.Set(sourceHeader, models.SourceCLI)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	apiErr := &APIError{Status: resp.StatusCode}
+	var errResp struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10)); json.Unmarshal(data, &errResp) == nil {
+		apiErr.Code, apiErr.Message = errResp.Error.Code, errResp.Error.Message
+	}
+	return nil, apiErr
+}
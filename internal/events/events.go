@@ -0,0 +1,130 @@
+// Package events 提供事件匯流排，將佇列與任務狀態的變化推送給訂閱者（例如 SSE 連線）；
+// web server 與 executor 分開執行時由 RedisRelay 在行程之間轉送事件。
+package events
+
+import (
+	"sync"
+	"time"
+
+	"web_test/internal/logger"
+	"web_test/pkg/models"
+)
+
+// 事件類型
+const (
+	TaskQueued   = "task.queued"   // 任務加入佇列
+	TaskRemoved  = "task.removed"  // 任務從佇列刪除
+	TaskStarted  = "task.started"  // 任務從佇列取出並開始執行
+	TaskStatus   = "task.status"   // 任務狀態改變
+	TaskFinished = "task.finished" // 任務結束並產生結果
+	TaskProgress = "task.progress" // 執行中任務的進度更新
+)
+
+// Event 是一筆佇列或任務的變化
+type Event struct {
+	ID     uint64             `json:"id"` // 遞增序號，斷線重連時以 Last-Event-ID 補送
+	Type   string             `json:"type"`
+	At     int64              `json:"at"` // UTC Unix 秒
+	TaskID string             `json:"task_id"`
+	Status string             `json:"status,omitempty"`
+	Task   *models.Task       `json:"task,omitempty"`   // TaskQueued 時的任務內容
+	Result *models.TaskResult `json:"result,omitempty"` // TaskFinished 時的結果（不含 log）
+
+	Progress *models.ProgressInfo `json:"progress,omitempty"` // TaskProgress 時的進度
+}
+
+const (
+	subscriberBuffer = 64
+	historySize      = 256 // 保留最近的事件供重連補送
+)
+
+// Bus 將事件廣播給所有訂閱者；訂閱者來不及接收時丟棄該訂閱者的事件，不會阻塞發布者
+type Bus struct {
+	mu      sync.Mutex
+	nextID  uint64
+	subs    map[chan Event]struct{}
+	history []Event
+	relay   func(Event) error // 不為 nil 時 Publish 經由 relay 送出，收到後才以 dispatch 分派
+}
+
+// NewBus 建立事件匯流排
+func NewBus() *Bus {
+	return &Bus{subs: make(map[chan Event]struct{})}
+}
+
+// Publish 發布事件，ID 與時間由匯流排設定。nil 的 Bus 不做任何事
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	if e.At == 0 {
+		e.At = time.Now().Unix()
+	}
+	b.mu.Lock()
+	relay := b.relay
+	b.mu.Unlock()
+	if relay != nil {
+		err := relay(e)
+		if err == nil {
+			return
+		}
+		logger.MainLog.Warnf("Failed to relay event %s of task %s, delivering locally: %v", e.Type, e.TaskID, err)
+	}
+	b.dispatch(e)
+}
+
+// setRelay 設定事件的轉送方式，nil 表示只分派給本地訂閱者
+func (b *Bus) setRelay(relay func(Event) error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.relay = relay
+}
+
+// dispatch 設定 ID 後將事件保留並送給本地訂閱者；ID 只在同一個行程內遞增
+func (b *Bus) dispatch(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	e.ID = b.nextID
+	b.history = append(b.history, e)
+	if len(b.history) > historySize {
+		b.history = b.history[len(b.history)-historySize:]
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe 訂閱事件，回傳 ID 大於 lastID 且仍保留的事件與之後事件的 channel；
+// 結束時須呼叫 cancel
+func (b *Bus) Subscribe(lastID uint64) (missed []Event, ch <-chan Event, cancel func()) {
+	c := make(chan Event, subscriberBuffer)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if lastID > 0 {
+		for _, e := range b.history {
+			if e.ID > lastID {
+				missed = append(missed, e)
+			}
+		}
+	}
+	b.subs[c] = struct{}{}
+	return missed, c, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[c]; ok {
+			delete(b.subs, c)
+			close(c)
+		}
+	}
+}
+
+// Subscribers 回傳目前的訂閱者數量
+func (b *Bus) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
@@ -0,0 +1,104 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"web_test/pkg/database"
+	"web_test/pkg/models"
+	"web_test/pkg/queue"
+)
+
+func receive(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return Event{}
+	}
+}
+
+func TestBusReplayAndCancel(t *testing.T) {
+	bus := NewBus()
+	bus.Publish(Event{Type: TaskQueued, TaskID: "1"})
+	bus.Publish(Event{Type: TaskRemoved, TaskID: "1"})
+
+	missed, ch, cancel := bus.Subscribe(1)
+	if len(missed) != 1 || missed[0].ID != 2 || missed[0].Type != TaskRemoved {
+		t.Fatalf("missed = %+v, want only event 2", missed)
+	}
+	bus.Publish(Event{Type: TaskQueued, TaskID: "2"})
+	if e := receive(t, ch); e.ID != 3 || e.TaskID != "2" || e.At == 0 {
+		t.Errorf("event = %+v", e)
+	}
+
+	cancel()
+	cancel()
+	if n := bus.Subscribers(); n != 0 {
+		t.Errorf("subscribers after cancel = %d", n)
+	}
+	if _, ok := <-ch; ok {
+		t.Error("channel still open after cancel")
+	}
+}
+
+func TestSlowSubscriberDoesNotBlock(t *testing.T) {
+	bus := NewBus()
+	_, _, cancel := bus.Subscribe(0)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < subscriberBuffer*2; i++ {
+			bus.Publish(Event{Type: TaskStatus})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a full subscriber")
+	}
+}
+
+func TestPublishingQueueAndStore(t *testing.T) {
+	ctx := context.Background()
+	bus := NewBus()
+	_, ch, cancel := bus.Subscribe(0)
+	defer cancel()
+	q := NewQueue(queue.NewListQueue(), bus)
+	store := NewStore(database.NewMemoryDB(), bus)
+
+	if err := q.PushTask(ctx, &models.Task{ID: "7"}); err != nil {
+		t.Fatal(err)
+	}
+	if e := receive(t, ch); e.Type != TaskQueued || e.Task == nil || e.Status != models.StatusQueueing {
+		t.Errorf("push event = %+v", e)
+	}
+	if err := q.RemoveTask(ctx, "missing"); err == nil {
+		t.Fatal("removing a missing task succeeded")
+	}
+	if _, err := q.PopTask(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if e := receive(t, ch); e.Type != TaskStarted || e.TaskID != "7" {
+		t.Errorf("pop event = %+v, want task.started and no event for the failed removal", e)
+	}
+
+	if err := store.SaveResult(ctx, &models.TaskResult{TaskID: "7", Status: models.StatusRunning}); err != nil {
+		t.Fatal(err)
+	}
+	if e := receive(t, ch); e.Type != TaskStatus || e.Status != models.StatusRunning {
+		t.Errorf("running event = %+v", e)
+	}
+	if err := store.SaveResult(ctx, &models.TaskResult{TaskID: "7", Status: models.StatusFailed, Logs: []string{"long log"}}); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, ch)
+	if e := receive(t, ch); e.Type != TaskFinished || e.Result == nil || e.Result.Logs != nil {
+		t.Errorf("finished event = %+v, want the result without logs", e)
+	}
+}
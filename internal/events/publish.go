@@ -0,0 +1,79 @@
+package events
+
+import (
+	"context"
+
+	"web_test/pkg/database"
+	"web_test/pkg/models"
+	"web_test/pkg/queue"
+)
+
+// publishingQueue 在佇列操作成功後發布事件
+type publishingQueue struct {
+	queue.TaskQueue
+	bus *Bus
+}
+
+// NewQueue 包裝 TaskQueue，加入、刪除與取出任務時發布事件
+func NewQueue(q queue.TaskQueue, bus *Bus) queue.TaskQueue {
+	return &publishingQueue{TaskQueue: q, bus: bus}
+}
+
+func (q *publishingQueue) PushTask(ctx context.Context, task *models.Task) error {
+	if err := q.TaskQueue.PushTask(ctx, task); err != nil {
+		return err
+	}
+	q.bus.Publish(Event{Type: TaskQueued, TaskID: task.ID, Status: models.StatusQueueing, Task: task})
+	return nil
+}
+
+func (q *publishingQueue) PopTask(ctx context.Context) (*models.Task, error) {
+	task, err := q.TaskQueue.PopTask(ctx)
+	if err != nil || task == nil {
+		return task, err
+	}
+	q.bus.Publish(Event{Type: TaskStarted, TaskID: task.ID})
+	return task, nil
+}
+
+func (q *publishingQueue) RemoveTask(ctx context.Context, taskID string) error {
+	if err := q.TaskQueue.RemoveTask(ctx, taskID); err != nil {
+		return err
+	}
+	q.bus.Publish(Event{Type: TaskRemoved, TaskID: taskID})
+	return nil
+}
+
+// publishingStore 在任務結果與進度儲存成功後發布事件
+type publishingStore struct {
+	database.ResultStore
+	bus *Bus
+}
+
+// NewStore 包裝 ResultStore，SaveResult 成功時發布 TaskStatus，結束的任務另發布 TaskFinished；
+// SaveProgress 成功時發布 TaskProgress
+func NewStore(s database.ResultStore, bus *Bus) database.ResultStore {
+	return &publishingStore{ResultStore: s, bus: bus}
+}
+
+func (s *publishingStore) SaveResult(ctx context.Context, result *models.TaskResult) error {
+	if err := s.ResultStore.SaveResult(ctx, result); err != nil {
+		return err
+	}
+	s.bus.Publish(Event{Type: TaskStatus, TaskID: result.TaskID, Status: result.Status})
+	if models.IsTerminalStatus(result.Status) {
+		summary := *result
+		summary.Logs = nil
+		s.bus.Publish(Event{Type: TaskFinished, TaskID: result.TaskID, Status: result.Status, Result: &summary})
+	}
+	return nil
+}
+
+func (s *publishingStore) SaveProgress(ctx context.Context, progress *models.ProgressInfo) error {
+	if err := s.ResultStore.SaveProgress(ctx, progress); err != nil {
+		return err
+	}
+	snapshot := *progress
+	s.bus.Publish(Event{Type: TaskProgress, TaskID: progress.TaskID, Status: models.StatusRunning, Progress: &snapshot})
+	return nil
+}
@@ -0,0 +1,92 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"web_test/internal/logger"
+)
+
+const (
+	redisPublishTimeout = 2 * time.Second
+	relayRetryDelay     = 5 * time.Second
+)
+
+// RedisRelay 經由 Redis pub/sub 在行程之間轉送事件，讓 serve 行程的 /api/events 收到 work 行程發布的事件
+type RedisRelay struct {
+	client  *redis.Client
+	channel string
+}
+
+// NewRedisRelay 建立使用 addr 上 Redis / KVRocks 的轉送；pub/sub 不分 DB，channel 名稱帶上 DB 編號
+func NewRedisRelay(addr, password string, db int) *RedisRelay {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:             addr,
+		Password:         password,
+		DB:               db,
+		DisableIndentity: true,
+		Protocol:         2,
+	})
+	return &RedisRelay{client: rdb, channel: fmt.Sprintf("web_test:events:%d", db)}
+}
+
+// Run 將 bus 接上 Redis，直到 ctx 結束：訂閱成功後 bus.Publish 改為送到 Redis，
+// 每個行程（包含發布者自己）收到後再分派給本地訂閱者。Redis 無法連線時每 5 秒重試，期間事件只分派給本地訂閱者
+func (r *RedisRelay) Run(ctx context.Context, bus *Bus) error {
+	for {
+		err := r.relay(ctx, bus)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		logger.MainLog.Warnf("Event relay stopped: %v, retrying in %s", err, relayRetryDelay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(relayRetryDelay):
+		}
+	}
+}
+
+func (r *RedisRelay) relay(ctx context.Context, bus *Bus) error {
+	pubsub := r.client.Subscribe(ctx, r.channel)
+	defer pubsub.Close()
+	// 等待訂閱確認後才改由 Redis 發布，避免自己發布的事件在訂閱完成前遺失
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return err
+	}
+	bus.setRelay(r.publish)
+	defer bus.setRelay(nil)
+	logger.MainLog.Infof("Relaying events through Redis channel %s", r.channel)
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-ch:
+			if !ok {
+				return fmt.Errorf("subscription to %s closed", r.channel)
+			}
+			var e Event
+			if err := json.Unmarshal([]byte(msg.Payload), &e); err != nil {
+				logger.MainLog.Warnf("Ignoring invalid event on %s: %v", r.channel, err)
+				continue
+			}
+			bus.dispatch(e)
+		}
+	}
+}
+
+func (r *RedisRelay) publish(e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisPublishTimeout)
+	defer cancel()
+	return r.client.Publish(ctx, r.channel, data).Err()
+}
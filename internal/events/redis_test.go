@@ -0,0 +1,86 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// waitRelay 等待 bus 接上 Redis
+func waitRelay(t *testing.T, bus *Bus) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		bus.mu.Lock()
+		ready := bus.relay != nil
+		bus.mu.Unlock()
+		if ready {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("relay not connected")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRedisRelayAcrossProcesses(t *testing.T) {
+	addr := miniredis.RunT(t).Addr()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// web 與 worker 各自有 Bus，經由同一個 Redis 轉送
+	web, worker := NewBus(), NewBus()
+	go NewRedisRelay(addr, "", 0).Run(ctx, web)
+	go NewRedisRelay(addr, "", 0).Run(ctx, worker)
+	waitRelay(t, web)
+	waitRelay(t, worker)
+
+	_, webCh, cancelWeb := web.Subscribe(0)
+	defer cancelWeb()
+	_, workerCh, cancelWorker := worker.Subscribe(0)
+	defer cancelWorker()
+
+	worker.Publish(Event{Type: TaskStatus, TaskID: "7", Status: "Running"})
+	if e := receive(t, webCh); e.Type != TaskStatus || e.TaskID != "7" || e.ID != 1 || e.At == 0 {
+		t.Errorf("web received %+v", e)
+	}
+	if e := receive(t, workerCh); e.TaskID != "7" {
+		t.Errorf("worker received %+v, want its own event once", e)
+	}
+
+	// 其他 DB 的事件不會收到
+	other := NewBus()
+	go NewRedisRelay(addr, "", 1).Run(ctx, other)
+	waitRelay(t, other)
+	other.Publish(Event{Type: TaskQueued, TaskID: "9"})
+	web.Publish(Event{Type: TaskQueued, TaskID: "8"})
+	if e := receive(t, webCh); e.TaskID != "8" || e.ID != 2 {
+		t.Errorf("web received %+v, want task 8 only", e)
+	}
+}
+
+func TestRedisRelayFallsBackToLocal(t *testing.T) {
+	mr := miniredis.RunT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	bus := NewBus()
+	done := make(chan struct{})
+	go func() {
+		NewRedisRelay(mr.Addr(), "", 0).Run(ctx, bus)
+		close(done)
+	}()
+	waitRelay(t, bus)
+	_, ch, unsubscribe := bus.Subscribe(0)
+	defer unsubscribe()
+
+	// Redis 無法連線時仍分派給本地訂閱者
+	mr.Close()
+	bus.Publish(Event{Type: TaskRemoved, TaskID: "3"})
+	if e := receive(t, ch); e.TaskID != "3" {
+		t.Errorf("event = %+v", e)
+	}
+	cancel()
+	<-done
+}
@@ -0,0 +1,70 @@
+package executor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"web_test/internal/logger"
+	"web_test/pkg/database"
+)
+
+// cancelPollInterval 為檢查取消要求的間隔；web server 可能在另一台主機，只能經由 ResultStore 得知
+var cancelPollInterval = 2 * time.Second
+
+// cancelWatcher 在任務執行期間定期檢查 ResultStore 的取消要求，收到時取消 run_task.sh
+type cancelWatcher struct {
+	db       database.ResultStore
+	taskID   string
+	interval time.Duration
+
+	mu    sync.Mutex
+	actor string // 要求取消的身分，空值表示沒有被取消
+}
+
+// watch 回傳任務使用的 ctx；收到取消要求時取消它。呼叫 stop 結束檢查
+func (w *cancelWatcher) watch(ctx context.Context) (taskCtx context.Context, stop func()) {
+	taskCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-taskCtx.Done():
+				return
+			case <-ticker.C:
+			}
+			actor, err := w.db.GetCancelRequest(taskCtx, w.taskID)
+			if err != nil {
+				logger.ExecutorLog.Warnf("Failed to check cancel request of task %s: %v", w.taskID, err)
+				continue
+			}
+			if actor != "" {
+				logger.ExecutorLog.Infof("Task %s cancelled by %s", w.taskID, actor)
+				w.mu.Lock()
+				w.actor = actor
+				w.mu.Unlock()
+				cancel()
+				return
+			}
+		}
+	}()
+	return taskCtx, func() {
+		close(done)
+		wg.Wait()
+		cancel()
+	}
+}
+
+// cancelledBy 回傳要求取消的身分，沒有被取消時為空字串
+func (w *cancelWatcher) cancelledBy() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.actor
+}
@@ -0,0 +1,117 @@
+package executor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"web_test/pkg/database"
+	"web_test/pkg/models"
+	"web_test/pkg/queue"
+)
+
+func TestCancelWatcher(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryDB()
+	db.RequestCancel(ctx, "8", "bob")
+
+	// 其他任務的取消要求不影響目前的任務
+	w := &cancelWatcher{db: db, taskID: "7", interval: 5 * time.Millisecond}
+	taskCtx, stop := w.watch(ctx)
+	select {
+	case <-taskCtx.Done():
+		t.Fatal("task context cancelled without a cancel request")
+	case <-time.After(30 * time.Millisecond):
+	}
+
+	db.RequestCancel(ctx, "7", "alice")
+	select {
+	case <-taskCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("task context not cancelled after the cancel request")
+	}
+	stop()
+	if actor := w.cancelledBy(); actor != "alice" {
+		t.Fatalf("cancelledBy = %q, want alice", actor)
+	}
+}
+
+func TestCancelWatcherStop(t *testing.T) {
+	w := &cancelWatcher{db: database.NewMemoryDB(), taskID: "7", interval: time.Hour}
+	taskCtx, stop := w.watch(context.Background())
+	stop()
+	if taskCtx.Err() == nil {
+		t.Fatal("task context still active after stop")
+	}
+	if actor := w.cancelledBy(); actor != "" {
+		t.Fatalf("cancelledBy = %q after stop, want empty", actor)
+	}
+}
+
+// fakeScript 在背景啟動持有輸出的子行程後等待；只終止腳本本身時 cmd.Run 會等到子行程結束
+const fakeScript = `#!/bin/bash
+trap 'echo cleanup > "$(dirname "$0")/terminated"; kill $(jobs -p) 2>/dev/null; exit 143' TERM
+echo "##progress stage test_all"
+sleep 60 &
+wait
+`
+
+func TestCancelStopsScript(t *testing.T) {
+	oldInterval, oldDelay := cancelPollInterval, scriptWaitDelay
+	t.Cleanup(func() { cancelPollInterval, scriptWaitDelay = oldInterval, oldDelay })
+	cancelPollInterval, scriptWaitDelay = 10*time.Millisecond, 10*time.Second
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "run_task.sh")
+	if err := os.WriteFile(script, []byte(fakeScript), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	db := database.NewMemoryDB()
+	q := queue.NewListQueue()
+	e := NewTaskExecutor(db, q)
+	e.SetPaths(Paths{Script: script})
+	e.noSudo = true
+
+	q.PushTask(ctx, &models.Task{ID: "1", Params: []models.TaskParams{{NF: "amf", PRVersion: "12"}}})
+	done := make(chan error, 1)
+	go func() { done <- e.processNextTask(ctx) }()
+
+	// 等腳本開始執行後要求取消
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if p, _ := db.GetProgress(ctx, "1"); p != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("script did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	started := time.Now()
+	db.RequestCancel(ctx, "1", "alice")
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("task still running 5s after the cancel request")
+	}
+	if elapsed := time.Since(started); elapsed > 3*time.Second {
+		t.Errorf("task took %s to stop after the cancel request", elapsed)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "terminated")); err != nil {
+		t.Errorf("run_task.sh trap did not run: %v", err)
+	}
+	result, err := db.GetResult(ctx, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != models.StatusFailed || result.Verdict != models.VerdictCancelled {
+		t.Errorf("result = %s/%s, want failed/cancelled", result.Status, result.Verdict)
+	}
+}
@@ -0,0 +1,39 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPaths(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "run_task.sh")
+	e := &TaskExecutor{}
+	e.SetPaths(Paths{Script: script})
+	if e.paths.LogsDir != filepath.Join(dir, "logs") {
+		t.Errorf("default logs dir = %s, want next to the script", e.paths.LogsDir)
+	}
+	t.Setenv("CI_WORK_DIR", "")
+	if got := e.paths.ciDir(); got != filepath.Join(dir, "ci-test") {
+		t.Errorf("default ci dir = %s", got)
+	}
+
+	if err := e.CheckEnvironment(); err == nil {
+		t.Error("CheckEnvironment passed without run_task.sh")
+	}
+	if err := os.WriteFile(script, []byte("#!/bin/bash\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.CheckEnvironment(); err == nil {
+		t.Error("CheckEnvironment passed without the ci-test directory")
+	}
+	ciDir := filepath.Join(dir, "work")
+	if err := os.Mkdir(ciDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	e.SetPaths(Paths{Script: script, CIDir: ciDir})
+	if err := e.CheckEnvironment(); err != nil {
+		t.Errorf("CheckEnvironment with a configured ci dir: %v", err)
+	}
+}
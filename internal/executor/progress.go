@@ -0,0 +1,166 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"web_test/internal/logger"
+	"web_test/pkg/database"
+	"web_test/pkg/models"
+)
+
+// progressMarker 為 run_task.sh 輸出進度標記的前綴，每個標記獨佔一行：
+//
+//	##progress plan <Test1|Test2|ulcl:<env>>  本次要執行的測試與 ULCL 環境
+//	##progress stage <name>                   進入執行階段（見 models 的 Stage 常數）
+//	##progress test <name>                    開始執行測試
+//	##progress done <name>                    測試結束（不論成敗）
+const progressMarker = "##progress "
+
+// maxPartialLine 限制未換行輸出的保留長度；spinner 以 \r 重繪時整個測試期間都不會換行
+const maxPartialLine = 4096
+
+// progressTracker 解析 run_task.sh 的輸出並將進度寫入 ResultStore，
+// 測試結束時更新該測試的歷史執行秒數，供預估剩餘時間
+type progressTracker struct {
+	mu        sync.Mutex
+	db        database.ResultStore
+	progress  models.ProgressInfo
+	plan      []string
+	done      map[string]bool
+	durations map[string]int64 // 歷史執行秒數
+	testStart time.Time
+	partial   []byte
+	now       func() time.Time
+}
+
+func newProgressTracker(db database.ResultStore, taskID string) *progressTracker {
+	durations, err := db.GetTestDurations(context.Background())
+	if err != nil {
+		logger.ExecutorLog.Warnf("Failed to load test durations, remaining time is unknown: %v", err)
+		durations = make(map[string]int64)
+	}
+	return &progressTracker{
+		db:        db,
+		progress:  models.ProgressInfo{TaskID: taskID, Remaining: -1},
+		done:      make(map[string]bool),
+		durations: durations,
+		now:       time.Now,
+	}
+}
+
+// Write 實作 io.Writer，逐行尋找進度標記
+func (p *progressTracker) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.partial = append(p.partial, b...)
+	for {
+		i := bytes.IndexByte(p.partial, '\n')
+		if i < 0 {
+			break
+		}
+		p.handleLine(string(p.partial[:i]))
+		p.partial = p.partial[i+1:]
+	}
+	if len(p.partial) > maxPartialLine {
+		p.partial = append([]byte(nil), p.partial[len(p.partial)-maxPartialLine:]...)
+	}
+	return len(b), nil
+}
+
+func (p *progressTracker) handleLine(line string) {
+	i := strings.Index(line, progressMarker)
+	if i < 0 {
+		return
+	}
+	fields := strings.Fields(line[i+len(progressMarker):])
+	if len(fields) != 2 {
+		return
+	}
+	kind, value := fields[0], fields[1]
+	now := p.now()
+	switch kind {
+	case "plan":
+		p.plan = p.plan[:0]
+		for _, name := range strings.Split(value, "|") {
+			if name != "" {
+				p.plan = append(p.plan, name)
+			}
+		}
+		p.progress.Total = len(p.plan)
+	case "stage":
+		// 重試階段仍屬於目前的測試或 ULCL 環境，不清除 CurrentTest
+		p.progress.Stage = value
+	case "test":
+		p.progress.CurrentTest = value
+		p.testStart = now
+	case "done":
+		if value != p.progress.CurrentTest || p.done[value] {
+			return
+		}
+		p.recordDuration(value, int64(now.Sub(p.testStart).Seconds()))
+		p.done[value] = true
+		p.progress.Completed++
+		p.progress.CurrentTest = ""
+	default:
+		return
+	}
+	p.update(now)
+	if err := p.db.SaveProgress(context.Background(), &p.progress); err != nil {
+		logger.ExecutorLog.Warnf("Failed to save progress of task %s: %v", p.progress.TaskID, err)
+	}
+}
+
+// recordDuration 以加權平均更新測試的歷史秒數，避免單次異常拉偏預估
+func (p *progressTracker) recordDuration(name string, seconds int64) {
+	if old, ok := p.durations[name]; ok {
+		seconds = (old*3 + seconds) / 4
+	}
+	p.durations[name] = seconds
+	if err := p.db.SaveTestDuration(context.Background(), name, seconds); err != nil {
+		logger.ExecutorLog.Warnf("Failed to save duration of %s: %v", name, err)
+	}
+}
+
+func (p *progressTracker) update(now time.Time) {
+	p.progress.UpdatedAt = now.Unix()
+	if p.progress.Total > 0 {
+		p.progress.Percent = min(p.progress.Completed*100/p.progress.Total, 100)
+	}
+	p.progress.Remaining = p.estimate(now)
+}
+
+// estimate 依歷史秒數加總尚未完成的測試，沒有紀錄的測試以已知測試的平均估計；
+// 完全沒有紀錄時回傳 -1
+func (p *progressTracker) estimate(now time.Time) int64 {
+	var known, sum int64
+	for _, name := range p.plan {
+		if d, ok := p.durations[name]; ok {
+			known++
+			sum += d
+		}
+	}
+	if known == 0 {
+		return -1
+	}
+	average := sum / known
+
+	var remaining int64
+	for _, name := range p.plan {
+		if p.done[name] {
+			continue
+		}
+		d, ok := p.durations[name]
+		if !ok {
+			d = average
+		}
+		if name == p.progress.CurrentTest {
+			d = max(d-int64(now.Sub(p.testStart).Seconds()), 0)
+		}
+		remaining += d
+	}
+	return remaining
+}
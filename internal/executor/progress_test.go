@@ -0,0 +1,67 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"web_test/pkg/database"
+	"web_test/pkg/models"
+)
+
+func TestProgressTracker(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryDB()
+	db.SaveTestDuration(ctx, "TestA", 100)
+	db.SaveTestDuration(ctx, "ulcl:ulcl-ti", 300)
+
+	clock := time.Unix(1000, 0)
+	p := newProgressTracker(db, "7")
+	p.now = func() time.Time { return clock }
+	write := func(format string, args ...any) {
+		t.Helper()
+		fmt.Fprintf(p, format, args...)
+	}
+	get := func() *models.ProgressInfo {
+		t.Helper()
+		progress, err := db.GetProgress(ctx, "7")
+		if err != nil || progress == nil {
+			t.Fatalf("GetProgress = %+v, %v", progress, err)
+		}
+		return progress
+	}
+
+	write("header\n##progress plan TestA|TestB|ulcl:ulcl-ti\n##progress stage test_all\n")
+	if got := get(); got.Total != 3 || got.Stage != models.StageTestAll || got.Remaining != 600 {
+		t.Errorf("after plan = %+v, want 3 tests and 100+200+300 seconds left (TestB uses the average)", got)
+	}
+
+	// 標記可能接在 spinner 的 \r 重繪之後，也可能分成多次寫入
+	write("##progress test TestA\n")
+	clock = clock.Add(40 * time.Second)
+	write("\r\x1b[K ⠋ Running: TestA\r\x1b[K##progr")
+	write("ess done TestA\n")
+	got := get()
+	if got.Completed != 1 || got.Percent != 33 || got.CurrentTest != "" {
+		t.Errorf("after TestA = %+v", got)
+	}
+	// TestA 更新為 85 秒，TestB 以 (85+300)/2 估計
+	if got.Remaining != 192+300 {
+		t.Errorf("remaining = %d, want 192+300 for TestB and the ULCL environment", got.Remaining)
+	}
+	if durations, _ := db.GetTestDurations(ctx); durations["TestA"] != (100*3+40)/4 {
+		t.Errorf("TestA duration = %d, want the weighted average", durations["TestA"])
+	}
+
+	write("##progress test ulcl:ulcl-ti\n")
+	clock = clock.Add(100 * time.Second)
+	write("##progress stage retry\n")
+	if got := get(); got.CurrentTest != "ulcl:ulcl-ti" || got.Stage != models.StageRetry || got.Remaining != 192+200 {
+		t.Errorf("during retry = %+v, want ulcl-ti still running with 200 seconds left", got)
+	}
+	write("##progress done TestB\n")
+	if got := get(); got.Completed != 1 {
+		t.Errorf("done for a test that is not running was counted: %+v", got)
+	}
+}
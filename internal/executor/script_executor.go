@@ -4,35 +4,146 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"web_test/internal/logger"
+	"web_test/internal/metrics"
+	"web_test/internal/reporter"
 	"web_test/pkg/database"
 	"web_test/pkg/models"
 	"web_test/pkg/queue"
 )
 
+// reportTimeout 限制回報結果所花的時間
+const reportTimeout = time.Minute
+
+// scriptWaitDelay 為取消任務送出 SIGTERM 後，等待 run_task.sh 關閉 compose 環境並結束的時間；
+// 逾時後強制結束 sudo 並關閉輸出，不再等待仍持有輸出的子行程
+var scriptWaitDelay = 2 * time.Minute
+
 type TaskExecutor struct {
-	queue queue.TaskQueue
-	db    database.ResultStore
+	queue    queue.TaskQueue
+	db       database.ResultStore
+	reporter reporter.Reporter
+	paths    Paths
+	noSudo   bool // 直接執行 run_task.sh，測試時使用
+
+	mu     sync.Mutex
+	status Status
+}
+
+// Status 是 executor 迴圈的狀態，供 readiness 檢查
+type Status struct {
+	Running   bool      // Start 迴圈是否仍在執行
+	TaskID    string    // 執行中的任務，空值為等待任務中
+	StartedAt time.Time // 目前任務的開始時間
+}
+
+// Status 回傳 executor 迴圈目前的狀態
+func (e *TaskExecutor) Status() Status {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.status
+}
+
+func (e *TaskExecutor) setStatus(update func(s *Status)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	update(&e.status)
+}
+
+// CheckEnvironment 確認 run_task.sh 可執行且 ci-test 目錄存在
+func (e *TaskExecutor) CheckEnvironment() error {
+	info, err := os.Stat(e.paths.Script)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&0o111 == 0 {
+		return fmt.Errorf("%s is not executable", e.paths.Script)
+	}
+	ciDir := e.paths.ciDir()
+	info, err = os.Stat(ciDir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", ciDir)
+	}
+	return nil
 }
 
 func NewTaskExecutor(db database.ResultStore, q queue.TaskQueue) *TaskExecutor {
-	return &TaskExecutor{
+	e := &TaskExecutor{
 		db:    db,
 		queue: q,
 	}
+	e.SetPaths(Paths{})
+	return e
+}
+
+// Paths 為 executor 使用的腳本與目錄；空值使用預設值，相對路徑以工作目錄為準
+type Paths struct {
+	Script  string // run_task.sh，預設為工作目錄下的 run_task.sh
+	LogsDir string // run_task.sh 寫入測試 log、failures.json 與 flaky.json 的目錄（-l），預設為腳本所在目錄的 logs
+	CIDir   string // ci-test 目錄（-d），預設由 run_task.sh 決定：CI_WORK_DIR 或腳本所在目錄的 ci-test
+}
+
+// SetPaths 設定腳本與目錄，並補上預設值
+func (e *TaskExecutor) SetPaths(p Paths) {
+	if p.Script == "" {
+		p.Script = "run_task.sh"
+	}
+	p.Script = absPath(p.Script)
+	if p.LogsDir == "" {
+		p.LogsDir = filepath.Join(filepath.Dir(p.Script), "logs")
+	}
+	p.LogsDir = absPath(p.LogsDir)
+	if p.CIDir != "" {
+		p.CIDir = absPath(p.CIDir)
+	}
+	e.paths = p
+}
+
+// absPath 將相對路徑轉為絕對路徑；run_task.sh 以 sudo 執行，不能依賴呼叫端的工作目錄
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return abs
+}
+
+// ciDir 回傳 run_task.sh 會使用的 ci-test 目錄
+func (p Paths) ciDir() string {
+	if p.CIDir != "" {
+		return p.CIDir
+	}
+	if dir := os.Getenv("CI_WORK_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(filepath.Dir(p.Script), "ci-test")
+}
+
+// SetReporter 設定任務開始與結束時的回報對象，nil 表示不回報
+func (e *TaskExecutor) SetReporter(r reporter.Reporter) {
+	e.reporter = r
 }
 
 // Start 啟動 executor,持續處理任務
 func (e *TaskExecutor) Start(ctx context.Context) error {
 	logger.ExecutorLog.Info("Executor started, waiting for tasks...")
+	e.setStatus(func(s *Status) { s.Running = true })
+	defer e.setStatus(func(s *Status) { *s = Status{} })
 
 	for {
 		select {
@@ -57,6 +168,8 @@ func (e *TaskExecutor) processNextTask(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	e.setStatus(func(s *Status) { s.TaskID, s.StartedAt = task.ID, time.Now() })
+	defer e.setStatus(func(s *Status) { s.TaskID, s.StartedAt = "", time.Time{} })
 
 	// 建構日誌訊息
 	var paramStrs []string
@@ -65,82 +178,220 @@ func (e *TaskExecutor) processNextTask(ctx context.Context) error {
 	}
 	logger.ExecutorLog.Infof("Processing task %s with params: [%s]", task.ID, strings.Join(paramStrs, ", "))
 
+	// 回報開始執行，並補上未知的 PR head commit
+	if e.reporter != nil {
+		if err := e.reporter.TaskStarted(ctx, task); err != nil {
+			logger.ExecutorLog.Warnf("Failed to report start of task %s: %v", task.ID, err)
+		}
+	}
+
 	// 標記任務為執行中狀態
+	startedAt := time.Now()
+	metrics.TaskStarted(task, startedAt)
 	runningResult := &models.TaskResult{
-		TaskID:    task.ID,
-		Status:    "running",
-		Params:    task.Params,
-		Timestamp: time.Now().Unix(),
+		TaskID:      task.ID,
+		Status:      models.StatusRunning,
+		Params:      task.Params,
+		BaseRef:     task.BaseRef,
+		Selection:   task.Selection,
+		SubmittedBy: task.SubmittedBy,
+		Source:      task.Source,
+		Timestamp:   startedAt.Unix(),
+		StartedAt:   startedAt.Unix(),
 	}
 
 	if err := e.db.SaveResult(ctx, runningResult); err != nil {
+		if errors.Is(err, database.ErrIllegalTransition) {
+			// 任務已有其他狀態（例如已完成），不重複執行
+			logger.ExecutorLog.Errorf("Skip task %s: %v", task.ID, err)
+			return nil
+		}
 		logger.ExecutorLog.Errorf("Failed to save running status for task %s: %v", task.ID, err)
 	}
 
-	// 執行任務，獲取多個結果
-	e.executeTask(ctx, task)
-
-	defer func() {
-		if err := e.db.DeleteResult(context.Background(), task.ID, "running"); err != nil {
-			logger.ExecutorLog.Errorf("Failed to delete running status for task %s: %v", task.ID, err)
-		} else {
-			logger.ExecutorLog.Infof("Task %s completed, running status deleted", task.ID)
-		}
-	}()
+	// 執行任務，最終結果會在同一個原子操作中移出執行中集合
+	e.executeTask(ctx, task, startedAt)
+	logger.ExecutorLog.Infof("Task %s completed", task.ID)
 
 	return nil
 }
 
-func (e *TaskExecutor) executeTask(ctx context.Context, task *models.Task) {
-	IsSuccess := e.cmdrun(ctx, task)
+func (e *TaskExecutor) executeTask(ctx context.Context, task *models.Task, startedAt time.Time) {
+	// 清除上一個任務留下的 failures.json 與 flaky.json，run_task.sh 提早結束時才不會誤用
+	for _, name := range []string{"failures.json", "flaky.json"} {
+		if err := os.Remove(filepath.Join(e.paths.LogsDir, name)); err != nil && !os.IsNotExist(err) {
+			logger.ExecutorLog.Warnf("Failed to remove old %s: %v", name, err)
+		}
+	}
+	watcher := &cancelWatcher{db: e.db, taskID: task.ID, interval: cancelPollInterval}
+	taskCtx, stopWatch := watcher.watch(ctx)
+	exitCode := e.cmdrun(taskCtx, task)
+	stopWatch()
+	cancelledBy := watcher.cancelledBy()
+	// 任務結束後不再需要進度與取消要求，最終結果由 saveFinalResult 寫入
+	if err := e.db.DeleteProgress(context.Background(), task.ID); err != nil {
+		logger.ExecutorLog.Warnf("Failed to delete progress of task %s: %v", task.ID, err)
+	}
+	if err := e.db.ClearCancel(context.Background(), task.ID); err != nil {
+		logger.ExecutorLog.Warnf("Failed to clear cancel request of task %s: %v", task.ID, err)
+	}
 
-	if IsSuccess {
-		result := &models.TaskResult{
+	var result *models.TaskResult
+	if cancelledBy != "" {
+		result = &models.TaskResult{
 			TaskID:    task.ID,
-			Status:    "Success",
+			Status:    models.StatusFailed,
+			Params:    task.Params,
+			Logs:      []string{fmt.Sprintf("Task cancelled by %s", cancelledBy)},
+			Timestamp: time.Now().Unix(),
+		}
+	} else if exitCode == 0 {
+		result = &models.TaskResult{
+			TaskID:    task.ID,
+			Status:    models.StatusSuccess,
 			Params:    task.Params,
 			Timestamp: time.Now().Unix(),
 		}
-		e.db.SaveResult(ctx, result)
 	} else {
-		e.handleFailedTests(ctx, task)
+		result = e.collectFailedTests(task)
+	}
+	result.BaseRef = task.BaseRef
+	result.Selection = task.Selection
+	result.SubmittedBy = task.SubmittedBy
+	result.Source = task.Source
+	result.Verdict = models.VerdictFromExitCode(exitCode)
+	if cancelledBy != "" {
+		result.Verdict = models.VerdictCancelled
 	}
+	result.FlakyTests = e.readFlakyTests()
+	e.saveFinalResult(result, startedAt)
+}
 
+// saveFinalResult 補上開始、結束時間與執行秒數後儲存最終結果並回報；
+// 即使 ctx 已取消也要寫入，避免任務卡在執行中
+func (e *TaskExecutor) saveFinalResult(result *models.TaskResult, startedAt time.Time) error {
+	result.StartedAt = startedAt.Unix()
+	result.FinishedAt = result.Timestamp
+	result.Duration = result.FinishedAt - result.StartedAt
+	if err := e.db.SaveResult(context.Background(), result); err != nil {
+		logger.ExecutorLog.Errorf("Failed to save result for task %s: %v", result.TaskID, err)
+		return err
+	}
+	logger.ExecutorLog.Infof("Saved result for task %s: %s (verdict %s, %d failed, %d flaky)",
+		result.TaskID, result.Status, result.Verdict, len(result.FailedTests), len(result.FlakyTests))
+	metrics.TaskFinished(result)
+
+	if e.reporter != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), reportTimeout)
+		defer cancel()
+		if err := e.reporter.TaskFinished(ctx, result); err != nil {
+			logger.ExecutorLog.Warnf("Failed to report result of task %s: %v", result.TaskID, err)
+		}
+	}
+	return nil
 }
 
-func (e *TaskExecutor) cmdrun(ctx context.Context, task *models.Task) bool {
+// cmdrun 執行 run_task.sh 並回傳結束碼；無法啟動或被中斷時回傳 -1
+func (e *TaskExecutor) cmdrun(ctx context.Context, task *models.Task) int {
 	// 建構命令參數
-	args := []string{"-n"}
+	args := []string{"-n", "-l", e.paths.LogsDir}
+	if e.paths.CIDir != "" {
+		args = append(args, "-d", e.paths.CIDir)
+	}
+	if task.BaseRef != "" {
+		args = append(args, "-b", task.BaseRef)
+	}
+	// 只執行挑選的測試與 ULCL 環境，nil 或 Full 時執行完整測試
+	if sel := task.Selection; sel != nil && !sel.Full {
+		args = append(args, "-t", strings.Join(sel.Tests, "|"))
+		for _, env := range sel.Envs {
+			args = append(args, "-e", env)
+		}
+	}
 	for _, param := range task.Params {
-		args = append(args, "-p", fmt.Sprintf("%s:%s", param.NF, param.PRVersion))
+		// 有 head commit 時以 nf:pr:sha 傳入，fetch 階段會確認取出的正是該 commit
+		arg := fmt.Sprintf("%s:%s", param.NF, param.PRVersion)
+		if param.HeadSHA != "" {
+			arg += ":" + param.HeadSHA
+		}
+		args = append(args, "-p", arg)
 	}
 
-	wd, _ := os.Getwd()
-	scriptPath := filepath.Clean(filepath.Join(wd, "run_task.sh"))
-
 	// 執行 run_task.sh，傳遞多個 -p 參數
-	cmd := exec.CommandContext(ctx, "sudo", append([]string{scriptPath}, args...)...)
+	cmd := e.scriptCommand(ctx, args)
 
 	var logBuffer bytes.Buffer
 
-	multiWriter := io.MultiWriter(os.Stdout, &logBuffer)
+	multiWriter := io.MultiWriter(os.Stdout, &logBuffer, newProgressTracker(e.db, task.ID))
 
 	cmd.Stdout = multiWriter
 	cmd.Stderr = multiWriter
 
 	err := cmd.Run()
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() >= 0 {
+		logger.ExecutorLog.Infof("run_task.sh for task %s exited with code %d", task.ID, exitErr.ExitCode())
+		return exitErr.ExitCode()
+	}
+	logger.ExecutorLog.Errorf("run_task.sh for task %s failed: %v", task.ID, err)
+	return -1
+}
 
-	// 如果命令失敗，返回錯誤
-	if err != nil {
-		return false
+// scriptCommand 以 sudo 在獨立的 process group 執行 run_task.sh。ctx 取消時只終止 sudo 無法停止
+// run_task.sh 與它以 root 啟動的 docker、go test，因此改為對整個 process group 送出 SIGTERM，
+// 由 run_task.sh 的 trap 關閉 compose 環境
+func (e *TaskExecutor) scriptCommand(ctx context.Context, args []string) *exec.Cmd {
+	name, args := "sudo", append([]string{e.paths.Script}, args...)
+	if e.noSudo {
+		name, args = args[0], args[1:]
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		if err := e.terminateGroup(cmd.Process.Pid); err != nil {
+			logger.ExecutorLog.Warnf("Failed to terminate process group %d: %v", cmd.Process.Pid, err)
+			return cmd.Process.Kill()
+		}
+		return nil
 	}
+	cmd.WaitDelay = scriptWaitDelay
+	return cmd
+}
 
-	return true
+// terminateGroup 對 process group 送出 SIGTERM；子行程以 root 執行，需要經由 sudo kill
+func (e *TaskExecutor) terminateGroup(pgid int) error {
+	args := []string{"kill", "-TERM", "--", "-" + strconv.Itoa(pgid)}
+	if !e.noSudo {
+		args = append([]string{"sudo", "-n"}, args...)
+	}
+	if out, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// readFlakyTests 讀取 run_task.sh 寫入 LogsDir 的 flaky.json（重跑後通過的測試），不存在時回傳 nil
+func (e *TaskExecutor) readFlakyTests() []string {
+	data, err := os.ReadFile(filepath.Join(e.paths.LogsDir, "flaky.json"))
+	if err != nil {
+		return nil
+	}
+	var flaky struct {
+		FlakyTests []string `json:"flaky_tests"`
+	}
+	if err := json.Unmarshal(data, &flaky); err != nil {
+		logger.ExecutorLog.Warnf("Failed to parse flaky.json: %v", err)
+		return nil
+	}
+	return flaky.FlakyTests
 }
 
-func (e *TaskExecutor) handleFailedTests(ctx context.Context, task *models.Task) {
-	wd, _ := os.Getwd()
-	failuresPath := filepath.Clean(filepath.Join(wd, "logs", "failures.json"))
+// collectFailedTests 依 LogsDir 的 failures.json 讀取失敗測試與其 log，產生失敗結果（尚未儲存）
+func (e *TaskExecutor) collectFailedTests(task *models.Task) *models.TaskResult {
+	failuresPath := filepath.Join(e.paths.LogsDir, "failures.json")
 
 	logger.ExecutorLog.Infof("Reading failures from: %s", failuresPath)
 
@@ -149,16 +400,14 @@ func (e *TaskExecutor) handleFailedTests(ctx context.Context, task *models.Task)
 	if err != nil {
 		logger.ExecutorLog.Errorf("Failed to read failures.json: %v", err)
 		// 如果找不到 failures.json，存儲通用失敗結果
-		result := &models.TaskResult{
+		return &models.TaskResult{
 			TaskID:      task.ID,
-			Status:      "Failed",
+			Status:      models.StatusFailed,
 			Params:      task.Params,
 			Logs:        []string{"Task execution failed, but failures.json not found"},
 			FailedTests: []string{"JsonNotFound"},
 			Timestamp:   time.Now().Unix(),
 		}
-		e.db.SaveResult(ctx, result)
-		return
 	}
 
 	// 解析 JSON
@@ -167,7 +416,14 @@ func (e *TaskExecutor) handleFailedTests(ctx context.Context, task *models.Task)
 	}
 	if err := json.Unmarshal(data, &failureData); err != nil {
 		logger.ExecutorLog.Errorf("Failed to parse failures.json: %v", err)
-		return
+		return &models.TaskResult{
+			TaskID:      task.ID,
+			Status:      models.StatusFailed,
+			Params:      task.Params,
+			Logs:        []string{fmt.Sprintf("Task execution failed, but failures.json is invalid: %v", err)},
+			FailedTests: []string{"JsonInvalid"},
+			Timestamp:   time.Now().Unix(),
+		}
 	}
 
 	logger.ExecutorLog.Infof("Found %d failed tests", len(failureData.FailedTests))
@@ -176,7 +432,7 @@ func (e *TaskExecutor) handleFailedTests(ctx context.Context, task *models.Task)
 	var failedTestNames []string
 
 	// 為每個失敗的測試讀取 log 檔案並存儲
-	logsDir := filepath.Clean(filepath.Join(wd, "logs"))
+	logsDir := e.paths.LogsDir
 	for _, testLogFile := range failureData.FailedTests {
 		logFilePath := filepath.Join(logsDir, testLogFile)
 
@@ -199,20 +455,12 @@ func (e *TaskExecutor) handleFailedTests(ctx context.Context, task *models.Task)
 		logger.ExecutorLog.Infof("Successfully read log file for failed test: %s", testName)
 	}
 
-	result := &models.TaskResult{
+	return &models.TaskResult{
 		TaskID:      task.ID,
-		Status:      "Failed",
+		Status:      models.StatusFailed,
 		Params:      task.Params,
 		Logs:        allLogs,
 		FailedTests: failedTestNames,
 		Timestamp:   time.Now().Unix(),
 	}
-
-	if err := e.db.SaveResult(ctx, result); err != nil {
-		logger.ExecutorLog.Errorf("Failed to save result for task %s: %v", task.ID, err)
-		return
-	}
-
-	logger.ExecutorLog.Infof("Successfully saved result for task %s with %d failed tests", task.ID, len(failedTestNames))
-
 }
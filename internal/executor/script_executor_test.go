@@ -0,0 +1,62 @@
+package executor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"web_test/pkg/database"
+	"web_test/pkg/models"
+	"web_test/pkg/queue"
+)
+
+func TestStaleLogFilesIgnored(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "run_task.sh")
+	// 佇列中的 commit 已取不到，run_task.sh 在 fetch 階段就結束
+	if err := os.WriteFile(script, []byte("#!/bin/bash\nexit 9\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	logsDir := filepath.Join(dir, "logs")
+	if err := os.MkdirAll(logsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// 上一個任務留下的結果
+	for name, content := range map[string]string{
+		"failures.json":  `{"failed_tests": ["TestPaging.log"]}`,
+		"flaky.json":     `{"flaky_tests": ["TestTngf"]}`,
+		"TestPaging.log": "--- FAIL: TestPaging\nexit status 1\n",
+	} {
+		if err := os.WriteFile(filepath.Join(logsDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	db := database.NewMemoryDB()
+	q := queue.NewListQueue()
+	e := NewTaskExecutor(db, q)
+	e.SetPaths(Paths{Script: script})
+	e.noSudo = true
+
+	q.PushTask(ctx, &models.Task{ID: "2", Params: []models.TaskParams{{NF: "amf", PRVersion: "12", HeadSHA: "abc123"}}})
+	if err := e.processNextTask(ctx); err != nil {
+		t.Fatal(err)
+	}
+	result, err := db.GetResult(ctx, "2")
+	if err != nil || result == nil {
+		t.Fatalf("result = %+v, %v", result, err)
+	}
+	if result.Verdict != models.VerdictStale {
+		t.Errorf("verdict = %q, want %q", result.Verdict, models.VerdictStale)
+	}
+	for _, name := range result.FailedTests {
+		if name == "TestPaging" {
+			t.Errorf("failed tests = %v, include the previous task's TestPaging", result.FailedTests)
+		}
+	}
+	if len(result.FlakyTests) != 0 {
+		t.Errorf("flaky tests = %v, want none", result.FlakyTests)
+	}
+}
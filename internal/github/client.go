@@ -0,0 +1,425 @@
+// Package github 是 CI 使用的 GitHub REST API client。
+// 支援 token 認證、依 Link header 分頁，並依 X-RateLimit-* header 在額度用盡時等待重置。
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"web_test/internal/logger"
+	"web_test/internal/metrics"
+	"web_test/pkg/models"
+)
+
+const (
+	// DefaultBaseURL 為 github.com 的 REST API 位址
+	DefaultBaseURL = "https://api.github.com"
+	// TokenEnv 為未在設定檔指定 token 時讀取的環境變數
+	TokenEnv = "GITHUB_TOKEN"
+
+	userAgent = "web_test-ci"
+	perPage   = 100
+	// maxPages 避免異常的 Link header 造成無限分頁
+	maxPages = 50
+	// defaultMaxWait 為額度用盡時願意等待重置的最長時間，超過則直接回傳 RateLimitError
+	defaultMaxWait = time.Minute
+)
+
+// ErrRateLimited 表示 GitHub API 額度已用盡
+var ErrRateLimited = errors.New("github API rate limit exceeded")
+
+// APIError 是 GitHub 回傳非 2xx 狀態碼時的錯誤
+type APIError struct {
+	StatusCode int
+	Method     string
+	URL        string
+	Message    string // GitHub 回傳的 message 欄位
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("github: %s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("github: %s %s: %d %s", e.Method, e.URL, e.StatusCode, e.Message)
+}
+
+// RateLimitError 表示額度用盡，且重置時間超過願意等待的上限
+type RateLimitError struct {
+	Limit int
+	Reset time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v (limit %d, resets at %s)", ErrRateLimited, e.Limit, e.Reset.UTC().Format(time.RFC3339))
+}
+
+func (e *RateLimitError) Unwrap() error { return ErrRateLimited }
+
+// RateLimit 是最近一次回應的 X-RateLimit-* 資訊
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// Client 是 GitHub REST API client，可同時給多個 goroutine 使用
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	token      string
+	maxWait    time.Duration
+
+	mu        sync.Mutex
+	rateLimit *RateLimit
+}
+
+// NewClient 建立 client。baseURL 為空字串時使用 DefaultBaseURL，
+// GitHub Enterprise 請使用 https://<host>/api/v3。
+// token 為空字串時以匿名方式呼叫 API（每小時 60 次）。
+func NewClient(baseURL, token string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		maxWait:    defaultMaxWait,
+	}
+}
+
+// BaseURL 回傳 API 位址
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// Authenticated 回傳 client 是否帶有 token
+func (c *Client) Authenticated() bool {
+	return c.token != ""
+}
+
+// RateLimit 回傳最近一次回應的額度資訊，尚未呼叫過 API 時回傳 nil
+func (c *Client) RateLimit() *RateLimit {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rateLimit == nil {
+		return nil
+	}
+	rl := *c.rateLimit
+	return &rl
+}
+
+// PullRequest 是單一 PR 中 CI 需要的欄位
+type PullRequest struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	State  string `json:"state"`
+	Draft  bool   `json:"draft"`
+	User   struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Head struct {
+		SHA string `json:"sha"`
+		Ref string `json:"ref"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	MergeableState string    `json:"mergeable_state"` // 只有 GetPull 的回應有此欄位
+}
+
+func (pr *PullRequest) toModel() models.PullRequest {
+	m := models.PullRequest{
+		Number:         pr.Number,
+		Title:          pr.Title,
+		Author:         pr.User.Login,
+		HeadSHA:        pr.Head.SHA,
+		BaseRef:        pr.Base.Ref,
+		Draft:          pr.Draft,
+		MergeableState: pr.MergeableState,
+	}
+	for _, label := range pr.Labels {
+		m.Labels = append(m.Labels, label.Name)
+	}
+	if !pr.UpdatedAt.IsZero() {
+		m.UpdatedAt = pr.UpdatedAt.Unix()
+	}
+	return m
+}
+
+// PullsResult 是 ListOpenPulls 的結果
+type PullsResult struct {
+	PRs         []models.PullRequest
+	ETag        string // 第一頁的 ETag，供下次條件式請求使用
+	NotModified bool   // 以 etag 發出的條件式請求回傳 304，PRs 為空
+}
+
+// ListOpenPulls 依 Link header 取得 owner/repo 所有開啟中的 PR。
+// etag 不為空時第一頁以 If-None-Match 發出條件式請求，內容未變更則回傳 NotModified。
+func (c *Client) ListOpenPulls(ctx context.Context, owner, repo, etag string) (*PullsResult, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls?state=open&per_page=%d", c.baseURL, owner, repo, perPage)
+	result := &PullsResult{PRs: []models.PullRequest{}}
+
+	for page := 0; url != ""; page++ {
+		if page >= maxPages {
+			return nil, fmt.Errorf("github: %s/%s has more than %d pages of pull requests", owner, repo, maxPages)
+		}
+		header := http.Header{}
+		if page == 0 && etag != "" {
+			header.Set("If-None-Match", etag)
+		}
+		res, err := c.do(ctx, http.MethodGet, url, header, nil)
+		if err != nil {
+			return nil, err
+		}
+		if page == 0 {
+			if res.StatusCode == http.StatusNotModified {
+				res.Body.Close()
+				return &PullsResult{ETag: etag, NotModified: true}, nil
+			}
+			result.ETag = res.Header.Get("ETag")
+		}
+
+		var prs []PullRequest
+		err = decodeBody(res, &prs)
+		if err != nil {
+			return nil, err
+		}
+		for _, pr := range prs {
+			result.PRs = append(result.PRs, pr.toModel())
+		}
+		url = nextPageURL(res.Header.Get("Link"))
+	}
+	return result, nil
+}
+
+// GetPull 取得單一 PR
+func (c *Client) GetPull(ctx context.Context, owner, repo string, number int) (*PullRequest, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", c.baseURL, owner, repo, number)
+	res, err := c.do(ctx, http.MethodGet, url, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var pr PullRequest
+	if err := decodeBody(res, &pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// ListPullFiles 依 Link header 取得 PR 變更的檔案路徑（GitHub 最多回傳 3000 個）
+func (c *Client) ListPullFiles(ctx context.Context, owner, repo string, number int) ([]string, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/files?per_page=%d", c.baseURL, owner, repo, number, perPage)
+	files := []string{}
+	for page := 0; url != ""; page++ {
+		if page >= maxPages {
+			return nil, fmt.Errorf("github: %s/%s#%d has more than %d pages of files", owner, repo, number, maxPages)
+		}
+		res, err := c.do(ctx, http.MethodGet, url, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var batch []struct {
+			Filename string `json:"filename"`
+		}
+		if err := decodeBody(res, &batch); err != nil {
+			return nil, err
+		}
+		for _, f := range batch {
+			files = append(files, f.Filename)
+		}
+		url = nextPageURL(res.Header.Get("Link"))
+	}
+	return files, nil
+}
+
+// LatestRelease 取得 owner/repo 最新的 release，沒有任何 release 時回傳 nil, nil
+func (c *Client) LatestRelease(ctx context.Context, owner, repo string) (*models.Release, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/releases?per_page=1", c.baseURL, owner, repo)
+	res, err := c.do(ctx, http.MethodGet, url, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var rels []models.Release
+	if err := decodeBody(res, &rels); err != nil {
+		return nil, err
+	}
+	if len(rels) == 0 {
+		return nil, nil
+	}
+	return &rels[0], nil
+}
+
+// ListReleases 取得 owner/repo 最新的 perPage 個 release，最新的在前
+func (c *Client) ListReleases(ctx context.Context, owner, repo string) ([]models.Release, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/releases?per_page=%d", c.baseURL, owner, repo, perPage)
+	res, err := c.do(ctx, http.MethodGet, url, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	rels := []models.Release{}
+	if err := decodeBody(res, &rels); err != nil {
+		return nil, err
+	}
+	return rels, nil
+}
+
+// do 發出請求並記錄額度；額度用盡時等待重置後重試一次。
+// body 不為 nil 時以 JSON 送出。
+// 回傳的 response 狀態碼為 2xx 或 304，其餘狀態碼轉成 *APIError。
+func (c *Client) do(ctx context.Context, method, url string, header http.Header, body interface{}) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("github: encode request: %w", err)
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		if err := c.waitForReset(ctx); err != nil {
+			return nil, err
+		}
+
+		var reqBody io.Reader
+		if payload != nil {
+			reqBody = bytes.NewReader(payload)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+		if err != nil {
+			return nil, fmt.Errorf("github: build request: %w", err)
+		}
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		req.Header.Set("User-Agent", userAgent)
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+
+		logger.GitHubLog.Debugf("%s %s", method, url)
+		res, err := c.httpClient.Do(req)
+		if err != nil {
+			metrics.GitHubRequestsTotal.WithLabelValues(method, "error").Inc()
+			return nil, fmt.Errorf("github: %s %s: %w", method, url, err)
+		}
+		metrics.GitHubRequestsTotal.WithLabelValues(method, strconv.Itoa(res.StatusCode)).Inc()
+		rl := c.recordRateLimit(res.Header)
+
+		if res.StatusCode < 300 || res.StatusCode == http.StatusNotModified {
+			return res, nil
+		}
+
+		apiErr := newAPIError(res, method, url)
+		if isRateLimited(res.StatusCode, rl) {
+			if attempt == 0 && rl != nil && time.Until(rl.Reset) <= c.maxWait {
+				logger.GitHubLog.Warnf("Rate limit exceeded, waiting until %s", rl.Reset.Format(time.RFC3339))
+				continue
+			}
+			if rl != nil {
+				return nil, &RateLimitError{Limit: rl.Limit, Reset: rl.Reset}
+			}
+			return nil, fmt.Errorf("%w: %v", ErrRateLimited, apiErr)
+		}
+		return nil, apiErr
+	}
+}
+
+// waitForReset 在上一次回應顯示額度已用盡時，等待到重置時間
+func (c *Client) waitForReset(ctx context.Context) error {
+	rl := c.RateLimit()
+	if rl == nil || rl.Remaining > 0 {
+		return nil
+	}
+	wait := time.Until(rl.Reset)
+	if wait <= 0 {
+		return nil
+	}
+	if wait > c.maxWait {
+		return &RateLimitError{Limit: rl.Limit, Reset: rl.Reset}
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// recordRateLimit 解析 X-RateLimit-* header，header 不完整時回傳 nil
+func (c *Client) recordRateLimit(h http.Header) *RateLimit {
+	limit, err1 := strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	remaining, err2 := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	reset, err3 := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return nil
+	}
+	rl := &RateLimit{Limit: limit, Remaining: remaining, Reset: time.Unix(reset, 0)}
+	c.mu.Lock()
+	c.rateLimit = rl
+	c.mu.Unlock()
+	if remaining == 0 {
+		logger.GitHubLog.Warnf("Rate limit exhausted (limit %d), resets at %s", limit, rl.Reset.Format(time.RFC3339))
+	}
+	return rl
+}
+
+// isRateLimited 判斷錯誤回應是否為額度用盡（GitHub 以 403 或 429 回傳）
+func isRateLimited(status int, rl *RateLimit) bool {
+	if status == http.StatusTooManyRequests {
+		return true
+	}
+	return status == http.StatusForbidden && rl != nil && rl.Remaining == 0
+}
+
+func newAPIError(res *http.Response, method, url string) *APIError {
+	defer res.Body.Close()
+	apiErr := &APIError{StatusCode: res.StatusCode, Method: method, URL: url}
+	var body struct {
+		Message string `json:"message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+	if json.Unmarshal(data, &body) == nil {
+		apiErr.Message = body.Message
+	}
+	return apiErr
+}
+
+func decodeBody(res *http.Response, v interface{}) error {
+	defer res.Body.Close()
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return fmt.Errorf("github: decode %s: %w", res.Request.URL, err)
+	}
+	return nil
+}
+
+var linkNextRe = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="next"`)
+
+// nextPageURL 從 Link header 取出 rel="next" 的網址，沒有下一頁時回傳空字串
+func nextPageURL(link string) string {
+	m := linkNextRe.FindStringSubmatch(link)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
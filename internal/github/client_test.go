@@ -0,0 +1,141 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return NewClient(srv.URL+"/", "secret")
+}
+
+func TestListOpenPullsPagination(t *testing.T) {
+	var c *Client
+	c = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		if page < 3 {
+			next := fmt.Sprintf("%s/repos/free5gc/amf/pulls?state=open&per_page=100&page=%d", c.baseURL, page+1)
+			w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next", <%s>; rel="last"`, next, next))
+		}
+		if page == 1 {
+			w.Header().Set("ETag", `"v1"`)
+		}
+		fmt.Fprintf(w, `[{"number":%d,"title":"pr %d"}]`, page, page)
+	})
+
+	res, err := c.ListOpenPulls(context.Background(), "free5gc", "amf", "")
+	if err != nil {
+		t.Fatalf("ListOpenPulls: %v", err)
+	}
+	if len(res.PRs) != 3 || res.PRs[2].Number != 3 {
+		t.Fatalf("PRs = %+v, want 3 pages", res.PRs)
+	}
+	if res.ETag != `"v1"` {
+		t.Errorf("ETag = %q, want first page ETag", res.ETag)
+	}
+}
+
+func TestListOpenPullsNotModified(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != `"v1"` {
+			t.Errorf("If-None-Match = %q", r.Header.Get("If-None-Match"))
+		}
+		w.WriteHeader(http.StatusNotModified)
+	})
+
+	res, err := c.ListOpenPulls(context.Background(), "free5gc", "amf", `"v1"`)
+	if err != nil {
+		t.Fatalf("ListOpenPulls: %v", err)
+	}
+	if !res.NotModified || res.ETag != `"v1"` {
+		t.Fatalf("result = %+v, want NotModified with the same ETag", res)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"Not Found"}`)
+	})
+
+	_, err := c.ListOpenPulls(context.Background(), "free5gc", "nope", "")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "Not Found" {
+		t.Fatalf("err = %v, want 404 APIError", err)
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `not json`)
+	})
+
+	if _, err := c.ListOpenPulls(context.Background(), "free5gc", "amf", ""); err == nil {
+		t.Fatal("expected decode error")
+	}
+}
+
+func TestRateLimitWaitsForReset(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		w.Header().Set("X-RateLimit-Limit", "60")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Second).Unix(), 10))
+		if n == 1 {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message":"API rate limit exceeded"}`)
+			return
+		}
+		w.Header().Set("X-RateLimit-Remaining", "59")
+		fmt.Fprint(w, `[]`)
+	})
+	c.maxWait = 5 * time.Second
+
+	if _, err := c.ListOpenPulls(context.Background(), "free5gc", "amf", ""); err != nil {
+		t.Fatalf("ListOpenPulls: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("calls = %d, want retry after reset", calls)
+	}
+	if rl := c.RateLimit(); rl == nil || rl.Remaining != 59 {
+		t.Fatalf("RateLimit = %+v", rl)
+	}
+}
+
+func TestRateLimitTooLong(t *testing.T) {
+	reset := time.Now().Add(time.Hour)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "60")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	_, err := c.ListOpenPulls(context.Background(), "free5gc", "amf", "")
+	var rlErr *RateLimitError
+	if !errors.Is(err, ErrRateLimited) || !errors.As(err, &rlErr) || rlErr.Reset.Unix() != reset.Unix() {
+		t.Fatalf("err = %v, want RateLimitError", err)
+	}
+
+	// 額度仍用盡時，後續請求不應再送出
+	if _, err := c.LatestRelease(context.Background(), "free5gc", "amf"); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err = %v, want ErrRateLimited without calling the API", err)
+	}
+}
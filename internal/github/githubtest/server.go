@@ -0,0 +1,436 @@
+// Package githubtest 提供測試用的 GitHub REST API 假伺服器，
+// 以預先放入的 pulls、releases 與 commits 回應，讓 PR 流程可以離線整合測試。
+//
+// 支援的端點：
+//
+//	GET /repos/{owner}/{repo}/pulls           （state=open，依 per_page/page 分頁並回傳 Link 與 ETag）
+//	GET /repos/{owner}/{repo}/releases        （最新的在前，支援 per_page/page）
+//	GET /repos/{owner}/{repo}/commits         （最新的在前，支援 per_page/page）
+//	GET /repos/{owner}/{repo}/commits/{sha}
+//	GET /repos/{owner}/{repo}/pulls/{number}  （含 mergeable_state）
+//	GET /repos/{owner}/{repo}/pulls/{number}/files（支援 per_page/page）
+//	POST /repos/{owner}/{repo}/statuses/{sha}
+//	GET|POST /repos/{owner}/{repo}/issues/{number}/comments
+//	PATCH /repos/{owner}/{repo}/issues/comments/{id}
+//
+// 回應都帶有 X-RateLimit-* header，額度可用 SetRateLimit 調整。
+package githubtest
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Pull 是假伺服器上的 pull request
+type Pull struct {
+	Number  int
+	Title   string
+	State   string // 空字串視為 "open"
+	HeadSHA string
+	HeadRef string
+	BaseRef string // 空字串視為 "main"
+	User    string
+	Labels  []string
+	Draft   bool
+	// Mergeable 為 mergeable_state，只出現在單一 PR 的回應中；空字串視為 "clean"
+	Mergeable string
+	UpdatedAt time.Time
+	Files     []string // 變更的檔案
+}
+
+// Release 是假伺服器上的 release
+type Release struct {
+	Name    string
+	TagName string
+}
+
+// Commit 是假伺服器上的 commit
+type Commit struct {
+	SHA     string
+	Message string
+}
+
+// Status 是 CI 建立的 commit status
+type Status struct {
+	State       string `json:"state"`
+	TargetURL   string `json:"target_url"`
+	Description string `json:"description"`
+	Context     string `json:"context"`
+}
+
+// Comment 是 issue 或 PR 上的留言
+type Comment struct {
+	ID   int64  `json:"id"`
+	Body string `json:"body"`
+}
+
+type repoData struct {
+	pulls    []Pull
+	releases []Release // 最新的在前
+	commits  []Commit  // 最新的在前
+	statuses map[string][]Status
+	comments map[int][]*Comment
+}
+
+// Server 是假的 GitHub API，URL 可直接作為 github.NewClient 的 baseURL
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	repos     map[string]*repoData
+	commentID int64
+	requests  []string
+	limit     int
+	remaining int
+	reset     time.Time
+}
+
+// NewServer 啟動假伺服器，測試結束時請呼叫 Close
+func NewServer() *Server {
+	s := &Server{
+		repos:     make(map[string]*repoData),
+		limit:     5000,
+		remaining: 5000,
+		reset:     time.Now().Add(time.Hour),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func (s *Server) repo(owner, repo string) *repoData {
+	key := owner + "/" + repo
+	data, ok := s.repos[key]
+	if !ok {
+		data = &repoData{statuses: make(map[string][]Status), comments: make(map[int][]*Comment)}
+		s.repos[key] = data
+	}
+	return data
+}
+
+// AddPull 新增或取代 owner/repo 上同號碼的 PR
+func (s *Server) AddPull(owner, repo string, pr Pull) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data := s.repo(owner, repo)
+	for i := range data.pulls {
+		if data.pulls[i].Number == pr.Number {
+			data.pulls[i] = pr
+			return
+		}
+	}
+	data.pulls = append(data.pulls, pr)
+}
+
+// ClosePull 將 PR 標記為 closed，之後不會出現在開啟中的 PR 列表
+func (s *Server) ClosePull(owner, repo string, number int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data := s.repo(owner, repo)
+	for i := range data.pulls {
+		if data.pulls[i].Number == number {
+			data.pulls[i].State = "closed"
+		}
+	}
+}
+
+// AddRelease 新增 release，最後加入的視為最新
+func (s *Server) AddRelease(owner, repo string, rel Release) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data := s.repo(owner, repo)
+	data.releases = append([]Release{rel}, data.releases...)
+}
+
+// AddCommit 新增 commit，最後加入的視為最新
+func (s *Server) AddCommit(owner, repo string, commit Commit) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data := s.repo(owner, repo)
+	data.commits = append([]Commit{commit}, data.commits...)
+}
+
+// AddComment 在 issue 或 PR 上新增留言，回傳留言 ID
+func (s *Server) AddComment(owner, repo string, number int, body string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addComment(s.repo(owner, repo), number, body).ID
+}
+
+func (s *Server) addComment(data *repoData, number int, body string) *Comment {
+	s.commentID++
+	comment := &Comment{ID: s.commentID, Body: body}
+	data.comments[number] = append(data.comments[number], comment)
+	return comment
+}
+
+// Statuses 回傳 sha 上的 commit status，依建立順序
+func (s *Server) Statuses(owner, repo, sha string) []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Status(nil), s.repo(owner, repo).statuses[sha]...)
+}
+
+// Comments 回傳 issue 或 PR 上的留言，依建立順序
+func (s *Server) Comments(owner, repo string, number int) []Comment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var comments []Comment
+	for _, c := range s.repo(owner, repo).comments[number] {
+		comments = append(comments, *c)
+	}
+	return comments
+}
+
+// SetRateLimit 設定剩餘額度與重置時間；remaining 為 0 時 API 回傳 403
+func (s *Server) SetRateLimit(remaining int, reset time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remaining = remaining
+	s.reset = reset
+}
+
+// Requests 回傳收到的請求（"METHOD path?query"），依收到的順序
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.RequestURI())
+
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(s.limit))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(s.reset.Unix(), 10))
+	if s.remaining <= 0 {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		writeMessage(w, http.StatusForbidden, "API rate limit exceeded")
+		return
+	}
+	s.remaining--
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(s.remaining))
+
+	// /repos/{owner}/{repo}/{kind}[/...]
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 4 || parts[0] != "repos" {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	data, ok := s.repos[parts[1]+"/"+parts[2]]
+	if !ok {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	route := r.Method + " " + strings.Join(parts[3:], "/")
+
+	switch {
+	case route == "GET pulls":
+		var items []interface{}
+		for _, pr := range data.pulls {
+			if pr.State == "" || pr.State == "open" {
+				items = append(items, pullJSON(parts[1], parts[2], pr))
+			}
+		}
+		s.writePage(w, r, items)
+	case r.Method == http.MethodGet && parts[3] == "pulls" && len(parts) == 5:
+		number, _ := strconv.Atoi(parts[4])
+		for _, pr := range data.pulls {
+			if pr.Number == number {
+				item := pullJSON(parts[1], parts[2], pr)
+				item["mergeable_state"] = pr.Mergeable
+				if pr.Mergeable == "" {
+					item["mergeable_state"] = "clean"
+				}
+				writeJSON(w, r, http.StatusOK, item)
+				return
+			}
+		}
+		writeMessage(w, http.StatusNotFound, "Not Found")
+	case r.Method == http.MethodGet && parts[3] == "pulls" && len(parts) == 6 && parts[5] == "files":
+		number, _ := strconv.Atoi(parts[4])
+		for _, pr := range data.pulls {
+			if pr.Number == number {
+				var items []interface{}
+				for _, name := range pr.Files {
+					items = append(items, map[string]interface{}{"filename": name, "status": "modified"})
+				}
+				s.writePage(w, r, items)
+				return
+			}
+		}
+		writeMessage(w, http.StatusNotFound, "Not Found")
+	case route == "GET releases":
+		var items []interface{}
+		for _, rel := range data.releases {
+			items = append(items, map[string]interface{}{"name": rel.Name, "tag_name": rel.TagName})
+		}
+		s.writePage(w, r, items)
+	case route == "GET commits":
+		var items []interface{}
+		for _, commit := range data.commits {
+			items = append(items, commitJSON(commit))
+		}
+		s.writePage(w, r, items)
+	case r.Method == http.MethodGet && parts[3] == "commits" && len(parts) == 5:
+		for _, commit := range data.commits {
+			if strings.HasPrefix(commit.SHA, parts[4]) {
+				writeJSON(w, r, http.StatusOK, commitJSON(commit))
+				return
+			}
+		}
+		writeMessage(w, http.StatusUnprocessableEntity, "No commit found for SHA: "+parts[4])
+	case r.Method == http.MethodPost && parts[3] == "statuses" && len(parts) == 5:
+		var status Status
+		if err := json.NewDecoder(r.Body).Decode(&status); err != nil || status.State == "" {
+			writeMessage(w, http.StatusUnprocessableEntity, "Validation Failed")
+			return
+		}
+		data.statuses[parts[4]] = append(data.statuses[parts[4]], status)
+		writeJSON(w, r, http.StatusCreated, status)
+	case parts[3] == "issues" && len(parts) == 6 && parts[5] == "comments":
+		number, err := strconv.Atoi(parts[4])
+		if err != nil {
+			writeMessage(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			var items []interface{}
+			for _, c := range data.comments[number] {
+				items = append(items, c)
+			}
+			s.writePage(w, r, items)
+		case http.MethodPost:
+			var body Comment
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				writeMessage(w, http.StatusBadRequest, "Problems parsing JSON")
+				return
+			}
+			writeJSON(w, r, http.StatusCreated, s.addComment(data, number, body.Body))
+		default:
+			writeMessage(w, http.StatusNotFound, "Not Found")
+		}
+	case r.Method == http.MethodPatch && len(parts) == 6 && parts[3] == "issues" && parts[4] == "comments":
+		id, _ := strconv.ParseInt(parts[5], 10, 64)
+		var body Comment
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeMessage(w, http.StatusBadRequest, "Problems parsing JSON")
+			return
+		}
+		for _, comments := range data.comments {
+			for _, c := range comments {
+				if c.ID == id {
+					c.Body = body.Body
+					writeJSON(w, r, http.StatusOK, c)
+					return
+				}
+			}
+		}
+		writeMessage(w, http.StatusNotFound, "Not Found")
+	default:
+		writeMessage(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+// writePage 依 per_page/page 切出一頁，並加上 rel="next"/"last" 的 Link header
+func (s *Server) writePage(w http.ResponseWriter, r *http.Request, items []interface{}) {
+	q := r.URL.Query()
+	perPage, err := strconv.Atoi(q.Get("per_page"))
+	if err != nil || perPage <= 0 {
+		perPage = 30
+	}
+	page, err := strconv.Atoi(q.Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	lastPage := (len(items) + perPage - 1) / perPage
+	if page < lastPage {
+		link := func(p int) string {
+			q.Set("page", strconv.Itoa(p))
+			return fmt.Sprintf("<%s%s?%s>", s.URL, r.URL.Path, q.Encode())
+		}
+		w.Header().Set("Link", fmt.Sprintf(`%s; rel="next", %s; rel="last"`, link(page+1), link(lastPage)))
+	}
+
+	start := (page - 1) * perPage
+	end := start + perPage
+	if start > len(items) {
+		start = len(items)
+	}
+	if end > len(items) {
+		end = len(items)
+	}
+	writeJSON(w, r, http.StatusOK, append([]interface{}{}, items[start:end]...))
+}
+
+// writeJSON 回傳 JSON 並依內容產生 ETag，符合 If-None-Match 時回傳 304
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sum := sha1.Sum(body)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func writeMessage(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+func pullJSON(owner, repo string, pr Pull) map[string]interface{} {
+	state := pr.State
+	if state == "" {
+		state = "open"
+	}
+	base := pr.BaseRef
+	if base == "" {
+		base = "main"
+	}
+	labels := []interface{}{}
+	for _, name := range pr.Labels {
+		labels = append(labels, map[string]interface{}{"name": name})
+	}
+	updated := pr.UpdatedAt
+	if updated.IsZero() {
+		updated = time.Unix(0, 0)
+	}
+	return map[string]interface{}{
+		"number":     pr.Number,
+		"title":      pr.Title,
+		"state":      state,
+		"draft":      pr.Draft,
+		"labels":     labels,
+		"updated_at": updated.UTC().Format(time.RFC3339),
+		"user":       map[string]interface{}{"login": pr.User},
+		"head":       map[string]interface{}{"sha": pr.HeadSHA, "ref": pr.HeadRef},
+		"base":       map[string]interface{}{"ref": base, "repo": map[string]interface{}{"full_name": owner + "/" + repo}},
+	}
+}
+
+func commitJSON(commit Commit) map[string]interface{} {
+	return map[string]interface{}{
+		"sha":    commit.SHA,
+		"commit": map[string]interface{}{"message": commit.Message},
+	}
+}
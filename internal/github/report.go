@@ -0,0 +1,93 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Commit status 的狀態
+const (
+	StatePending = "pending"
+	StateSuccess = "success"
+	StateFailure = "failure"
+	StateError   = "error"
+)
+
+// Status 是 commit status 的內容
+type Status struct {
+	State       string `json:"state"`
+	TargetURL   string `json:"target_url,omitempty"`
+	Description string `json:"description,omitempty"` // GitHub 限制 140 字元
+	Context     string `json:"context"`
+}
+
+// IssueComment 是 issue 或 PR 上的留言
+type IssueComment struct {
+	ID   int64  `json:"id"`
+	Body string `json:"body"`
+}
+
+// maxDescription 為 commit status description 的長度上限
+const maxDescription = 140
+
+// CreateStatus 在 sha 上建立 commit status
+func (c *Client) CreateStatus(ctx context.Context, owner, repo, sha string, status Status) error {
+	if r := []rune(status.Description); len(r) > maxDescription {
+		status.Description = string(r[:maxDescription-1]) + "…"
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/statuses/%s", c.baseURL, owner, repo, sha)
+	res, err := c.do(ctx, http.MethodPost, url, nil, status)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
+
+// ListIssueComments 依 Link header 取得 issue 或 PR 的所有留言
+func (c *Client) ListIssueComments(ctx context.Context, owner, repo string, number int) ([]IssueComment, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d/comments?per_page=%d", c.baseURL, owner, repo, number, perPage)
+	var comments []IssueComment
+	for page := 0; url != ""; page++ {
+		if page >= maxPages {
+			return nil, fmt.Errorf("github: %s/%s#%d has more than %d pages of comments", owner, repo, number, maxPages)
+		}
+		res, err := c.do(ctx, http.MethodGet, url, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var batch []IssueComment
+		if err := decodeBody(res, &batch); err != nil {
+			return nil, err
+		}
+		comments = append(comments, batch...)
+		url = nextPageURL(res.Header.Get("Link"))
+	}
+	return comments, nil
+}
+
+// CreateIssueComment 在 issue 或 PR 上新增留言
+func (c *Client) CreateIssueComment(ctx context.Context, owner, repo string, number int, body string) (*IssueComment, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d/comments", c.baseURL, owner, repo, number)
+	res, err := c.do(ctx, http.MethodPost, url, nil, map[string]string{"body": body})
+	if err != nil {
+		return nil, err
+	}
+	var comment IssueComment
+	if err := decodeBody(res, &comment); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// UpdateIssueComment 修改既有留言
+func (c *Client) UpdateIssueComment(ctx context.Context, owner, repo string, id int64, body string) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/issues/comments/%d", c.baseURL, owner, repo, id)
+	res, err := c.do(ctx, http.MethodPatch, url, nil, map[string]string{"body": body})
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
@@ -0,0 +1,144 @@
+// Package metrics 定義 Prometheus 指標，並提供 /metrics 使用的 handler。
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"web_test/pkg/models"
+)
+
+const namespace = "web_test"
+
+// taskBuckets 涵蓋數十秒到數小時的等待與執行時間
+var taskBuckets = []float64{10, 30, 60, 120, 300, 600, 1200, 1800, 3600, 7200, 14400}
+
+var (
+	// HTTPRequestDuration 依路由樣板（例如 /api/queue/delete/:taskID）記錄 HTTP 延遲
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// TaskWaitSeconds 為任務從加入佇列到開始執行的時間
+	TaskWaitSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "task_wait_seconds",
+		Help:      "Time tasks spend in the queue before running.",
+		Buckets:   taskBuckets,
+	})
+
+	// TaskRunSeconds 為任務執行時間，依最終狀態區分
+	TaskRunSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "task_run_seconds",
+		Help:      "Time tasks spend running, by final status.",
+		Buckets:   taskBuckets,
+	}, []string{"status"})
+
+	// TasksTotal 依最終狀態與 NF 計數；多個 NF 的任務每個 NF 各計一次
+	TasksTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tasks_total",
+		Help:      "Finished tasks by status and NF.",
+	}, []string{"status", "nf"})
+
+	// TestFailuresTotal 依測試名稱計數失敗次數
+	TestFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "test_failures_total",
+		Help:      "Failed tests by test name.",
+	}, []string{"test"})
+
+	// GitHubRequestsTotal 依 HTTP method 與狀態碼計數 GitHub API 呼叫，連線失敗時 code 為 error
+	GitHubRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "github_requests_total",
+		Help:      "GitHub API calls by method and response code.",
+	}, []string{"method", "code"})
+
+	// StoreDuration 依 ResultStore 方法與結果（ok、error）記錄延遲
+	StoreDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "store_operation_duration_seconds",
+		Help:      "ResultStore latency by operation.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"operation", "result"})
+
+	queueLength = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_length",
+		Help:      "Tasks waiting in the queue.",
+	}, readQueueLength)
+)
+
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestDuration, TaskWaitSeconds, TaskRunSeconds, TasksTotal, TestFailuresTotal,
+		GitHubRequestsTotal, StoreDuration, queueLength,
+	)
+}
+
+// Handler 回傳 Prometheus text format 的 /metrics handler
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+var (
+	queueMu  sync.RWMutex
+	queueLen func() (int, error)
+)
+
+// SetQueueLength 設定讀取佇列長度的函式，未設定或讀取失敗時 queue_length 為 0
+func SetQueueLength(fn func() (int, error)) {
+	queueMu.Lock()
+	defer queueMu.Unlock()
+	queueLen = fn
+}
+
+func readQueueLength() float64 {
+	queueMu.RLock()
+	fn := queueLen
+	queueMu.RUnlock()
+	if fn == nil {
+		return 0
+	}
+	n, err := fn()
+	if err != nil {
+		return 0
+	}
+	return float64(n)
+}
+
+// TaskStarted 記錄任務在佇列中等待的時間；沒有加入佇列時間的舊任務不記錄
+func TaskStarted(task *models.Task, startedAt time.Time) {
+	if task.QueuedAt == 0 {
+		return
+	}
+	TaskWaitSeconds.Observe(max(float64(startedAt.Unix()-task.QueuedAt), 0))
+}
+
+// TaskFinished 記錄任務的執行時間、結果與失敗的測試
+func TaskFinished(result *models.TaskResult) {
+	TaskRunSeconds.WithLabelValues(result.Status).Observe(float64(result.Duration))
+	for _, p := range result.Params {
+		TasksTotal.WithLabelValues(result.Status, p.NF).Inc()
+	}
+	if len(result.Params) == 0 {
+		TasksTotal.WithLabelValues(result.Status, "").Inc()
+	}
+	for _, test := range result.FailedTests {
+		TestFailuresTotal.WithLabelValues(test).Inc()
+	}
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"web_test/pkg/database"
+	"web_test/pkg/models"
+)
+
+func TestTaskFinished(t *testing.T) {
+	TaskFinished(&models.TaskResult{
+		Status:      models.StatusFailed,
+		Params:      []models.TaskParams{{NF: "amf"}, {NF: "smf"}},
+		FailedTests: []string{"TestPaging"},
+		Duration:    90,
+	})
+	if n := testutil.ToFloat64(TasksTotal.WithLabelValues(models.StatusFailed, "smf")); n != 1 {
+		t.Errorf("tasks_total{smf} = %v, want 1", n)
+	}
+	if n := testutil.ToFloat64(TestFailuresTotal.WithLabelValues("TestPaging")); n != 1 {
+		t.Errorf("test_failures_total{TestPaging} = %v, want 1", n)
+	}
+}
+
+func TestQueueLengthAndStoreLatency(t *testing.T) {
+	t.Cleanup(func() { SetQueueLength(nil) })
+	SetQueueLength(func() (int, error) { return 3, nil })
+	if n := testutil.ToFloat64(queueLength); n != 3 {
+		t.Errorf("queue_length = %v, want 3", n)
+	}
+
+	store := NewStore(database.NewMemoryDB())
+	before := testutil.CollectAndCount(StoreDuration)
+	if _, err := store.GetResult(context.Background(), "missing"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveResult(context.Background(), &models.TaskResult{TaskID: "1", Status: models.StatusSuccess}); err == nil {
+		t.Fatal("saving a result that never ran succeeded")
+	}
+	if after := testutil.CollectAndCount(StoreDuration); after != before+2 {
+		t.Errorf("store latency series = %d, want %d (GetResult ok and SaveResult error)", after, before+2)
+	}
+}
@@ -0,0 +1,197 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"web_test/pkg/database"
+	"web_test/pkg/models"
+)
+
+// timedStore 記錄每個 ResultStore 方法的延遲
+type timedStore struct {
+	store database.ResultStore
+}
+
+// NewStore 包裝 ResultStore，將每次呼叫的延遲記錄到 store_operation_duration_seconds
+func NewStore(s database.ResultStore) database.ResultStore {
+	return &timedStore{store: s}
+}
+
+func observe(operation string, start time.Time, err *error) {
+	result := "ok"
+	if *err != nil {
+		result = "error"
+	}
+	StoreDuration.WithLabelValues(operation, result).Observe(time.Since(start).Seconds())
+}
+
+func (s *timedStore) SaveResult(ctx context.Context, result *models.TaskResult) (err error) {
+	defer observe("SaveResult", time.Now(), &err)
+	return s.store.SaveResult(ctx, result)
+}
+
+func (s *timedStore) GetResult(ctx context.Context, taskID string) (_ *models.TaskResult, err error) {
+	defer observe("GetResult", time.Now(), &err)
+	return s.store.GetResult(ctx, taskID)
+}
+
+func (s *timedStore) GetRunningTasks(ctx context.Context) (_ []*models.TaskResult, err error) {
+	defer observe("GetRunningTasks", time.Now(), &err)
+	return s.store.GetRunningTasks(ctx)
+}
+
+func (s *timedStore) DeleteResult(ctx context.Context, taskID string, status string) (err error) {
+	defer observe("DeleteResult", time.Now(), &err)
+	return s.store.DeleteResult(ctx, taskID, status)
+}
+
+func (s *timedStore) IncrementTaskID(ctx context.Context) (_ int, err error) {
+	defer observe("IncrementTaskID", time.Now(), &err)
+	return s.store.IncrementTaskID(ctx)
+}
+
+func (s *timedStore) SaveHistory(ctx context.Context, record *models.HistoryRecord) (err error) {
+	defer observe("SaveHistory", time.Now(), &err)
+	return s.store.SaveHistory(ctx, record)
+}
+
+func (s *timedStore) GetHistory(ctx context.Context, start, end int64) (_ []*models.HistoryRecord, err error) {
+	defer observe("GetHistory", time.Now(), &err)
+	return s.store.GetHistory(ctx, start, end)
+}
+
+func (s *timedStore) SavePrCache(ctx context.Context, entry *models.PrCacheEntry) (err error) {
+	defer observe("SavePrCache", time.Now(), &err)
+	return s.store.SavePrCache(ctx, entry)
+}
+
+func (s *timedStore) GetPrCache(ctx context.Context, repo string) (_ *models.PrCacheEntry, err error) {
+	defer observe("GetPrCache", time.Now(), &err)
+	return s.store.GetPrCache(ctx, repo)
+}
+
+func (s *timedStore) ListPrCache(ctx context.Context) (_ []*models.PrCacheEntry, err error) {
+	defer observe("ListPrCache", time.Now(), &err)
+	return s.store.ListPrCache(ctx)
+}
+
+func (s *timedStore) ClearPrCache(ctx context.Context, repo string) (err error) {
+	defer observe("ClearPrCache", time.Now(), &err)
+	return s.store.ClearPrCache(ctx, repo)
+}
+
+func (s *timedStore) DeleteLegacyPrCache(ctx context.Context) (_ bool, err error) {
+	defer observe("DeleteLegacyPrCache", time.Now(), &err)
+	return s.store.DeleteLegacyPrCache(ctx)
+}
+
+func (s *timedStore) SaveToken(ctx context.Context, token *models.APIToken) (err error) {
+	defer observe("SaveToken", time.Now(), &err)
+	return s.store.SaveToken(ctx, token)
+}
+
+func (s *timedStore) GetToken(ctx context.Context, id string) (_ *models.APIToken, err error) {
+	defer observe("GetToken", time.Now(), &err)
+	return s.store.GetToken(ctx, id)
+}
+
+func (s *timedStore) ListTokens(ctx context.Context) (_ []*models.APIToken, err error) {
+	defer observe("ListTokens", time.Now(), &err)
+	return s.store.ListTokens(ctx)
+}
+
+func (s *timedStore) DeleteToken(ctx context.Context, id string) (err error) {
+	defer observe("DeleteToken", time.Now(), &err)
+	return s.store.DeleteToken(ctx, id)
+}
+
+func (s *timedStore) AppendAudit(ctx context.Context, entry *models.AuditEntry) (err error) {
+	defer observe("AppendAudit", time.Now(), &err)
+	return s.store.AppendAudit(ctx, entry)
+}
+
+func (s *timedStore) TrimAudit(ctx context.Context, n int64) (err error) {
+	defer observe("TrimAudit", time.Now(), &err)
+	return s.store.TrimAudit(ctx, n)
+}
+
+func (s *timedStore) GetAudit(ctx context.Context, start, end int64) (_ []*models.AuditEntry, err error) {
+	defer observe("GetAudit", time.Now(), &err)
+	return s.store.GetAudit(ctx, start, end)
+}
+
+func (s *timedStore) SaveProgress(ctx context.Context, progress *models.ProgressInfo) (err error) {
+	defer observe("SaveProgress", time.Now(), &err)
+	return s.store.SaveProgress(ctx, progress)
+}
+
+func (s *timedStore) GetProgress(ctx context.Context, taskID string) (_ *models.ProgressInfo, err error) {
+	defer observe("GetProgress", time.Now(), &err)
+	return s.store.GetProgress(ctx, taskID)
+}
+
+func (s *timedStore) DeleteProgress(ctx context.Context, taskID string) (err error) {
+	defer observe("DeleteProgress", time.Now(), &err)
+	return s.store.DeleteProgress(ctx, taskID)
+}
+
+func (s *timedStore) RequestCancel(ctx context.Context, taskID, actor string) (err error) {
+	defer observe("RequestCancel", time.Now(), &err)
+	return s.store.RequestCancel(ctx, taskID, actor)
+}
+
+func (s *timedStore) GetCancelRequest(ctx context.Context, taskID string) (_ string, err error) {
+	defer observe("GetCancelRequest", time.Now(), &err)
+	return s.store.GetCancelRequest(ctx, taskID)
+}
+
+func (s *timedStore) ClearCancel(ctx context.Context, taskID string) (err error) {
+	defer observe("ClearCancel", time.Now(), &err)
+	return s.store.ClearCancel(ctx, taskID)
+}
+
+func (s *timedStore) SaveTestDuration(ctx context.Context, name string, seconds int64) (err error) {
+	defer observe("SaveTestDuration", time.Now(), &err)
+	return s.store.SaveTestDuration(ctx, name, seconds)
+}
+
+func (s *timedStore) GetTestDurations(ctx context.Context) (_ map[string]int64, err error) {
+	defer observe("GetTestDurations", time.Now(), &err)
+	return s.store.GetTestDurations(ctx)
+}
+
+func (s *timedStore) Ping(ctx context.Context) (err error) {
+	defer observe("Ping", time.Now(), &err)
+	return s.store.Ping(ctx)
+}
+
+func (s *timedStore) ListResults(ctx context.Context) (_ []*models.TaskResult, err error) {
+	defer observe("ListResults", time.Now(), &err)
+	return s.store.ListResults(ctx)
+}
+
+func (s *timedStore) RestoreResult(ctx context.Context, result *models.TaskResult) (err error) {
+	defer observe("RestoreResult", time.Now(), &err)
+	return s.store.RestoreResult(ctx, result)
+}
+
+func (s *timedStore) TrimHistory(ctx context.Context, n int64) (err error) {
+	defer observe("TrimHistory", time.Now(), &err)
+	return s.store.TrimHistory(ctx, n)
+}
+
+func (s *timedStore) ReplaceHistory(ctx context.Context, records []*models.HistoryRecord) (err error) {
+	defer observe("ReplaceHistory", time.Now(), &err)
+	return s.store.ReplaceHistory(ctx, records)
+}
+
+func (s *timedStore) GetTaskIDCounter(ctx context.Context) (_ int, err error) {
+	defer observe("GetTaskIDCounter", time.Now(), &err)
+	return s.store.GetTaskIDCounter(ctx)
+}
+
+func (s *timedStore) RestoreTaskIDCounter(ctx context.Context, n int) (err error) {
+	defer observe("RestoreTaskIDCounter", time.Now(), &err)
+	return s.store.RestoreTaskIDCounter(ctx, n)
+}
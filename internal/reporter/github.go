@@ -0,0 +1,223 @@
+package reporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"web_test/internal/github"
+	"web_test/internal/logger"
+	"web_test/pkg/models"
+)
+
+// DefaultStatusContext 為 commit status 預設的 context 名稱
+const DefaultStatusContext = "free5gc-ci"
+
+// summaryMarker 用於找出 CI 先前留下的摘要留言，以更新取代新增
+const summaryMarker = "<!-- web_test-ci:summary -->"
+
+// GitHubReporter 在每個 PR 的 head commit 上建立 commit status，
+// 並在 PR 上新增或更新一則摘要留言
+type GitHubReporter struct {
+	client        *github.Client
+	repos         map[string]models.NFRepo // NF -> repo
+	publicURL     string                   // Web UI 的對外網址，用於預覽頁連結
+	statusContext string
+}
+
+// NewGitHubReporter 建立 GitHubReporter；publicURL 為空時不附預覽頁連結
+func NewGitHubReporter(client *github.Client, repos []models.NFRepo, publicURL, statusContext string) *GitHubReporter {
+	if statusContext == "" {
+		statusContext = DefaultStatusContext
+	}
+	byNF := make(map[string]models.NFRepo, len(repos))
+	for _, repo := range repos {
+		byNF[repo.NF] = repo
+	}
+	return &GitHubReporter{
+		client:        client,
+		repos:         byNF,
+		publicURL:     strings.TrimRight(publicURL, "/"),
+		statusContext: statusContext,
+	}
+}
+
+// TaskStarted 補上未知的 HeadSHA，並在每個 head commit 上建立 pending status
+func (r *GitHubReporter) TaskStarted(ctx context.Context, task *models.Task) error {
+	var errs []error
+	for i := range task.Params {
+		param := &task.Params[i]
+		repo, number, err := r.target(*param)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if param.HeadSHA == "" {
+			pr, err := r.client.GetPull(ctx, repo.Owner, repo.Repo, number)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("resolve head of %s:%s: %w", param.NF, param.PRVersion, err))
+				continue
+			}
+			param.HeadSHA = pr.Head.SHA
+		}
+		err = r.client.CreateStatus(ctx, repo.Owner, repo.Repo, param.HeadSHA, github.Status{
+			State:       github.StatePending,
+			TargetURL:   r.previewURL(task.ID),
+			Description: fmt.Sprintf("Task %s is running", task.ID),
+			Context:     r.statusContext,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("set pending status of %s:%s: %w", param.NF, param.PRVersion, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// TaskFinished 在每個 head commit 上建立最終 status，並更新 PR 上的摘要留言
+func (r *GitHubReporter) TaskFinished(ctx context.Context, result *models.TaskResult) error {
+	state, description := statusFor(result)
+	body := r.summary(result)
+
+	var errs []error
+	for _, param := range result.Params {
+		repo, number, err := r.target(param)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if param.HeadSHA != "" {
+			err := r.client.CreateStatus(ctx, repo.Owner, repo.Repo, param.HeadSHA, github.Status{
+				State:       state,
+				TargetURL:   r.previewURL(result.TaskID),
+				Description: description,
+				Context:     r.statusContext,
+			})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("set status of %s:%s: %w", param.NF, param.PRVersion, err))
+			}
+		} else {
+			logger.ExecutorLog.Warnf("Task %s: head commit of %s:%s is unknown, skip commit status", result.TaskID, param.NF, param.PRVersion)
+		}
+		if err := r.upsertSummary(ctx, repo, number, body); err != nil {
+			errs = append(errs, fmt.Errorf("comment on %s:%s: %w", param.NF, param.PRVersion, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// target 回傳參數對應的 repo 與 PR 號碼
+func (r *GitHubReporter) target(param models.TaskParams) (models.NFRepo, int, error) {
+	repo, ok := r.repos[param.NF]
+	if !ok {
+		return models.NFRepo{}, 0, fmt.Errorf("NF %q is not configured", param.NF)
+	}
+	number, err := strconv.Atoi(param.PRVersion)
+	if err != nil {
+		return models.NFRepo{}, 0, fmt.Errorf("invalid PR number %q for %s", param.PRVersion, param.NF)
+	}
+	return repo, number, nil
+}
+
+// upsertSummary 更新 PR 上既有的摘要留言，沒有時新增一則
+func (r *GitHubReporter) upsertSummary(ctx context.Context, repo models.NFRepo, number int, body string) error {
+	comments, err := r.client.ListIssueComments(ctx, repo.Owner, repo.Repo, number)
+	if err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		if strings.Contains(comment.Body, summaryMarker) {
+			return r.client.UpdateIssueComment(ctx, repo.Owner, repo.Repo, comment.ID, body)
+		}
+	}
+	_, err = r.client.CreateIssueComment(ctx, repo.Owner, repo.Repo, number, body)
+	return err
+}
+
+func (r *GitHubReporter) previewURL(taskID string) string {
+	if r.publicURL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/static/preview.html?taskId=%s", r.publicURL, taskID)
+}
+
+// statusFor 依判定結果決定 commit status；CI 環境問題回報 error 而非 failure
+func statusFor(result *models.TaskResult) (state, description string) {
+	if result.Status == models.StatusSuccess {
+		if len(result.FlakyTests) > 0 {
+			return github.StateSuccess, fmt.Sprintf("Passed (%d flaky)", len(result.FlakyTests))
+		}
+		return github.StateSuccess, "All tests passed"
+	}
+	switch result.Verdict {
+	case models.VerdictInfra:
+		return github.StateError, "CI environment problem, not caused by this PR"
+	case models.VerdictPR:
+		return github.StateFailure, fmt.Sprintf("%d tests failed because of this PR", len(result.FailedTests))
+	case models.VerdictCancelled:
+		return github.StateError, "Cancelled"
+	case models.VerdictStale:
+		return github.StateError, "Queued commit is no longer available"
+	default:
+		return github.StateFailure, fmt.Sprintf("Failed (%d failed tests)", len(result.FailedTests))
+	}
+}
+
+// verdictText 是摘要留言中判定結果的說明
+var verdictText = map[string]string{
+	models.VerdictPass:      "✅ All tests passed",
+	models.VerdictPR:        "❌ Tests fail with this PR but pass on the release version, please fix the PR",
+	models.VerdictInfra:     "⚠️ Tests also fail on the release version, this is a CI environment problem",
+	models.VerdictUnknown:   "❌ Task failed, see the logs for details",
+	models.VerdictCancelled: "⏹️ Task was cancelled before it finished",
+	models.VerdictStale:     "⚠️ The queued commit can no longer be fetched (force-pushed?), run the CI again on the current head",
+}
+
+// summary 產生 PR 摘要留言
+func (r *GitHubReporter) summary(result *models.TaskResult) string {
+	var b strings.Builder
+	b.WriteString(summaryMarker + "\n")
+	fmt.Fprintf(&b, "### free5gc CI: task %s %s\n\n", result.TaskID, result.Status)
+
+	verdict := result.Verdict
+	if verdict == "" {
+		verdict = models.VerdictUnknown
+		if result.Status == models.StatusSuccess {
+			verdict = models.VerdictPass
+		}
+	}
+	b.WriteString(verdictText[verdict] + "\n\n")
+
+	var prs []string
+	for _, p := range result.Params {
+		pr := fmt.Sprintf("`%s` #%s", p.NF, p.PRVersion)
+		if p.HeadSHA != "" {
+			pr += fmt.Sprintf(" (%.7s)", p.HeadSHA)
+		}
+		prs = append(prs, pr)
+	}
+	fmt.Fprintf(&b, "**Tested PRs:** %s\n", strings.Join(prs, ", "))
+	if result.BaseRef != "" {
+		fmt.Fprintf(&b, "**Base:** free5gc `%s`\n", result.BaseRef)
+	}
+	if result.Duration > 0 {
+		fmt.Fprintf(&b, "**Duration:** %ds\n", result.Duration)
+	}
+	writeTestList(&b, "Failed tests", result.FailedTests)
+	writeTestList(&b, "Flaky tests (passed on rerun)", result.FlakyTests)
+	if url := r.previewURL(result.TaskID); url != "" {
+		fmt.Fprintf(&b, "\n[View logs](%s)\n", url)
+	}
+	return b.String()
+}
+
+func writeTestList(b *strings.Builder, title string, tests []string) {
+	if len(tests) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n**%s (%d):**\n", title, len(tests))
+	for _, t := range tests {
+		fmt.Fprintf(b, "- `%s`\n", t)
+	}
+}
@@ -0,0 +1,76 @@
+package reporter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"web_test/internal/github"
+	"web_test/internal/github/githubtest"
+	"web_test/pkg/models"
+)
+
+func TestGitHubReporter(t *testing.T) {
+	gh := githubtest.NewServer()
+	defer gh.Close()
+	gh.AddPull("free5gc", "go-upf", githubtest.Pull{Number: 7, HeadSHA: "abc123"})
+	gh.AddComment("free5gc", "go-upf", 7, "unrelated review comment")
+
+	r := NewGitHubReporter(github.NewClient(gh.URL, "token"),
+		[]models.NFRepo{{NF: "upf", Owner: "free5gc", Repo: "go-upf"}}, "http://ci.example.com/", "")
+	ctx := context.Background()
+
+	task := &models.Task{ID: "12", Params: []models.TaskParams{{NF: "upf", PRVersion: "7"}}}
+	if err := r.TaskStarted(ctx, task); err != nil {
+		t.Fatalf("TaskStarted: %v", err)
+	}
+	if task.Params[0].HeadSHA != "abc123" {
+		t.Fatalf("HeadSHA = %q, want resolved from the PR", task.Params[0].HeadSHA)
+	}
+
+	result := &models.TaskResult{
+		TaskID:      "12",
+		Status:      models.StatusFailed,
+		Params:      task.Params,
+		BaseRef:     "v4.0.1",
+		FailedTests: []string{"TestPaging"},
+		FlakyTests:  []string{"TestTngf"},
+		Verdict:     models.VerdictPR,
+	}
+	if err := r.TaskFinished(ctx, result); err != nil {
+		t.Fatalf("TaskFinished: %v", err)
+	}
+
+	statuses := gh.Statuses("free5gc", "go-upf", "abc123")
+	if len(statuses) != 2 || statuses[0].State != github.StatePending || statuses[1].State != github.StateFailure {
+		t.Fatalf("statuses = %+v, want pending then failure", statuses)
+	}
+	if statuses[1].Context != DefaultStatusContext || statuses[1].TargetURL != "http://ci.example.com/static/preview.html?taskId=12" {
+		t.Errorf("status = %+v", statuses[1])
+	}
+
+	comments := gh.Comments("free5gc", "go-upf", 7)
+	if len(comments) != 2 {
+		t.Fatalf("comments = %+v, want the summary added", comments)
+	}
+	for _, want := range []string{"TestPaging", "TestTngf", "please fix the PR", "`v4.0.1`", "preview.html?taskId=12"} {
+		if !strings.Contains(comments[1].Body, want) {
+			t.Errorf("summary does not contain %q:\n%s", want, comments[1].Body)
+		}
+	}
+
+	// 再次執行時更新同一則摘要留言；CI 環境問題回報 error
+	result.TaskID = "13"
+	result.Verdict = models.VerdictInfra
+	if err := r.TaskFinished(ctx, result); err != nil {
+		t.Fatalf("TaskFinished: %v", err)
+	}
+	comments = gh.Comments("free5gc", "go-upf", 7)
+	if len(comments) != 2 || !strings.Contains(comments[1].Body, "task 13") {
+		t.Fatalf("comments = %+v, want the summary updated in place", comments)
+	}
+	statuses = gh.Statuses("free5gc", "go-upf", "abc123")
+	if last := statuses[len(statuses)-1]; last.State != github.StateError {
+		t.Fatalf("last status = %+v, want error for an infra verdict", last)
+	}
+}
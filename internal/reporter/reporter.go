@@ -0,0 +1,16 @@
+// Package reporter 將任務的執行狀態與結果回報到外部系統（例如 GitHub）。
+package reporter
+
+import (
+	"context"
+
+	"web_test/pkg/models"
+)
+
+// Reporter 在任務開始與結束時由 executor 呼叫；回報失敗只會記錄，不影響任務結果
+type Reporter interface {
+	// TaskStarted 在任務開始執行前呼叫，可補上 task.Params 中未知的 HeadSHA
+	TaskStarted(ctx context.Context, task *models.Task) error
+	// TaskFinished 在最終結果儲存後呼叫
+	TaskFinished(ctx context.Context, result *models.TaskResult) error
+}
@@ -0,0 +1,194 @@
+// Package selection 依 PR 變更的檔案挑選需要執行的 free5gc 測試與 ULCL 環境。
+package selection
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"web_test/pkg/models"
+)
+
+// DefaultTests 為 run_task.sh 的 TEST_POOL
+var DefaultTests = []string{
+	"TestRegistration", "TestGUTIRegistration", "TestServiceRequest", "TestXnHandover",
+	"TestN2Handover", "TestDeregistration", "TestPDUSessionReleaseRequest", "TestPaging",
+	"TestNon3GPP", "TestReSynchronization", "TestDuplicateRegistration", "TestEAPAKAPrimeAuthentication",
+	"TestMultiAmfRegistration", "TestNasReroute", "TestTngf", "TestDC", "TestDynamicDC", "TestXnDCHandover",
+}
+
+// DefaultEnvs 為 run_task.sh 的 TEST_ENVS
+var DefaultEnvs = []string{"ulcl-ti", "ulcl-mp"}
+
+// Rule 將 NF 中的路徑對應到相關的測試與環境；規則依順序比對，先符合者優先
+type Rule struct {
+	NF string `yaml:"nf" json:"nf"` // 空字串代表任何 NF
+	// Paths 為 repo 內的路徑前綴（以 / 結尾代表目錄）或 path.Match 樣式，空值代表所有檔案
+	Paths []string `yaml:"paths" json:"paths"`
+	Tests []string `yaml:"tests" json:"tests,omitempty"`
+	Envs  []string `yaml:"envs" json:"envs,omitempty"`
+	Full  bool     `yaml:"full" json:"full,omitempty"` // 符合時執行完整測試
+}
+
+func (r *Rule) matches(nf, file string) bool {
+	if r.NF != "" && r.NF != nf {
+		return false
+	}
+	if len(r.Paths) == 0 {
+		return true
+	}
+	for _, p := range r.Paths {
+		if strings.HasSuffix(p, "/") && strings.HasPrefix(file, p) {
+			return true
+		}
+		if ok, _ := path.Match(p, file); ok || p == file {
+			return true
+		}
+	}
+	return false
+}
+
+// Selector 依設定的規則挑選測試
+type Selector struct {
+	rules []Rule
+	tests []string
+	envs  []string
+}
+
+// NewSelector 建立 Selector；tests、envs 為可選的全部測試與環境，空值時使用 run_task.sh 的預設值。
+// 規則中出現不在清單內的測試或環境時回傳錯誤。
+func NewSelector(rules []Rule, tests, envs []string) (*Selector, error) {
+	if len(tests) == 0 {
+		tests = DefaultTests
+	}
+	if len(envs) == 0 {
+		envs = DefaultEnvs
+	}
+	s := &Selector{rules: rules, tests: tests, envs: envs}
+	for i, rule := range rules {
+		if unknown := s.unknown(rule.Tests, s.tests); len(unknown) > 0 {
+			return nil, fmt.Errorf("rule %d: unknown tests %v", i, unknown)
+		}
+		if unknown := s.unknown(rule.Envs, s.envs); len(unknown) > 0 {
+			return nil, fmt.Errorf("rule %d: unknown envs %v", i, unknown)
+		}
+	}
+	return s, nil
+}
+
+// Tests 回傳可選的全部測試
+func (s *Selector) Tests() []string { return s.tests }
+
+// Envs 回傳可選的全部環境
+func (s *Selector) Envs() []string { return s.envs }
+
+// Select 依每個 NF 變更的檔案挑選測試，每個檔案使用第一條符合的規則。
+// 有檔案不符合任何規則、符合 full 規則、變更檔案未知或挑不出任何測試時執行完整測試。
+func (s *Selector) Select(changes map[string][]string) *models.TestSelection {
+	full := func(reason string) *models.TestSelection {
+		return &models.TestSelection{Full: true, Reasons: []string{reason}}
+	}
+	if len(changes) == 0 {
+		return full("changed files are unknown")
+	}
+
+	tests := make(map[string]bool)
+	envs := make(map[string]bool)
+	var reasons []string
+	for _, nf := range sortedKeys(changes) {
+		files := changes[nf]
+		if files == nil {
+			return full(fmt.Sprintf("changed files of %s are unknown", nf))
+		}
+		for _, file := range files {
+			rule := s.match(nf, file)
+			if rule == nil {
+				return full(fmt.Sprintf("%s: %s matches no rule", nf, file))
+			}
+			if rule.Full {
+				return full(fmt.Sprintf("%s: %s requires the full test set", nf, file))
+			}
+			for _, t := range rule.Tests {
+				tests[t] = true
+			}
+			for _, e := range rule.Envs {
+				envs[e] = true
+			}
+		}
+		reasons = append(reasons, fmt.Sprintf("%s: %d changed files", nf, len(files)))
+	}
+	if len(tests) == 0 {
+		return full("no test matches the changed files")
+	}
+
+	sel := &models.TestSelection{Tests: s.ordered(tests, s.tests), Envs: s.ordered(envs, s.envs), Reasons: reasons}
+	if len(sel.Envs) == 0 {
+		sel.Envs = append([]string(nil), s.envs...)
+	}
+	return sel
+}
+
+// match 回傳第一條符合的規則
+func (s *Selector) match(nf, file string) *Rule {
+	for i := range s.rules {
+		if s.rules[i].matches(nf, file) {
+			return &s.rules[i]
+		}
+	}
+	return nil
+}
+
+// Validate 檢查使用者指定的測試與環境都在可選清單內
+func (s *Selector) Validate(sel *models.TestSelection) error {
+	if sel == nil || sel.Full {
+		return nil
+	}
+	if len(sel.Tests) == 0 {
+		return fmt.Errorf("no test selected")
+	}
+	if unknown := s.unknown(sel.Tests, s.tests); len(unknown) > 0 {
+		return fmt.Errorf("unknown tests %v", unknown)
+	}
+	if unknown := s.unknown(sel.Envs, s.envs); len(unknown) > 0 {
+		return fmt.Errorf("unknown envs %v", unknown)
+	}
+	return nil
+}
+
+func (s *Selector) unknown(names, known []string) []string {
+	var unknown []string
+	for _, n := range names {
+		found := false
+		for _, k := range known {
+			if n == k {
+				found = true
+				break
+			}
+		}
+		if !found {
+			unknown = append(unknown, n)
+		}
+	}
+	return unknown
+}
+
+// ordered 依可選清單的順序回傳 set 中的名稱
+func (s *Selector) ordered(set map[string]bool, order []string) []string {
+	var out []string
+	for _, name := range order {
+		if set[name] {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
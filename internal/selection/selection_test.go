@@ -0,0 +1,94 @@
+package selection
+
+import (
+	"reflect"
+	"testing"
+
+	"web_test/pkg/models"
+)
+
+func newTestSelector(t *testing.T) *Selector {
+	t.Helper()
+	s, err := NewSelector([]Rule{
+		{Paths: []string{"go.mod"}, Full: true},
+		{Paths: []string{"*.md"}, Tests: []string{"TestRegistration"}},
+		{NF: "amf", Paths: []string{"internal/gmm/"}, Tests: []string{"TestDeregistration", "TestRegistration"}, Envs: []string{"ulcl-ti"}},
+		{NF: "upf", Tests: []string{"TestPaging"}, Envs: []string{"ulcl-mp"}},
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("NewSelector: %v", err)
+	}
+	return s
+}
+
+func TestSelect(t *testing.T) {
+	s := newTestSelector(t)
+	tests := []struct {
+		name    string
+		changes map[string][]string
+		want    *models.TestSelection
+	}{
+		{
+			name:    "rules are merged in pool order",
+			changes: map[string][]string{"amf": {"internal/gmm/handler.go"}, "upf": {"internal/pfcp/node.go"}},
+			want: &models.TestSelection{
+				Tests:   []string{"TestRegistration", "TestDeregistration", "TestPaging"},
+				Envs:    []string{"ulcl-ti", "ulcl-mp"},
+				Reasons: []string{"amf: 1 changed files", "upf: 1 changed files"},
+			},
+		},
+		{
+			name:    "first matching rule wins and no env means all envs",
+			changes: map[string][]string{"upf": {"README.md"}},
+			want: &models.TestSelection{
+				Tests:   []string{"TestRegistration"},
+				Envs:    DefaultEnvs,
+				Reasons: []string{"upf: 1 changed files"},
+			},
+		},
+		{
+			name:    "unmatched file",
+			changes: map[string][]string{"amf": {"internal/ngap/handler.go"}},
+			want:    &models.TestSelection{Full: true, Reasons: []string{"amf: internal/ngap/handler.go matches no rule"}},
+		},
+		{
+			name:    "full rule",
+			changes: map[string][]string{"amf": {"internal/gmm/handler.go", "go.mod"}},
+			want:    &models.TestSelection{Full: true, Reasons: []string{"amf: go.mod requires the full test set"}},
+		},
+		{
+			name:    "unknown changes",
+			changes: map[string][]string{"amf": nil},
+			want:    &models.TestSelection{Full: true, Reasons: []string{"changed files of amf are unknown"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.Select(tt.changes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Select = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSelectorRejectsUnknownNames(t *testing.T) {
+	if _, err := NewSelector([]Rule{{Tests: []string{"TestNope"}}}, nil, nil); err == nil {
+		t.Error("unknown test accepted")
+	}
+	if _, err := NewSelector([]Rule{{Tests: []string{"TestPaging"}, Envs: []string{"ulcl-xx"}}}, nil, nil); err == nil {
+		t.Error("unknown env accepted")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	s := newTestSelector(t)
+	if err := s.Validate(&models.TestSelection{Tests: []string{"TestPaging"}, Envs: []string{"ulcl-ti"}}); err != nil {
+		t.Errorf("valid selection: %v", err)
+	}
+	if err := s.Validate(&models.TestSelection{}); err == nil {
+		t.Error("empty selection accepted")
+	}
+	if err := s.Validate(&models.TestSelection{Tests: []string{"TestPaging; rm -rf /"}}); err == nil {
+		t.Error("unknown test accepted")
+	}
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"web_test/internal/logger"
+	"web_test/pkg/backup"
+	"web_test/pkg/models"
+)
+
+// maxImportSize 限制上傳備份檔的大小（含 multipart 的其他部分）；備份檔會整個讀進記憶體，
+// 更大的備份請在服務行程外以 `web_test import` 還原
+var maxImportSize int64 = 64 << 20
+
+func AdminRoute() []Route {
+	return []Route{
+		{
+			Name:        "export backup",
+			Method:      http.MethodGet,
+			Pattern:     "/export",
+			HandlerFunc: ExportBackupHandler,
+			Role:        models.RoleAdmin,
+			Produces:    "application/zip",
+		},
+		{
+			Name:        "import backup",
+			Method:      http.MethodPost,
+			Pattern:     "/import",
+			HandlerFunc: ImportBackupHandler,
+			Role:        models.RoleAdmin,
+			Response:    backup.Manifest{},
+		},
+		{
+			Name:        "list API tokens",
+			Method:      http.MethodGet,
+			Pattern:     "/tokens",
+			HandlerFunc: ListTokensHandler,
+			Role:        models.RoleAdmin,
+			Response:    []models.APIToken{},
+		},
+		{
+			Name:        "create API token",
+			Method:      http.MethodPost,
+			Pattern:     "/tokens",
+			HandlerFunc: CreateTokenHandler,
+			Role:        models.RoleAdmin,
+			Request:     createTokenRequest{},
+			Response:    createTokenResponse{},
+			Status:      http.StatusCreated,
+		},
+		{
+			Name:        "revoke API token",
+			Method:      http.MethodDelete,
+			Pattern:     "/tokens/:id",
+			HandlerFunc: DeleteTokenHandler,
+			Role:        models.RoleAdmin,
+			Response:    statusResponse{},
+		},
+	}
+}
+
+// ExportBackupHandler 下載包含所有資料的備份檔，壓縮的同時寫入回應
+func ExportBackupHandler(c *gin.Context) {
+	fileName := fmt.Sprintf("web_test-backup-%s.zip", time.Now().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+	c.Header("Content-Type", "application/zip")
+	if _, err := backup.Export(c.Request.Context(), DB, c.Writer); err != nil {
+		logger.WebLog.Errorf("ExportBackupHandler: %v", err)
+		// 讀取資料失敗時尚未寫出內容，仍可回傳錯誤；寫到一半失敗時只能中斷回應
+		if !c.Writer.Written() {
+			c.Header("Content-Disposition", "")
+			c.Header("Content-Type", "")
+			respondError(c, http.StatusInternalServerError, "failed to export backup")
+		}
+		return
+	}
+}
+
+// ImportBackupHandler 還原備份檔；接受 multipart 欄位 "file" 或直接以 body 上傳
+func ImportBackupHandler(c *gin.Context) {
+	// multipart 也經由 Request.Body 讀取，先限制大小再解析
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, _, err := c.Request.FormFile("file")
+		if err != nil {
+			respondImportReadError(c, err)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		respondImportReadError(c, err)
+		return
+	}
+
+	manifest, err := backup.Import(context.Background(), DB, bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		if errors.Is(err, backup.ErrInvalidArchive) {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		logger.WebLog.Errorf("ImportBackupHandler: %v", err)
+		respondError(c, http.StatusInternalServerError, "failed to restore backup")
+		return
+	}
+	logger.WebLog.Infof("Imported backup: %d results, %d history records", manifest.Results, manifest.History)
+	auditRequest(c, models.AuditConfig, "backup", fmt.Sprintf("imported %d results, %d history records", manifest.Results, manifest.History))
+	c.JSON(http.StatusOK, manifest)
+}
+
+// respondImportReadError 回應讀取上傳備份檔的錯誤，超過 maxImportSize 時回傳 413
+func respondImportReadError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		respondError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("archive is larger than %d bytes", maxImportSize))
+		return
+	}
+	respondError(c, http.StatusBadRequest, fmt.Sprintf("failed to read archive: %v", err))
+}
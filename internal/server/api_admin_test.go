@@ -0,0 +1,105 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"web_test/pkg/backup"
+	"web_test/pkg/database"
+	"web_test/pkg/models"
+)
+
+func TestImportBackupSizeLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	oldDB, oldMax := DB, maxImportSize
+	t.Cleanup(func() { DB, maxImportSize = oldDB, oldMax })
+	DB = database.NewMemoryDB()
+	engine := gin.New()
+	engine.POST("/import", ImportBackupHandler)
+
+	var archive bytes.Buffer
+	if _, err := backup.Export(context.Background(), database.NewMemoryDB(), &archive); err != nil {
+		t.Fatal(err)
+	}
+	maxImportSize = int64(archive.Len()) + 1024
+
+	upload := func(file []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, _ := mw.CreateFormFile("file", "backup.zip")
+		fw.Write(file)
+		mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/import", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := upload(archive.Bytes()); w.Code != http.StatusOK {
+		t.Fatalf("multipart import = %d %s", w.Code, w.Body)
+	}
+	// multipart 上傳也受 maxImportSize 限制
+	if w := upload(make([]byte, maxImportSize+1)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized multipart import = %d %s, want 413", w.Code, w.Body)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/import", bytes.NewReader(make([]byte, maxImportSize+1)))
+	req.Header.Set("Content-Type", "application/zip")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized raw import = %d %s, want 413", w.Code, w.Body)
+	}
+}
+
+// failingStore 拒絕寫入歷史紀錄，模擬匯入時 ResultStore 失敗
+type failingStore struct {
+	database.ResultStore
+}
+
+func (failingStore) ReplaceHistory(context.Context, []*models.HistoryRecord) error {
+	return errors.New("redis: connection refused")
+}
+
+func TestImportBackupErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	oldDB := DB
+	t.Cleanup(func() { DB = oldDB })
+	engine := gin.New()
+	engine.POST("/import", ImportBackupHandler)
+	engine.GET("/export", ExportBackupHandler)
+
+	DB = database.NewMemoryDB()
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/export", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("export = %d %s", w.Code, w.Header())
+	}
+	archive := w.Body.Bytes()
+
+	importArchive := func(data []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/import", bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/zip")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+	if w := importArchive([]byte("not a zip")); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid archive = %d %s, want 400", w.Code, w.Body)
+	}
+
+	// 寫入失敗是伺服器的問題，且不回傳內部錯誤
+	DB = failingStore{database.NewMemoryDB()}
+	w = importArchive(archive)
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "redis") {
+		t.Errorf("store failure = %d %s, want 500 without the internal error", w.Code, w.Body)
+	}
+}
@@ -12,27 +12,36 @@ import (
 
 	"github.com/gin-gonic/gin"
 	go_redis "github.com/redis/go-redis/v9"
+
+	"web_test/pkg/models"
 )
 
 func DownloadRoute() []Route {
-    return []Route{
+	return []Route{
 		{
-			Name:    "download all logs",
-			Method:  http.MethodGet,
-			Pattern: "/:taskID",
+			Name:        "download all logs",
+			Method:      http.MethodGet,
+			Pattern:     "/:taskID",
 			HandlerFunc: DownloadAllLogHandler,
+			Role:        models.RoleViewer,
+			Produces:    "application/zip",
 		},
 		{
-			Name:    "download single log",
-			Method:  http.MethodGet,
-			Pattern: "/single/:taskID/:failedTest",
+			Name:        "download single log",
+			Method:      http.MethodGet,
+			Pattern:     "/single/:taskID/:failedTest",
 			HandlerFunc: DownloadSingleLogHandler,
+			Role:        models.RoleViewer,
+			Produces:    "text/plain",
 		},
-        {
-			Name:    "get task result",
-			Method:  http.MethodGet,
-			Pattern: "/task/:taskID",
+		{
+			Name:        "get task result",
+			Method:      http.MethodGet,
+			Pattern:     "/task/:taskID",
 			HandlerFunc: GetTaskResultHandler,
+			Role:        models.RoleViewer,
+			Response:    taskResultView{},
+			Query:       map[string]string{"tz": "顯示時間使用的 IANA 時區"},
 		},
 	}
 }
@@ -41,7 +50,7 @@ func DownloadAllLogHandler(c *gin.Context) {
 	taskIDStr := c.Param("taskID")
 	taskID, err := strconv.Atoi(taskIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		respondError(c, http.StatusBadRequest, "Invalid task ID")
 		return
 	}
 
@@ -49,16 +58,16 @@ func DownloadAllLogHandler(c *gin.Context) {
 	taskResult, err := DB.GetResult(ctx, taskIDStr) 
 	if err != nil {
 		if err == go_redis.Nil { // go_redis.Nil means key does not exist
-			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Task result for ID %d not found", taskID)})
+			respondError(c, http.StatusNotFound, fmt.Sprintf("Task result for ID %d not found", taskID))
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve task result"})
+			respondError(c, http.StatusInternalServerError, "Failed to retrieve task result")
 		}
 		return
 	}
     
     // 檢查指標是否為 nil
     if taskResult == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task result is empty"})
+		respondError(c, http.StatusNotFound, "Task result is empty")
 		return
 	}
 
@@ -84,13 +93,13 @@ func DownloadAllLogHandler(c *gin.Context) {
 
 		fileWriter, err := zipWriter.CreateHeader(header)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create file header in ZIP: %v", err)})
+			respondError(c, http.StatusInternalServerError, fmt.Sprintf("Failed to create file header in ZIP: %v", err))
 			return
 		}
 
 		_, err = fileWriter.Write([]byte(logContent))
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to write log content to ZIP: %v", err)})
+			respondError(c, http.StatusInternalServerError, fmt.Sprintf("Failed to write log content to ZIP: %v", err))
 			return
 		}
 	}
@@ -103,7 +112,7 @@ func DownloadAllLogHandler(c *gin.Context) {
     }
     jsonWriter, err := zipWriter.CreateHeader(jsonHeader)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create JSON file header in ZIP"})
+        respondError(c, http.StatusInternalServerError, "Failed to create JSON file header in ZIP")
         return
     }
     // Marshal taskResult (解除指標引用後使用)
@@ -114,7 +123,7 @@ func DownloadAllLogHandler(c *gin.Context) {
 	// 4. 關閉 zipWriter
 	err = zipWriter.Close()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to close ZIP writer: %v", err)})
+		respondError(c, http.StatusInternalServerError, fmt.Sprintf("Failed to close ZIP writer: %v", err))
 		return
 	}
     
@@ -137,11 +146,11 @@ func DownloadSingleLogHandler(c *gin.Context) {
 
     taskID, err := strconv.Atoi(taskIDStr)
     if err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+        respondError(c, http.StatusBadRequest, "Invalid task ID")
         return
     }
     if targetTestName == "" {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid failed test name"})
+        respondError(c, http.StatusBadRequest, "Invalid failed test name")
         return
     }
 
@@ -150,15 +159,15 @@ func DownloadSingleLogHandler(c *gin.Context) {
     taskResult, err := DB.GetResult(ctx, taskIDStr) 
     if err != nil {
         if err == go_redis.Nil {
-            c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Task result for ID %d not found", taskID)})
+            respondError(c, http.StatusNotFound, fmt.Sprintf("Task result for ID %d not found", taskID))
         } else {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve task result"})
+            respondError(c, http.StatusInternalServerError, "Failed to retrieve task result")
         }
         return
     }
     
     if taskResult == nil {
-        c.JSON(http.StatusNotFound, gin.H{"error": "Task result is empty"})
+        respondError(c, http.StatusNotFound, "Task result is empty")
         return
     }
 
@@ -182,7 +191,7 @@ func DownloadSingleLogHandler(c *gin.Context) {
     }
 
     if !found {
-        c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Log not found for test: %s", targetTestName)})
+        respondError(c, http.StatusNotFound, fmt.Sprintf("Log not found for test: %s", targetTestName))
         return
     }
 
@@ -216,22 +225,46 @@ func replaceBadChars(s string) string {
     return s
 }
 
+// taskResultView 在任務結果上附加依時區格式化的時間，並標示 PR 是否已有新的 commit
+type taskResultView struct {
+	*models.TaskResult
+	StartedTime  string              `json:"started_time,omitempty"`
+	FinishedTime string              `json:"finished_time,omitempty"`
+	Timezone     string              `json:"timezone"`
+	Stale        bool                `json:"stale,omitempty"`
+	StaleParams  []models.TaskParams `json:"stale_params,omitempty"` // HeadSHA 為 PR 目前的 head
+}
+
 // GetTaskResultHandler returns task result JSON for preview usage.
+// Display times follow ?tz= or the configured timezone.
 func GetTaskResultHandler(c *gin.Context) {
 	taskID := c.Param("taskID")
+	loc, err := requestLocation(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid timezone: %v", err))
+		return
+	}
 	ctx := context.Background()
 	result, err := DB.GetResult(ctx, taskID)
 	if err != nil {
 		if err == go_redis.Nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Task result for ID %s not found", taskID)})
+			respondError(c, http.StatusNotFound, fmt.Sprintf("Task result for ID %s not found", taskID))
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve task result"})
+		respondError(c, http.StatusInternalServerError, "Failed to retrieve task result")
 		return
 	}
 	if result == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Task result for ID %s not found", taskID)})
+		respondError(c, http.StatusNotFound, fmt.Sprintf("Task result for ID %s not found", taskID))
 		return
 	}
-	c.JSON(http.StatusOK, result)
+	stale := newHeadChecker(ctx).staleParams(result.Params)
+	c.JSON(http.StatusOK, taskResultView{
+		TaskResult:   result,
+		StartedTime:  formatUnix(result.StartedAt, loc),
+		FinishedTime: formatUnix(result.FinishedAt, loc),
+		Timezone:     loc.String(),
+		Stale:        len(stale) > 0,
+		StaleParams:  stale,
+	})
 }
@@ -2,47 +2,61 @@ package server
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 
 	"web_test/pkg/models"
-	"web_test/internal/logger"
 )
 
 func HistoryRoute() []Route {
-    return []Route{
+	return []Route{
 		{
-			Name:    "get history",
-			Method:  http.MethodGet,
-			Pattern: "/",
+			Name:        "get history",
+			Method:      http.MethodGet,
+			Pattern:     "/",
 			HandlerFunc: HistoryHandler,
+			Role:        models.RoleViewer,
+			Response:    []historyRecordView{},
+			Query:       map[string]string{"tz": "顯示時間使用的 IANA 時區"},
 		},
 	}
 }
 
+// historyRecordView 在歷史紀錄上標示 PR 是否已有新的 commit
+type historyRecordView struct {
+	models.HistoryRecord
+	Stale       bool                `json:"stale,omitempty"`
+	StaleParams []models.TaskParams `json:"stale_params,omitempty"` // HeadSHA 為 PR 目前的 head
+}
+
 // 7. 歷史紀錄
+// 時間以 UTC Unix 秒儲存，回傳時依 ?tz= 或設定的時區產生 time 欄位
 func HistoryHandler(c *gin.Context) {
+	loc, err := requestLocation(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid timezone: %v", err))
+		return
+	}
+
 	ctx := context.Background()
 	val, err := DB.GetHistory(ctx, 0, 100)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "failed to retrieve history"})
+		respondError(c, http.StatusInternalServerError, "failed to retrieve history")
 		return
 	}
 
-	var records []models.HistoryRecord
-
+	heads := newHeadChecker(ctx)
+	records := make([]historyRecordView, 0, len(val))
 	for _, v := range val {
-		dataBytes, err := json.Marshal(v)
-		if err != nil {
-			logger.WebLog.Warnf("HistoryRecord 序列化失敗: %v", err)
-			// 根據您的邏輯決定要 return 還是 continue
-			continue 
+		rec := *v
+		// 尚未遷移的舊紀錄沒有 FinishedAt，保留原字串
+		if rec.FinishedAt != 0 {
+			rec.Time = formatUnix(rec.FinishedAt, loc)
 		}
-		var rec models.HistoryRecord
-		json.Unmarshal([]byte(dataBytes), &rec)
-		records = append(records, rec)
+		stale := heads.staleParams(rec.Params)
+		records = append(records, historyRecordView{HistoryRecord: rec, Stale: len(stale) > 0, StaleParams: stale})
 	}
 	c.JSON(200, records)
 }
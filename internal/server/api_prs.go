@@ -2,14 +2,47 @@ package server
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"net/http"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"web_test/internal/logger"
 	"web_test/pkg/models"
 )
 
+// prCacheTTL 為 PR 快取的有效時間，過期後以 ETag 向 GitHub 重新確認
+var prCacheTTL = 10 * time.Minute
+
+// SetPrCacheTTL 設定 PR 快取的有效時間
+func SetPrCacheTTL(ttl time.Duration) {
+	prCacheTTL = ttl
+}
+
+// nfRepos 為可測試的 NF 與其 repo，順序即前端顯示順序
+var nfRepos []models.NFRepo
+
+// maxConcurrentFetches 限制同時向 GitHub 發出的請求數
+const maxConcurrentFetches = 4
+
+// SetNFRepos 設定可測試的 NF 與其 repo
+func SetNFRepos(repos []models.NFRepo) {
+	nfRepos = append([]models.NFRepo(nil), repos...)
+}
+
+// lookupNFRepo 依 NF 名稱找出對應的 repo
+func lookupNFRepo(nf string) (models.NFRepo, bool) {
+	for _, repo := range nfRepos {
+		if repo.NF == nf {
+			return repo, true
+		}
+	}
+	return models.NFRepo{}, false
+}
+
 func PrsRoute() []Route {
 	return []Route{
 		{
@@ -17,62 +50,254 @@ func PrsRoute() []Route {
 			Method:      http.MethodPost,
 			Pattern:     "/add_github",
 			HandlerFunc: AddGitHubTaskHandler,
+			Role:        models.RoleSubmitter,
+			Request:     models.GitHubRequest{},
+			Response:    fetchJob{},
+			Status:      http.StatusAccepted,
+			Query:       map[string]string{"force": "true 時忽略快取的 TTL"},
+		},
+		{
+			Name:        "get PR fetch job",
+			Method:      http.MethodGet,
+			Pattern:     "/jobs/:id",
+			HandlerFunc: GetJobHandler,
+			Role:        models.RoleViewer,
+			Response:    fetchJob{},
 		},
 		{
 			Name:        "get cached PRs",
 			Method:      http.MethodGet,
 			Pattern:     "/",
 			HandlerFunc: GetCachedPRsHandler,
+			Role:        models.RoleViewer,
+			Response:    []prCacheView{},
+			Query:       map[string]string{"repo": "只回傳 owner/repo 的快取"},
+		},
+		{
+			Name:        "refresh PRs of all NF repos",
+			Method:      http.MethodPost,
+			Pattern:     "/refresh_nfs",
+			HandlerFunc: RefreshNFPRsHandler,
+			Role:        models.RoleSubmitter,
+			Response:    fetchJob{},
+			Status:      http.StatusAccepted,
+			Query:       map[string]string{"force": "true 時忽略快取的 TTL"},
+		},
+		{
+			Name:        "get PRs grouped by NF",
+			Method:      http.MethodGet,
+			Pattern:     "/nfs",
+			HandlerFunc: GetNFPRsHandler,
+			Role:        models.RoleViewer,
+			Response:    []nfPRsView{},
+		},
+		{
+			Name:        "list free5gc releases",
+			Method:      http.MethodGet,
+			Pattern:     "/releases",
+			HandlerFunc: GetReleasesHandler,
+			Role:        models.RoleViewer,
+			Response:    releasesView{},
+			Query:       map[string]string{"force": "true 時略過快取"},
 		},
 		{
 			Name:        "clear PR cache",
 			Method:      http.MethodPost,
 			Pattern:     "/clear",
 			HandlerFunc: ClearPRCacheHandler,
+			Role:        models.RoleAdmin,
+			Response:    statusResponse{},
+			Query:       map[string]string{"repo": "只清除 owner/repo，省略時清除全部"},
 		},
 	}
 }
 
+// prCacheView 是回傳給前端的快取資料，附上快取年齡
+type prCacheView struct {
+	*models.PrCacheEntry
+	AgeSeconds int64 `json:"age_seconds"`
+	Stale      bool  `json:"stale"`
+}
+
 // 4. 取得 PR 快取
+// 可用 ?repo=owner/repo 篩選，回傳每個 repo 的快取與其年齡
 func GetCachedPRsHandler(c *gin.Context) {
 	ctx := context.Background()
-	val, err := DB.GetPrCache(ctx)
-	if err != nil {
-		c.JSON(200, []interface{}{})
-		return
+	var entries []*models.PrCacheEntry
+	if repo := c.Query("repo"); repo != "" {
+		entry, err := DB.GetPrCache(ctx, repo)
+		if err != nil {
+			logger.WebLog.Errorf("GetCachedPRsHandler: %v", err)
+			respondError(c, http.StatusInternalServerError, "failed to read PR cache")
+			return
+		}
+		if entry != nil {
+			entries = append(entries, entry)
+		}
+	} else {
+		all, err := DB.ListPrCache(ctx)
+		if err != nil {
+			logger.WebLog.Errorf("GetCachedPRsHandler: %v", err)
+			respondError(c, http.StatusInternalServerError, "failed to read PR cache")
+			return
+		}
+		entries = all
 	}
-	// return raw cached JSON
-	var raw interface{}
-	json.Unmarshal([]byte(val), &raw)
-	c.JSON(200, raw)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key() < entries[j].Key() })
+
+	now := time.Now().Unix()
+	views := make([]prCacheView, 0, len(entries))
+	for _, entry := range entries {
+		views = append(views, prCacheView{
+			PrCacheEntry: entry,
+			AgeSeconds:   now - entry.FetchedAt,
+			Stale:        entry.Expired(now),
+		})
+	}
+	c.JSON(200, views)
 }
 
 // 4.1 清除 PR 快取
+// 可用 ?repo=owner/repo 只清除單一 repo
 func ClearPRCacheHandler(c *gin.Context) {
 	ctx := context.Background()
-	if err := DB.ClearPrCache(ctx); err != nil {
-		c.JSON(500, gin.H{"error": "failed to clear PR cache"})
+	if err := DB.ClearPrCache(ctx, c.Query("repo")); err != nil {
+		respondError(c, http.StatusInternalServerError, "failed to clear PR cache")
 		return
 	}
-	c.JSON(200, gin.H{"status": "cleared"})
+	target := "pr_cache:" + c.Query("repo")
+	if c.Query("repo") == "" {
+		target = "pr_cache:all"
+	}
+	auditRequest(c, models.AuditConfig, target, "cleared")
+	c.JSON(http.StatusOK, statusResponse{Status: "cleared"})
 }
 
+// AddGitHubTaskHandler 建立背景工作更新 owner/repo 的 PR 快取，立即回傳 202 與工作 ID。
+// 快取未過期時不會呼叫 GitHub（?force=true 可強制更新）；
+// 過期時以儲存的 ETag 發出條件式請求。進度與錯誤由 GET /jobs/:id 查詢，失敗時快取維持原樣。
 func AddGitHubTaskHandler(c *gin.Context) {
-	ctx := context.Background()
 	var req models.GitHubRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": "invalid payload"})
+	if err := c.ShouldBindJSON(&req); err != nil || req.Owner == "" || req.Repo == "" {
+		respondError(c, http.StatusBadRequest, "invalid payload")
+		return
+	}
+
+	job := startFetchJob(req.Owner, req.Repo, c.Query("force") == "true")
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetJobHandler 回傳背景工作的狀態
+func GetJobHandler(c *gin.Context) {
+	job, ok := getFetchJob(c.Param("id"))
+	if !ok {
+		respondError(c, http.StatusNotFound, fmt.Sprintf("job %s not found", c.Param("id")))
 		return
 	}
+	c.JSON(http.StatusOK, job)
+}
+
+// refreshPrCache 依 TTL 與 ETag 更新單一 repo 的 PR 快取
+func refreshPrCache(ctx context.Context, owner, repo string, force bool) error {
+	key := models.PrCacheKey(owner, repo)
+	cached, err := DB.GetPrCache(ctx, key)
+	if err != nil {
+		return fmt.Errorf("read cache: %w", err)
+	}
+	now := time.Now()
+	if cached != nil && !force && !cached.Expired(now.Unix()) {
+		logger.GitHubLog.Debugf("PR cache of %s is fresh, skip fetching", key)
+		return nil
+	}
+
+	etag := ""
+	if cached != nil {
+		etag = cached.ETag
+	}
+	resp, err := FetchGitHubInfo(ctx, owner, repo, etag)
+	if err != nil {
+		return err
+	}
+
+	entry := &models.PrCacheEntry{
+		Owner:     owner,
+		Repo:      repo,
+		PRs:       resp.PRs,
+		ETag:      resp.ETag,
+		FetchedAt: now.Unix(),
+		ExpiresAt: now.Add(prCacheTTL).Unix(),
+	}
+	if resp.NotModified && cached != nil {
+		// PR 列表未變更，但快取中仍可能有 GitHub 尚未算出的 mergeable 狀態
+		entry.PRs = append([]models.PullRequest(nil), cached.PRs...)
+	}
+	fillPullDetails(ctx, owner, repo, entry.PRs, cached)
+	return DB.SavePrCache(ctx, entry)
+}
+
+// nfPRsView 是單一 NF 的 PR 列表
+type nfPRsView struct {
+	models.NFRepo
+	PRs        []models.PullRequest `json:"prs"`
+	FetchedAt  int64                `json:"fetched_at,omitempty"`
+	AgeSeconds int64                `json:"age_seconds,omitempty"`
+	Stale      bool                 `json:"stale"`
+	Cached     bool                 `json:"cached"`
+}
 
-	defer func() {
-		resp, err := FetchGitHubInfo(req.Owner, req.Repo)
-		if err == nil {
-			// 存快取
-			prsJSON, _ := json.Marshal(resp.PRs)
-			DB.SavePrCache(ctx, prsJSON)
+// GetNFPRsHandler 依設定的 NF 順序回傳各 NF 的 PR 快取，供前端提供可選的 NF:PR 組合
+func GetNFPRsHandler(c *gin.Context) {
+	ctx := context.Background()
+	now := time.Now().Unix()
+	views := make([]nfPRsView, 0, len(nfRepos))
+	for _, repo := range nfRepos {
+		view := nfPRsView{NFRepo: repo, PRs: []models.PullRequest{}, Stale: true}
+		entry, err := DB.GetPrCache(ctx, models.PrCacheKey(repo.Owner, repo.Repo))
+		if err != nil {
+			logger.WebLog.Errorf("GetNFPRsHandler: %v", err)
+			respondError(c, http.StatusInternalServerError, "failed to read PR cache")
+			return
+		}
+		if entry != nil {
+			view.PRs = entry.PRs
+			view.FetchedAt = entry.FetchedAt
+			view.AgeSeconds = now - entry.FetchedAt
+			view.Stale = entry.Expired(now)
+			view.Cached = true
 		}
-	}() // 立即執行抓取任務
+		views = append(views, view)
+	}
+	c.JSON(200, views)
+}
 
-	c.JSON(200, gin.H{"status": "queued"})
+// RefreshNFPRsHandler 建立背景工作同時更新所有 NF repo 的 PR 快取（?force=true 忽略 TTL），
+// 以 GET /jobs/:id 查詢結果
+func RefreshNFPRsHandler(c *gin.Context) {
+	c.JSON(http.StatusAccepted, startFetchJob("", "", c.Query("force") == "true"))
+}
+
+// refreshNFRepos 以有限的並行數更新所有 NF repo 的快取，回傳失敗的 NF 與錯誤
+func refreshNFRepos(ctx context.Context, force bool) map[string]error {
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs = make(map[string]error)
+		sem  = make(chan struct{}, maxConcurrentFetches)
+	)
+	for _, repo := range nfRepos {
+		wg.Add(1)
+		go func(repo models.NFRepo) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if err := refreshPrCache(ctx, repo.Owner, repo.Repo, force); err != nil {
+				logger.GitHubLog.Errorf("Failed to refresh PR cache of %s (%s/%s): %v", repo.NF, repo.Owner, repo.Repo, err)
+				mu.Lock()
+				errs[repo.NF] = err
+				mu.Unlock()
+			}
+		}(repo)
+	}
+	wg.Wait()
+	return errs
 }
@@ -0,0 +1,314 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"web_test/internal/github"
+	"web_test/internal/github/githubtest"
+	"web_test/pkg/database"
+	"web_test/pkg/models"
+)
+
+// newPRTestEngine 以記憶體 ResultStore 與假 GitHub 伺服器建立 /api/prs 路由
+func newPRTestEngine(t *testing.T, gh *githubtest.Server, repos []models.NFRepo) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	oldDB, oldClient, oldRepos := DB, ghClient, nfRepos
+	t.Cleanup(func() { DB, ghClient, nfRepos = oldDB, oldClient, oldRepos })
+
+	DB = database.NewMemoryDB()
+	SetGitHubClient(github.NewClient(gh.URL, ""))
+	SetNFRepos(repos)
+
+	engine := gin.New()
+	applyRoutes(engine.Group("/api/prs"), PrsRoute())
+	return engine
+}
+
+func doRequest(engine *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestNFPRsFlow(t *testing.T) {
+	gh := githubtest.NewServer()
+	defer gh.Close()
+	gh.AddPull("free5gc", "amf", githubtest.Pull{Number: 1, Title: "amf fix"})
+	gh.AddPull("free5gc", "go-upf", githubtest.Pull{Number: 7, Title: "upf feature"})
+	gh.AddPull("free5gc", "go-upf", githubtest.Pull{Number: 8, Title: "closed", State: "closed"})
+	gh.AddRelease("free5gc", "go-upf", githubtest.Release{TagName: "v1.2.0"})
+
+	engine := newPRTestEngine(t, gh, []models.NFRepo{
+		{NF: "amf", Owner: "free5gc", Repo: "amf"},
+		{NF: "upf", Owner: "free5gc", Repo: "go-upf"},
+	})
+
+	if job := runJob(t, engine, "/api/prs/refresh_nfs", ""); job.Status != JobSucceeded || job.Repos != 2 {
+		t.Fatalf("refresh_nfs job = %+v, want succeeded for 2 repos", job)
+	}
+
+	w := doRequest(engine, http.MethodGet, "/api/prs/nfs", "")
+	var views []nfPRsView
+	if err := json.Unmarshal(w.Body.Bytes(), &views); err != nil {
+		t.Fatalf("decode /nfs: %v", err)
+	}
+	if len(views) != 2 || views[0].NF != "amf" || views[1].NF != "upf" {
+		t.Fatalf("views = %+v, want amf then upf", views)
+	}
+	if !views[1].Cached || len(views[1].PRs) != 1 || views[1].PRs[0].Number != 7 {
+		t.Fatalf("upf PRs = %+v, want only open PR 7", views[1].PRs)
+	}
+
+	// 快取未過期時不應再呼叫 GitHub
+	before := len(gh.Requests())
+	runJob(t, engine, "/api/prs/refresh_nfs", "")
+	if after := len(gh.Requests()); after != before {
+		t.Fatalf("fresh cache triggered %d GitHub requests", after-before)
+	}
+
+	// 強制更新會帶 ETag，新的 PR 需出現在快取中
+	gh.AddPull("free5gc", "amf", githubtest.Pull{Number: 2, Title: "amf feature"})
+	runJob(t, engine, "/api/prs/refresh_nfs?force=true", "")
+	entry, err := DB.GetPrCache(context.Background(), "free5gc/amf")
+	if err != nil || entry == nil || len(entry.PRs) != 2 {
+		t.Fatalf("amf cache = %+v, %v; want 2 PRs", entry, err)
+	}
+}
+
+func TestRefreshNFsJobFailures(t *testing.T) {
+	gh := githubtest.NewServer()
+	defer gh.Close()
+	gh.AddPull("free5gc", "amf", githubtest.Pull{Number: 1})
+	engine := newPRTestEngine(t, gh, []models.NFRepo{
+		{NF: "amf", Owner: "free5gc", Repo: "amf"},
+		{NF: "upf", Owner: "free5gc", Repo: "go-upf"},
+	})
+
+	job := runJob(t, engine, "/api/prs/refresh_nfs", "")
+	if job.Status != JobPartial || len(job.Errors) != 1 || job.Errors["upf"] == "" {
+		t.Fatalf("job = %+v, want partial with upf failed", job)
+	}
+
+	SetNFRepos([]models.NFRepo{{NF: "upf", Owner: "free5gc", Repo: "go-upf"}})
+	job = runJob(t, engine, "/api/prs/refresh_nfs", "")
+	if job.Status != JobFailed || job.Error == "" || job.StatusCode != http.StatusNotFound {
+		t.Fatalf("job = %+v, want failed with 404", job)
+	}
+}
+
+// runJob 送出 add_github 或 refresh_nfs 並等待背景工作結束，回傳 GET /jobs/:id 的結果
+func runJob(t *testing.T, engine *gin.Engine, path, body string) fetchJob {
+	t.Helper()
+	w := doRequest(engine, http.MethodPost, path, body)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("%s: %d %s, want 202", path, w.Code, w.Body)
+	}
+	var job fetchJob
+	if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil || job.ID == "" {
+		t.Fatalf("decode job: %v (%s)", err, w.Body)
+	}
+
+	fetchJobs.Lock()
+	done := fetchJobs.byID[job.ID].done
+	fetchJobs.Unlock()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("job %s did not finish", job.ID)
+	}
+
+	w = doRequest(engine, http.MethodGet, "/api/prs/jobs/"+job.ID, "")
+	job = fetchJob{}
+	if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil {
+		t.Fatalf("decode job status: %v (%s)", err, w.Body)
+	}
+	return job
+}
+
+func TestAddGitHubJob(t *testing.T) {
+	gh := githubtest.NewServer()
+	defer gh.Close()
+	engine := newPRTestEngine(t, gh, nil)
+	gh.AddPull("free5gc", "amf", githubtest.Pull{Number: 1})
+
+	job := runJob(t, engine, "/api/prs/add_github", `{"owner":"free5gc","repo":"amf"}`)
+	if job.Status != JobSucceeded || job.FinishedAt == 0 {
+		t.Fatalf("job = %+v, want succeeded", job)
+	}
+
+	job = runJob(t, engine, "/api/prs/add_github", `{"owner":"free5gc","repo":"missing"}`)
+	if job.Status != JobFailed || job.StatusCode != http.StatusNotFound || !strings.Contains(job.Error, "Not Found") {
+		t.Fatalf("missing repo: %+v, want failed with 404 and the GitHub message", job)
+	}
+
+	// 失敗時不覆蓋既有快取
+	gh.AddPull("free5gc", "amf", githubtest.Pull{Number: 2})
+	gh.SetRateLimit(0, time.Now().Add(time.Hour))
+	job = runJob(t, engine, "/api/prs/add_github?force=true", `{"owner":"free5gc","repo":"amf"}`)
+	if job.Status != JobFailed || job.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("rate limited: %+v, want failed with 429", job)
+	}
+	entry, err := DB.GetPrCache(context.Background(), "free5gc/amf")
+	if err != nil || entry == nil || len(entry.PRs) != 1 {
+		t.Fatalf("amf cache = %+v, %v; want the previous PR list kept", entry, err)
+	}
+
+	if w := doRequest(engine, http.MethodGet, "/api/prs/jobs/999", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown job: %d, want 404", w.Code)
+	}
+	if w := doRequest(engine, http.MethodPost, "/api/prs/add_github", `{"owner":"free5gc"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("missing repo name: %d, want 400", w.Code)
+	}
+}
+
+func TestPRMetadataAndChangedFiles(t *testing.T) {
+	gh := githubtest.NewServer()
+	defer gh.Close()
+	updated := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	gh.AddPull("free5gc", "go-upf", githubtest.Pull{
+		Number: 7, Title: "upf feature", User: "alice", HeadSHA: "aaa", BaseRef: "v4.0",
+		Labels: []string{"ci"}, Draft: true, Mergeable: "dirty", UpdatedAt: updated,
+		Files: []string{"internal/pfcp/handler.go", "go.mod"},
+	})
+	gh.AddPull("free5gc", "go-upf", githubtest.Pull{Number: 8, HeadSHA: "bbb", Files: []string{"README.md"}})
+	engine := newPRTestEngine(t, gh, []models.NFRepo{{NF: "upf", Owner: "free5gc", Repo: "go-upf"}})
+
+	runJob(t, engine, "/api/prs/refresh_nfs", "")
+	entry, err := DB.GetPrCache(context.Background(), "free5gc/go-upf")
+	if err != nil || entry == nil || len(entry.PRs) != 2 {
+		t.Fatalf("cache = %+v, %v; want 2 PRs", entry, err)
+	}
+	pr := entry.PRs[0]
+	if pr.Author != "alice" || pr.BaseRef != "v4.0" || !pr.Draft || pr.MergeableState != "dirty" ||
+		pr.UpdatedAt != updated.Unix() || len(pr.Labels) != 1 || pr.Labels[0] != "ci" {
+		t.Fatalf("PR 7 = %+v, want full metadata", pr)
+	}
+	if pr.ChangedFiles != nil || countRequests(gh.Requests(), "/files") != 0 {
+		t.Fatalf("PR 7 = %+v, want changed files left to test selection", pr)
+	}
+
+	// 挑選測試時才取得變更檔案並寫回快取，之後不再向 GitHub 查詢
+	params := []models.TaskParams{{NF: "upf", PRVersion: "7", HeadSHA: "aaa"}}
+	for i := 0; i < 2; i++ {
+		if files := changedFiles(context.Background(), params, true)["upf"]; len(files) != 2 || files[0] != "internal/pfcp/handler.go" {
+			t.Fatalf("changed files = %v", files)
+		}
+	}
+	if n := countRequests(gh.Requests(), "/pulls/7/files"); n != 1 {
+		t.Fatalf("file requests of PR 7 = %d, want 1", n)
+	}
+
+	// 只有 head 改變的 PR 重新查詢 mergeable 狀態，並清空變更檔案
+	gh.AddPull("free5gc", "go-upf", githubtest.Pull{Number: 7, HeadSHA: "ccc", Mergeable: "clean", Files: []string{"main.go"}})
+	before := len(gh.Requests())
+	runJob(t, engine, "/api/prs/refresh_nfs?force=true", "")
+	requests := gh.Requests()[before:]
+	if countRequests(requests, "/pulls/7") != 1 || countRequests(requests, "/pulls/8") != 0 || countRequests(requests, "/files") != 0 {
+		t.Fatalf("requests = %v, want only the detail of PR 7", requests)
+	}
+	entry, _ = DB.GetPrCache(context.Background(), "free5gc/go-upf")
+	if entry.PRs[0].MergeableState != "clean" || entry.PRs[0].ChangedFiles != nil {
+		t.Fatalf("PR 7 = %+v, want refetched state and no stale files", entry.PRs[0])
+	}
+}
+
+// countRequests 回傳路徑（不含查詢字串）以 suffix 結尾的請求數
+func countRequests(requests []string, suffix string) int {
+	n := 0
+	for _, req := range requests {
+		if path, _, _ := strings.Cut(req, "?"); strings.HasSuffix(path, suffix) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestPRMergeableUnknownRefetched(t *testing.T) {
+	gh := githubtest.NewServer()
+	defer gh.Close()
+	gh.AddPull("free5gc", "amf", githubtest.Pull{Number: 3, HeadSHA: "aaa", Mergeable: "unknown", Files: []string{"go.mod"}})
+	engine := newPRTestEngine(t, gh, []models.NFRepo{{NF: "amf", Owner: "free5gc", Repo: "amf"}})
+
+	runJob(t, engine, "/api/prs/refresh_nfs", "")
+	entry, _ := DB.GetPrCache(context.Background(), "free5gc/amf")
+	if entry == nil || len(entry.PRs) != 1 || entry.PRs[0].MergeableState != "unknown" {
+		t.Fatalf("cache = %+v, want PR 3 with unknown state", entry)
+	}
+
+	// GitHub 算出 mergeable 狀態後，即使 PR 未變更也要重新查詢，但不取得變更檔案
+	gh.AddPull("free5gc", "amf", githubtest.Pull{Number: 3, HeadSHA: "aaa", Mergeable: "dirty", Files: []string{"go.mod"}})
+	before := len(gh.Requests())
+	runJob(t, engine, "/api/prs/refresh_nfs?force=true", "")
+	requests := gh.Requests()[before:]
+	if countRequests(requests, "/pulls/3") != 1 || countRequests(requests, "/files") != 0 {
+		t.Fatalf("requests = %v, want one PR detail request and no file requests", requests)
+	}
+	entry, _ = DB.GetPrCache(context.Background(), "free5gc/amf")
+	if entry.PRs[0].MergeableState != "dirty" {
+		t.Fatalf("PR 3 = %+v, want dirty", entry.PRs[0])
+	}
+}
+
+func TestFetchJobDedup(t *testing.T) {
+	gh := githubtest.NewServer()
+	defer gh.Close()
+	newPRTestEngine(t, gh, nil)
+	// GitHub 在 unblock 前不回應，讓強制更新的工作維持執行中
+	release := make(chan struct{})
+	blocking := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		http.NotFound(w, r)
+	}))
+	var once sync.Once
+	unblock := func() { once.Do(func() { close(release) }) }
+	defer blocking.Close()
+	defer unblock()
+	SetGitHubClient(github.NewClient(blocking.URL, ""))
+
+	// 模擬執行中、未強制更新的工作
+	fetchJobs.Lock()
+	fetchJobs.nextID++
+	running := &fetchJob{ID: strconv.Itoa(fetchJobs.nextID), Owner: "free5gc", Repo: "amf", Status: JobRunning, done: make(chan struct{})}
+	fetchJobs.byID[running.ID] = running
+	fetchJobs.Unlock()
+	t.Cleanup(func() {
+		fetchJobs.Lock()
+		delete(fetchJobs.byID, running.ID)
+		fetchJobs.Unlock()
+	})
+
+	if job := startFetchJob("free5gc", "amf", false); job.ID != running.ID {
+		t.Fatalf("unforced request started job %s, want the running job %s", job.ID, running.ID)
+	}
+	forced := startFetchJob("free5gc", "amf", true)
+	if forced.ID == running.ID || !forced.Force {
+		t.Fatalf("forced request = %+v, want a new forced job", forced)
+	}
+	if job := startFetchJob("free5gc", "amf", true); job.ID != forced.ID {
+		t.Fatalf("second forced request started job %s, want the forced job %s", job.ID, forced.ID)
+	}
+
+	unblock()
+	fetchJobs.Lock()
+	done := fetchJobs.byID[forced.ID].done
+	fetchJobs.Unlock()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("forced job %s did not finish", forced.ID)
+	}
+}
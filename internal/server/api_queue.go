@@ -3,9 +3,11 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"      // Add fmt for Sprintf
 	"net/http" // Add http for status codes
 	"strconv"
+	"time"
 
 	"web_test/internal/logger" // Import logger
 	"web_test/pkg/models"
@@ -14,24 +16,56 @@ import (
 )
 
 func QueueRoute() []Route {
-    return []Route{
+	return []Route{
 		{
-			Name:    "get queue",
-			Method:  http.MethodGet,
-			Pattern: "/list",
+			Name:        "get queue",
+			Method:      http.MethodGet,
+			Pattern:     "/list",
 			HandlerFunc: GetQueueHandler,
+			Role:        models.RoleViewer,
+			Response:    []models.TaskResult{},
 		},
 		{
-			Name:    "remove from queue",
-			Method:  http.MethodDelete,
-			Pattern: "/delete/:taskID",
+			Name:        "remove from queue",
+			Method:      http.MethodDelete,
+			Pattern:     "/delete/:taskID",
 			HandlerFunc: DeleteFromQueueHandler,
+			Role:        models.RoleSubmitter,
+			Response:    statusResponse{},
 		},
-        {
-			Name:    "run PR task",
-			Method:  http.MethodPost,
-			Pattern: "/run-pr",
+		{
+			Name:        "run PR task",
+			Method:      http.MethodPost,
+			Pattern:     "/run-pr",
 			HandlerFunc: RunPRTaskHandler,
+			Role:        models.RoleSubmitter,
+			Request:     models.RunPRRequest{},
+			Response:    runPRResponse{},
+		},
+		{
+			Name:        "preview test selection",
+			Method:      http.MethodPost,
+			Pattern:     "/select-tests",
+			HandlerFunc: SelectTestsHandler,
+			Role:        models.RoleViewer,
+			Request:     models.RunPRRequest{},
+			Response:    testSelectionView{},
+		},
+		{
+			Name:        "get task progress",
+			Method:      http.MethodGet,
+			Pattern:     "/progress/:taskID",
+			HandlerFunc: GetTaskProgressHandler,
+			Role:        models.RoleViewer,
+			Response:    models.ProgressInfo{},
+		},
+		{
+			Name:        "cancel task",
+			Method:      http.MethodPost,
+			Pattern:     "/cancel/:taskID",
+			HandlerFunc: CancelTaskHandler,
+			Role:        models.RoleSubmitter,
+			Response:    statusResponse{},
 		},
 	}
 }
@@ -39,7 +73,7 @@ func QueueRoute() []Route {
 // 1. 取得佇列
 func GetQueueHandler(c *gin.Context) {
 	if TaskQ == nil {
-		c.JSON(500, gin.H{"error": "task queue is not initialized"})
+		respondError(c, http.StatusInternalServerError, "task queue is not initialized")
 		return
 	}
 	ctx := context.Background()
@@ -48,14 +82,19 @@ func GetQueueHandler(c *gin.Context) {
 	running_tasks, err := DB.GetRunningTasks(ctx)
 	if err != nil {
 		logger.WebLog.Errorf("GetQueueHandler: Failed to get running tasks: %v", err)
-		c.JSON(500, gin.H{"error": "failed to get running tasks"})
+		respondError(c, http.StatusInternalServerError, "failed to get running tasks")
 		return
 	}
 	for _, rt := range running_tasks {
 		taskResult := models.TaskResult{
-			TaskID: rt.TaskID,
-			Status: "running",
-			Params: rt.Params,
+			TaskID:      rt.TaskID,
+			Status:      models.StatusRunning,
+			Params:      rt.Params,
+			BaseRef:     rt.BaseRef,
+			Selection:   rt.Selection,
+			SubmittedBy: rt.SubmittedBy,
+			Source:      rt.Source,
+			Progress:    taskProgress(ctx, rt.TaskID),
 		}
 		return_tasks = append(return_tasks, taskResult)
 	}
@@ -63,14 +102,14 @@ func GetQueueHandler(c *gin.Context) {
 	tasks, err := TaskQ.GetTasks(ctx)
 	if err != nil {
 		logger.WebLog.Errorf("GetQueueHandler: Failed to get tasks from queue: %v", err)
-		c.JSON(500, gin.H{"error": "failed to get tasks from queue"})
+		respondError(c, http.StatusInternalServerError, "failed to get tasks from queue")
 		return
 	}
 	for _, task := range tasks {
 		taskBytes, err := json.Marshal(task)
 		if err != nil {
 			logger.WebLog.Warnf("序列化失敗: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode task"})
+			respondError(c, http.StatusInternalServerError, "Failed to encode task")
 			return
 		}
 		var tmp models.Task
@@ -79,9 +118,13 @@ func GetQueueHandler(c *gin.Context) {
 			continue
 		}
 		taskResult := models.TaskResult{
-			TaskID: tmp.ID,
-			Status: "queueing", // Placeholder status
-			Params: tmp.Params,
+			TaskID:      tmp.ID,
+			Status:      models.StatusQueueing, // Placeholder status
+			Params:      tmp.Params,
+			BaseRef:     tmp.BaseRef,
+			Selection:   tmp.Selection,
+			SubmittedBy: tmp.SubmittedBy,
+			Source:      tmp.Source,
 		}
 		return_tasks = append(return_tasks, taskResult)
 	}
@@ -91,7 +134,7 @@ func GetQueueHandler(c *gin.Context) {
 // 2. 刪除佇列任務
 func DeleteFromQueueHandler(c *gin.Context) {
 	if TaskQ == nil {
-		c.JSON(500, gin.H{"error": "task queue is not initialized"})
+		respondError(c, http.StatusInternalServerError, "task queue is not initialized")
 		return
 	}
 	ctx := context.Background()
@@ -99,10 +142,20 @@ func DeleteFromQueueHandler(c *gin.Context) {
 	targetID, err := strconv.Atoi(taskIDStr)
 	if err != nil {
 		logger.WebLog.Errorf("DeleteFromQueueHandler: Invalid task ID received: %s, error: %v", taskIDStr, err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		respondError(c, http.StatusBadRequest, "Invalid task ID")
 		return
 	}
 
+	// 先取得任務內容，供稽核紀錄使用
+	var detail string
+	if tasks, err := TaskQ.GetTasks(ctx); err == nil {
+		for _, task := range tasks {
+			if task.ID == taskIDStr {
+				detail = describeParams(task.Params)
+			}
+		}
+	}
+
 	deleted := false
 	if err := TaskQ.RemoveTask(ctx, taskIDStr); err == nil {
 		deleted = true
@@ -110,62 +163,153 @@ func DeleteFromQueueHandler(c *gin.Context) {
 		logger.WebLog.Errorf("DeleteFromQueueHandler: Failed to remove task %d from queue: %v", targetID, err)
 	}
 	if deleted {
-		c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+		auditRequest(c, models.AuditDelete, "task:"+taskIDStr, detail)
+		c.JSON(http.StatusOK, statusResponse{Status: "deleted"})
 	} else {
-		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Task ID %d not found in queue", targetID)})
+		respondError(c, http.StatusNotFound, fmt.Sprintf("Task ID %d not found in queue", targetID))
 	}
 }
 // 5. 執行 PR 任務 (加入佇列)
 func RunPRTaskHandler(c *gin.Context) {
 	if TaskQ == nil {
-		c.JSON(500, gin.H{"error": "task queue is not initialized"})
+		respondError(c, http.StatusInternalServerError, "task queue is not initialized")
 		return
 	}
 	ctx := context.Background()
 	var req models.RunPRRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		logger.WebLog.Errorf("Failed to bind JSON: %v", err)
-		c.JSON(400, gin.H{"error": "invalid payload"})
+		respondError(c, http.StatusBadRequest, "invalid payload")
 		return
 	}
-	logger.WebLog.Infof("Received request: %+v", req)
-	logger.WebLog.Infof("Params slice length: %d", len(req.Params))
-	logger.WebLog.Infof("Params content: %+v", req.Params)
+	logger.WebLog.Debugf("Received run-pr request: %+v", req)
 	if len(req.Params) == 0 {
-		c.JSON(400, gin.H{"error": "params cannot be empty"})
+		respondError(c, http.StatusBadRequest, "params cannot be empty")
+		return
+	}
+	if !validBaseRef(req.BaseRef) {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid base ref %q", req.BaseRef))
 		return
 	}
 
-	taskID, err := GenerateUniqueTaskID() // Use the new unique ID generator
+	params, err := parseRunParams(req.Params)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "failed to generate task ID"})
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if req.Selection != nil {
+		if err := validateSelection(req.Selection); err != nil {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid test selection: %v", err))
+			return
+		}
+		req.Selection.Manual = true
+	}
+	task, err := enqueueTask(ctx, &models.Task{
+		Params:      params,
+		BaseRef:     req.BaseRef,
+		Selection:   req.Selection,
+		SubmittedBy: requestActor(c),
+		Source:      requestSource(c),
+	})
+	if err != nil {
+		logger.WebLog.Errorf("RunPRTaskHandler: %v", err)
+		respondError(c, enqueueErrorStatus(err), err.Error())
+		return
+	}
+	logger.WebLog.Infof("Enqueued PR task %s with %d params on base %q", task.ID, len(params), task.BaseRef)
+	resp := runPRResponse{TaskID: task.ID, Status: "queued", Selection: task.Selection}
+	if isAPIV1(c) {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+	c.JSON(http.StatusOK, legacyRunPRResponse{runPRResponse: resp, Reply: "任務已加入佇列，參數已傳送。"})
+}
+
+// parseRunParams 將前端送來的 [NF, PR] 組合轉成任務參數
+func parseRunParams(pairs [][]string) ([]models.TaskParams, error) {
 	var params []models.TaskParams
-	for _, pair := range req.Params {
+	for _, pair := range pairs {
 		if len(pair) < 2 {
-			continue 
+			continue
 		}
 		nf := string(pair[0])
 		prVersion := string(pair[1])
-		logger.WebLog.Infof("Processing NF: %s, PRVersion: %s", nf, prVersion)
+		if _, ok := lookupNFRepo(nf); !ok {
+			return nil, fmt.Errorf("unknown NF %q", nf)
+		}
+		if _, err := strconv.Atoi(prVersion); err != nil {
+			return nil, fmt.Errorf("invalid PR number %q for %s", prVersion, nf)
+		}
 		params = append(params, models.TaskParams{
 			NF:        nf,
 			PRVersion: prVersion,
 		})
 	}
 	if len(params) == 0 {
-		c.JSON(400, gin.H{"error": "no valid params provided"})
-		return
+		return nil, errors.New("no valid params provided")
 	}
-	task := models.Task{
-		ID:     fmt.Sprintf("%d", taskID), // Assign the generated unique TaskID
-		Params: params,                    // 轉發參數
+	return params, nil
+}
+
+// errHeadUnresolved 表示加入佇列時無法取得 PR 的 head commit
+var errHeadUnresolved = errors.New("cannot resolve PR head commit")
+
+// enqueueTask 補上 PR head commit 與測試範圍、產生任務 ID 並將任務加入佇列，供 UI 與 webhook 共用。
+// task.Selection 為 nil 時依 PR 變更的檔案自動挑選。
+func enqueueTask(ctx context.Context, task *models.Task) (*models.Task, error) {
+	if err := resolveHeadSHAs(ctx, task.Params); err != nil {
+		return nil, fmt.Errorf("%w: %w", errHeadUnresolved, err)
 	}
-	logger.WebLog.Infof("Enqueuing PR task %s with %d params", task.ID, len(params))
-	if err := TaskQ.PushTask(ctx, &task); err != nil {
-		c.JSON(500, gin.H{"error": fmt.Sprintf("failed to enqueue task: %v", err)})
-		return
+	if task.Selection == nil {
+		task.Selection = selectTests(ctx, task.Params, true)
+	}
+	taskID, err := GenerateUniqueTaskID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate task ID: %w", err)
+	}
+	task.ID = strconv.Itoa(taskID)
+	task.QueuedAt = time.Now().Unix()
+	if err := TaskQ.PushTask(ctx, task); err != nil {
+		return nil, fmt.Errorf("failed to enqueue task: %w", err)
+	}
+	detail := describeParams(task.Params)
+	if task.BaseRef != "" {
+		detail += " base=" + task.BaseRef
+	}
+	recordAudit(ctx, task.SubmittedBy, task.Source, models.AuditEnqueue, "task:"+task.ID, detail)
+	return task, nil
+}
+
+// enqueueErrorStatus 回傳 enqueueTask 錯誤對應的 HTTP 狀態碼
+func enqueueErrorStatus(err error) int {
+	if errors.Is(err, errHeadUnresolved) {
+		return gitHubErrorStatus(err)
+	}
+	return http.StatusInternalServerError
+}
+
+// findQueuedTask 回傳佇列中 NF 與 PR 和 params 相同的任務（不比較 head commit），沒有則回傳 nil
+func findQueuedTask(ctx context.Context, params []models.TaskParams) (*models.Task, error) {
+	tasks, err := TaskQ.GetTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, task := range tasks {
+		if sameParams(task.Params, params) {
+			return task, nil
+		}
+	}
+	return nil, nil
+}
+
+func sameParams(a, b []models.TaskParams) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].NF != b[i].NF || a[i].PRVersion != b[i].PRVersion {
+			return false
+		}
 	}
-	c.JSON(200, gin.H{"reply": "任務已加入佇列，參數已傳送。"})
+	return true
 }
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"web_test/internal/logger"
+	"web_test/pkg/models"
+)
+
+// baseRepo 為 ci-operation.sh pull 取出的 free5gc 主 repo，可選的測試基準來自其 release
+var baseRepo = models.GitHubTask{Owner: "free5gc", Repo: "free5gc"}
+
+// SetBaseRepo 設定列出 release 使用的 free5gc 主 repo（owner/repo）
+func SetBaseRepo(fullName string) {
+	if owner, repo, ok := strings.Cut(fullName, "/"); ok && owner != "" && repo != "" {
+		baseRepo = models.GitHubTask{Owner: owner, Repo: repo}
+	}
+}
+
+// baseRefPattern 限制測試基準為 tag、分支或 commit 名稱，避免被當成 git 選項
+var baseRefPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]*$`)
+
+// validBaseRef 檢查測試基準是否為合法的 git ref；空字串代表預設分支
+func validBaseRef(ref string) bool {
+	if ref == "" {
+		return true
+	}
+	return len(ref) <= 100 && baseRefPattern.MatchString(ref) &&
+		!strings.Contains(ref, "..") && !strings.HasSuffix(ref, ".lock") && !strings.HasSuffix(ref, "/")
+}
+
+// releaseCache 在記憶體中保存 free5gc 的 release 列表，與 PR 快取使用相同的有效時間
+var releaseCache struct {
+	sync.Mutex
+	repo      string
+	releases  []models.Release
+	expiresAt time.Time
+}
+
+// releasesView 是回傳給前端的 release 列表
+type releasesView struct {
+	Repo     string           `json:"repo"`
+	Releases []models.Release `json:"releases"` // 最新的在前
+}
+
+// 取得可選的測試基準 (free5gc release)；?force=true 時略過快取
+func GetReleasesHandler(c *gin.Context) {
+	rels, err := listBaseReleases(c.Request.Context(), c.Query("force") == "true")
+	if err != nil {
+		logger.GitHubLog.Errorf("Failed to list releases of %s/%s: %v", baseRepo.Owner, baseRepo.Repo, err)
+		respondError(c, gitHubErrorStatus(err), err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, releasesView{Repo: baseRepo.Owner + "/" + baseRepo.Repo, Releases: rels})
+}
+
+// listBaseReleases 回傳 free5gc 主 repo 的 release，快取未過期時不呼叫 GitHub
+func listBaseReleases(ctx context.Context, force bool) ([]models.Release, error) {
+	repo := baseRepo.Owner + "/" + baseRepo.Repo
+	releaseCache.Lock()
+	defer releaseCache.Unlock()
+	if !force && releaseCache.repo == repo && time.Now().Before(releaseCache.expiresAt) {
+		return releaseCache.releases, nil
+	}
+
+	rels, err := ghClient.ListReleases(ctx, baseRepo.Owner, baseRepo.Repo)
+	if err != nil {
+		return nil, err
+	}
+	releaseCache.repo = repo
+	releaseCache.releases = rels
+	releaseCache.expiresAt = time.Now().Add(prCacheTTL)
+	return rels, nil
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"web_test/internal/github/githubtest"
+	"web_test/pkg/models"
+	"web_test/pkg/queue"
+)
+
+func TestReleasesAndBaseRef(t *testing.T) {
+	gh := githubtest.NewServer()
+	defer gh.Close()
+	gh.AddRelease("free5gc", "free5gc", githubtest.Release{Name: "v4.0.0", TagName: "v4.0.0"})
+	gh.AddRelease("free5gc", "free5gc", githubtest.Release{Name: "v4.0.1", TagName: "v4.0.1"})
+	gh.AddPull("free5gc", "go-upf", githubtest.Pull{Number: 7, HeadSHA: "abc"})
+
+	engine := newPRTestEngine(t, gh, []models.NFRepo{{NF: "upf", Owner: "free5gc", Repo: "go-upf"}})
+	oldQ := TaskQ
+	t.Cleanup(func() { TaskQ = oldQ })
+	TaskQ = queue.NewListQueue()
+	applyRoutes(engine.Group("/api/queue"), QueueRoute())
+
+	w := doRequest(engine, http.MethodGet, "/api/prs/releases?force=true", "")
+	var view releasesView
+	if err := json.Unmarshal(w.Body.Bytes(), &view); err != nil {
+		t.Fatalf("decode /releases: %v (%s)", err, w.Body)
+	}
+	if view.Repo != "free5gc/free5gc" || len(view.Releases) != 2 || view.Releases[0].TagName != "v4.0.1" {
+		t.Fatalf("releases = %+v, want v4.0.1 then v4.0.0", view)
+	}
+
+	for _, ref := range []string{"-b", "main..dev", "v4.0.1; rm -rf /", "refs/heads/"} {
+		body := `{"params":[["upf","7"]],"base_ref":` + mustJSON(t, ref) + `}`
+		if w := doRequest(engine, http.MethodPost, "/api/queue/run-pr", body); w.Code != http.StatusBadRequest {
+			t.Errorf("base ref %q: %d, want 400", ref, w.Code)
+		}
+	}
+
+	w = doRequest(engine, http.MethodPost, "/api/queue/run-pr", `{"params":[["upf","7"]],"base_ref":"v4.0.1"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("run-pr: %d %s", w.Code, w.Body)
+	}
+	tasks, _ := TaskQ.GetTasks(context.Background())
+	if len(tasks) != 1 || tasks[0].BaseRef != "v4.0.1" || tasks[0].Params[0].HeadSHA != "abc" {
+		t.Fatalf("tasks = %+v, want one task on base v4.0.1", tasks)
+	}
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
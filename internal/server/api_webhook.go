@@ -0,0 +1,306 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"web_test/internal/logger"
+	"web_test/pkg/models"
+)
+
+// maxWebhookPayload 為 GitHub webhook payload 的上限 (25 MB)
+const maxWebhookPayload = 25 << 20
+
+// deliveryTTL 為重複的 X-GitHub-Delivery 被忽略的時間
+const deliveryTTL = time.Hour
+
+// webhookSecret 用於驗證 X-Hub-Signature-256，未設定時拒絕所有 webhook
+var webhookSecret string
+
+// triggerLabel 不為空時，pull_request 事件只在 PR 帶有此 label 時觸發
+var triggerLabel string
+
+// triggerCommand 為 PR 留言中觸發 CI 的指令
+var triggerCommand = "/ci run"
+
+// SetWebhookConfig 設定 webhook 的 secret、觸發 label 與留言指令
+func SetWebhookConfig(secret, label, command string) {
+	webhookSecret = secret
+	triggerLabel = label
+	if command != "" {
+		triggerCommand = command
+	}
+}
+
+// deliveries 記錄已處理的 X-GitHub-Delivery，避免 GitHub 重送時重複加入佇列
+var deliveries = struct {
+	sync.Mutex
+	seen map[string]time.Time
+}{seen: make(map[string]time.Time)}
+
+// trustedAssociations 為可以觸發 CI 的 author_association；CI 以 sudo 執行 PR 的程式碼，
+// 外部貢獻者的 PR 需要維護者加上觸發 label 或留言指令
+var trustedAssociations = map[string]bool{"OWNER": true, "MEMBER": true, "COLLABORATOR": true}
+
+// markDelivery 記錄 delivery ID，若已處理過則回傳 false
+func markDelivery(id string, now time.Time) bool {
+	if id == "" {
+		return true
+	}
+	deliveries.Lock()
+	defer deliveries.Unlock()
+	for k, t := range deliveries.seen {
+		if now.Sub(t) > deliveryTTL {
+			delete(deliveries.seen, k)
+		}
+	}
+	if _, ok := deliveries.seen[id]; ok {
+		return false
+	}
+	deliveries.seen[id] = now
+	return true
+}
+
+// forgetDelivery 移除 delivery ID；加入佇列失敗時 GitHub 會以相同 ID 重送，需要能再次處理
+func forgetDelivery(id string) {
+	deliveries.Lock()
+	defer deliveries.Unlock()
+	delete(deliveries.seen, id)
+}
+
+func WebhookRoute() []Route {
+	return []Route{
+		{
+			Name:        "github webhook",
+			Method:      http.MethodPost,
+			Pattern:     "/github",
+			HandlerFunc: GitHubWebhookHandler,
+			Role:        rolePublic,
+			Response:    webhookResponse{},
+		},
+	}
+}
+
+// webhookPayload 為 pull_request 與 issue_comment 事件中用到的欄位
+type webhookPayload struct {
+	Action      string `json:"action"`
+	PullRequest *struct {
+		Number int `json:"number"`
+		Head   struct {
+			SHA  string `json:"sha"`
+			Repo *struct {
+				FullName string `json:"full_name"`
+			} `json:"repo"` // fork 已刪除時為 null
+		} `json:"head"`
+		AuthorAssociation string `json:"author_association"`
+		Labels            []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+	} `json:"pull_request"`
+	Label *struct {
+		Name string `json:"name"`
+	} `json:"label"`
+	Issue *struct {
+		Number      int              `json:"number"`
+		PullRequest *json.RawMessage `json:"pull_request"` // 只有 PR 的留言才有此欄位
+	} `json:"issue"`
+	Comment *struct {
+		Body              string `json:"body"`
+		AuthorAssociation string `json:"author_association"`
+	} `json:"comment"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	Sender struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+}
+
+// GitHubWebhookHandler 接收 GitHub webhook。
+// 驗證 X-Hub-Signature-256 後，將設定中 NF repo 的 pull_request 事件
+// (opened、synchronize、reopened、labeled) 與含觸發指令的 PR 留言轉成任務加入佇列。
+func GitHubWebhookHandler(c *gin.Context) {
+	if webhookSecret == "" {
+		respondError(c, http.StatusServiceUnavailable, "webhook secret is not configured")
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookPayload))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "failed to read payload")
+		return
+	}
+	if !validSignature(webhookSecret, body, c.GetHeader("X-Hub-Signature-256")) {
+		logger.WebLog.Warnf("Rejected webhook with invalid signature from %s", c.ClientIP())
+		respondError(c, http.StatusUnauthorized, "invalid signature")
+		return
+	}
+
+	event := c.GetHeader("X-GitHub-Event")
+	if event == "ping" {
+		c.JSON(http.StatusOK, webhookResponse{Status: "pong"})
+		return
+	}
+	var payload webhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		respondError(c, http.StatusBadRequest, "invalid payload")
+		return
+	}
+
+	nf, pr, sha, reason := webhookTrigger(event, &payload)
+	if reason != "" {
+		c.JSON(http.StatusOK, webhookResponse{Status: "ignored", Reason: reason})
+		return
+	}
+	delivery := c.GetHeader("X-GitHub-Delivery")
+	if !markDelivery(delivery, time.Now()) {
+		c.JSON(http.StatusOK, webhookResponse{Status: "ignored", Reason: "duplicate delivery"})
+		return
+	}
+
+	ctx := context.Background()
+	actor := "github"
+	if payload.Sender.Login != "" {
+		actor += ":" + payload.Sender.Login
+	}
+	params := []models.TaskParams{{NF: nf, PRVersion: strconv.Itoa(pr), HeadSHA: sha}}
+	queued, err := findQueuedTask(ctx, params)
+	if err != nil {
+		logger.WebLog.Errorf("GitHubWebhookHandler: %v", err)
+		forgetDelivery(delivery)
+		respondError(c, http.StatusInternalServerError, "failed to read queue")
+		return
+	}
+	if queued != nil {
+		// 佇列中已有同一 PR：head 相同（或留言未帶 head）時忽略，有新的 push 時取代舊任務
+		if sha == "" || queued.Params[0].HeadSHA == sha {
+			c.JSON(http.StatusOK, webhookResponse{Status: "ignored", Reason: "already queued", TaskID: queued.ID})
+			return
+		}
+		if err := TaskQ.RemoveTask(ctx, queued.ID); err != nil {
+			logger.WebLog.Warnf("GitHubWebhookHandler: failed to remove superseded task %s: %v", queued.ID, err)
+		} else {
+			logger.WebLog.Infof("Task %s superseded by new head %s of %s:%d", queued.ID, sha, nf, pr)
+			recordAudit(ctx, actor, models.SourceWebhook, models.AuditDelete, "task:"+queued.ID, "superseded by "+shortSHA(sha))
+		}
+	}
+	task, err := enqueueTask(ctx, &models.Task{Params: params, SubmittedBy: actor, Source: models.SourceWebhook})
+	if err != nil {
+		logger.WebLog.Errorf("GitHubWebhookHandler: %v", err)
+		forgetDelivery(delivery)
+		respondError(c, enqueueErrorStatus(err), err.Error())
+		return
+	}
+	logger.WebLog.Infof("Webhook %s/%s enqueued task %s for %s:%d", event, payload.Action, task.ID, nf, pr)
+	c.JSON(http.StatusAccepted, webhookResponse{Status: "queued", TaskID: task.ID})
+}
+
+// webhookTrigger 判斷事件是否觸發 CI，回傳 NF、PR 號碼與 head commit（留言事件沒有 head）；
+// 不觸發時 reason 說明原因
+func webhookTrigger(event string, p *webhookPayload) (nf string, pr int, sha, reason string) {
+	repo, ok := lookupRepoByFullName(p.Repository.FullName)
+	if !ok {
+		return "", 0, "", "repository is not a configured NF repo"
+	}
+
+	switch event {
+	case "pull_request":
+		if p.PullRequest == nil {
+			return "", 0, "", "missing pull_request"
+		}
+		switch p.Action {
+		case "opened", "synchronize", "reopened":
+			if triggerLabel != "" && !hasLabel(p, triggerLabel) {
+				return "", 0, "", "pull request does not have the trigger label"
+			}
+			// fork 的 PR 只有可信任的作者會自動觸發；觸發 label 只代表維護者看過加 label 時的程式碼，
+			// 之後的 push 仍需維護者留言指令或重新加上 label
+			untrusted := isFork(p) && !trustedAssociations[p.PullRequest.AuthorAssociation]
+			if untrusted && (triggerLabel == "" || p.Action == "synchronize") {
+				return "", 0, "", "pull request from a fork by an untrusted author, a maintainer must comment " + triggerCommand
+			}
+		case "labeled":
+			// 只有加上觸發 label 時才觸發；未設定 label 時 labeled 不會觸發，避免重複執行
+			if triggerLabel == "" || p.Label == nil || p.Label.Name != triggerLabel {
+				return "", 0, "", "label is not the trigger label"
+			}
+		default:
+			return "", 0, "", "action " + p.Action + " does not trigger CI"
+		}
+		return repo.NF, p.PullRequest.Number, p.PullRequest.Head.SHA, ""
+	case "issue_comment":
+		if p.Action != "created" || p.Issue == nil || p.Issue.PullRequest == nil || p.Comment == nil {
+			return "", 0, "", "not a new pull request comment"
+		}
+		if !hasCommand(p.Comment.Body, triggerCommand) {
+			return "", 0, "", "comment does not contain " + triggerCommand
+		}
+		if !trustedAssociations[p.Comment.AuthorAssociation] {
+			return "", 0, "", "comment author is not an owner, member or collaborator"
+		}
+		return repo.NF, p.Issue.Number, "", ""
+	default:
+		return "", 0, "", "event " + event + " does not trigger CI"
+	}
+}
+
+// validSignature 驗證 X-Hub-Signature-256 (sha256=<hex HMAC>)
+func validSignature(secret string, body []byte, header string) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// lookupRepoByFullName 依 owner/repo 找出對應的 NF（不分大小寫）
+func lookupRepoByFullName(fullName string) (models.NFRepo, bool) {
+	for _, repo := range nfRepos {
+		if strings.EqualFold(models.PrCacheKey(repo.Owner, repo.Repo), fullName) {
+			return repo, true
+		}
+	}
+	return models.NFRepo{}, false
+}
+
+// isFork 判斷 PR 的 head 是否來自其他 repo；fork 已刪除時也視為 fork
+func isFork(p *webhookPayload) bool {
+	head := p.PullRequest.Head.Repo
+	return head == nil || !strings.EqualFold(head.FullName, p.Repository.FullName)
+}
+
+func hasLabel(p *webhookPayload, name string) bool {
+	for _, l := range p.PullRequest.Labels {
+		if l.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hasCommand 判斷留言是否有一行以指令開頭
+func hasCommand(body, command string) bool {
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if line == command || strings.HasPrefix(line, command+" ") {
+			return true
+		}
+	}
+	return false
+}
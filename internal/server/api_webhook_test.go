@@ -0,0 +1,246 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"web_test/internal/github"
+	"web_test/internal/github/githubtest"
+	"web_test/pkg/database"
+	"web_test/pkg/models"
+	"web_test/pkg/queue"
+)
+
+const testWebhookSecret = "s3cret"
+
+func newWebhookTestEngine(t *testing.T, label string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	oldDB, oldQ, oldRepos, oldClient := DB, TaskQ, nfRepos, ghClient
+	oldSecret, oldLabel, oldCommand := webhookSecret, triggerLabel, triggerCommand
+	t.Cleanup(func() {
+		DB, TaskQ, nfRepos, ghClient = oldDB, oldQ, oldRepos, oldClient
+		webhookSecret, triggerLabel, triggerCommand = oldSecret, oldLabel, oldCommand
+	})
+
+	// 留言與 labeled 事件沒有 head commit，加入佇列時向 GitHub 查詢
+	gh := githubtest.NewServer()
+	t.Cleanup(gh.Close)
+	gh.AddPull("free5gc", "go-upf", githubtest.Pull{Number: 7, HeadSHA: "c0ffee"})
+	gh.AddPull("free5gc", "go-upf", githubtest.Pull{Number: 42, HeadSHA: "abc"})
+	SetGitHubClient(github.NewClient(gh.URL, ""))
+
+	deliveries.Lock()
+	deliveries.seen = make(map[string]time.Time)
+	deliveries.Unlock()
+
+	DB = database.NewMemoryDB()
+	TaskQ = queue.NewListQueue()
+	SetNFRepos([]models.NFRepo{{NF: "upf", Owner: "free5gc", Repo: "go-upf"}})
+	SetWebhookConfig(testWebhookSecret, label, "/ci run")
+
+	engine := gin.New()
+	applyRoutes(engine.Group("/api/webhooks"), WebhookRoute())
+	return engine
+}
+
+func sendWebhook(engine *gin.Engine, event, delivery, body, secret string) *httptest.ResponseRecorder {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	req := httptest.NewRequest(http.MethodPost, "/api/webhooks/github", strings.NewReader(body))
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-GitHub-Delivery", delivery)
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func queuedTasks(t *testing.T) []*models.Task {
+	t.Helper()
+	tasks, err := TaskQ.GetTasks(context.Background())
+	if err != nil {
+		t.Fatalf("GetTasks: %v", err)
+	}
+	return tasks
+}
+
+const prOpened = `{"action":"opened","pull_request":{"number":42,"head":{"sha":"abc","repo":{"full_name":"free5gc/go-upf"}},"labels":[]},"repository":{"full_name":"free5gc/go-upf"},"sender":{"login":"octocat"}}`
+
+func TestWebhookSignature(t *testing.T) {
+	engine := newWebhookTestEngine(t, "")
+
+	if w := sendWebhook(engine, "pull_request", "d1", prOpened, "wrong"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("bad signature: %d, want 401", w.Code)
+	}
+	if n := len(queuedTasks(t)); n != 0 {
+		t.Fatalf("queued %d tasks for a forged webhook", n)
+	}
+}
+
+func TestWebhookEnqueuesAndDedups(t *testing.T) {
+	engine := newWebhookTestEngine(t, "")
+
+	w := sendWebhook(engine, "pull_request", "d1", prOpened, testWebhookSecret)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("opened: %d %s, want 202", w.Code, w.Body)
+	}
+	tasks := queuedTasks(t)
+	if len(tasks) != 1 || tasks[0].Params[0] != (models.TaskParams{NF: "upf", PRVersion: "42", HeadSHA: "abc"}) {
+		t.Fatalf("tasks = %+v, want upf:42@abc", tasks)
+	}
+
+	// 重送同一個 delivery
+	sendWebhook(engine, "pull_request", "d1", prOpened, testWebhookSecret)
+	// 不同 delivery，但同一 PR 已在佇列中
+	synced := strings.Replace(prOpened, `"opened"`, `"synchronize"`, 1)
+	w = sendWebhook(engine, "pull_request", "d2", synced, testWebhookSecret)
+	if !strings.Contains(w.Body.String(), "already queued") {
+		t.Fatalf("synchronize: %s, want already queued", w.Body)
+	}
+	if n := len(queuedTasks(t)); n != 1 {
+		t.Fatalf("queued %d tasks, want 1", n)
+	}
+
+	// 新的 push 取代佇列中的舊任務
+	pushed := strings.Replace(synced, `"abc"`, `"def"`, 1)
+	if w := sendWebhook(engine, "pull_request", "d3", pushed, testWebhookSecret); w.Code != http.StatusAccepted {
+		t.Fatalf("new head: %d %s, want 202", w.Code, w.Body)
+	}
+	tasks = queuedTasks(t)
+	if len(tasks) != 1 || tasks[0].Params[0].HeadSHA != "def" {
+		t.Fatalf("tasks = %+v, want only the task for head def", tasks)
+	}
+	if tasks[0].Source != models.SourceWebhook || tasks[0].SubmittedBy != "github:octocat" {
+		t.Errorf("task source = %q, submitter = %q", tasks[0].Source, tasks[0].SubmittedBy)
+	}
+	entries, _ := DB.GetAudit(context.Background(), 0, -1)
+	if len(entries) != 3 || entries[1].Action != models.AuditDelete || entries[1].Detail != "superseded by def" {
+		t.Errorf("audit = %+v, want enqueue, superseded delete, enqueue", entries)
+	}
+}
+
+func TestWebhookIgnoresUnknownRepoAndAction(t *testing.T) {
+	engine := newWebhookTestEngine(t, "")
+
+	other := strings.Replace(prOpened, "free5gc/go-upf", "someone/else", 1)
+	sendWebhook(engine, "pull_request", "d1", other, testWebhookSecret)
+	closed := strings.Replace(prOpened, `"opened"`, `"closed"`, 1)
+	sendWebhook(engine, "pull_request", "d2", closed, testWebhookSecret)
+	if n := len(queuedTasks(t)); n != 0 {
+		t.Fatalf("queued %d tasks, want 0", n)
+	}
+}
+
+func TestWebhookTriggerLabel(t *testing.T) {
+	engine := newWebhookTestEngine(t, "ci")
+
+	sendWebhook(engine, "pull_request", "d1", prOpened, testWebhookSecret)
+	if n := len(queuedTasks(t)); n != 0 {
+		t.Fatalf("unlabeled PR queued %d tasks", n)
+	}
+
+	labeled := `{"action":"labeled","label":{"name":"ci"},"pull_request":{"number":42,"labels":[{"name":"ci"}]},"repository":{"full_name":"free5gc/go-upf"}}`
+	if w := sendWebhook(engine, "pull_request", "d2", labeled, testWebhookSecret); w.Code != http.StatusAccepted {
+		t.Fatalf("labeled: %d %s, want 202", w.Code, w.Body)
+	}
+}
+
+func TestWebhookTriggerLabelForkPush(t *testing.T) {
+	engine := newWebhookTestEngine(t, "ci")
+
+	// 維護者加上 label 後，不可信任作者的新 push 不會自動觸發
+	push := `{"action":"synchronize","pull_request":{"number":42,"head":{"sha":"def","repo":{"full_name":"mallory/go-upf"}},"author_association":"CONTRIBUTOR","labels":[{"name":"ci"}]},"repository":{"full_name":"free5gc/go-upf"}}`
+	if w := sendWebhook(engine, "pull_request", "d1", push, testWebhookSecret); !strings.Contains(w.Body.String(), "untrusted author") {
+		t.Fatalf("push to a labeled fork PR: %d %s, want ignored", w.Code, w.Body)
+	}
+	if n := len(queuedTasks(t)); n != 0 {
+		t.Fatalf("push to a labeled fork PR queued %d tasks", n)
+	}
+
+	collaborator := strings.Replace(push, "CONTRIBUTOR", "COLLABORATOR", 1)
+	if w := sendWebhook(engine, "pull_request", "d2", collaborator, testWebhookSecret); w.Code != http.StatusAccepted {
+		t.Fatalf("push by a collaborator: %d %s, want 202", w.Code, w.Body)
+	}
+}
+
+func TestWebhookCommentCommand(t *testing.T) {
+	engine := newWebhookTestEngine(t, "ci")
+
+	comment := `{"action":"created","issue":{"number":7,"pull_request":{}},"comment":{"body":"LGTM\n/ci run","author_association":"MEMBER"},"repository":{"full_name":"free5gc/go-upf"}}`
+	if w := sendWebhook(engine, "issue_comment", "d1", comment, testWebhookSecret); w.Code != http.StatusAccepted {
+		t.Fatalf("comment: %d %s, want 202", w.Code, w.Body)
+	}
+	if tasks := queuedTasks(t); len(tasks) != 1 || tasks[0].Params[0].HeadSHA != "c0ffee" {
+		t.Fatalf("tasks = %+v, want head resolved at enqueue", tasks)
+	}
+	issue := `{"action":"created","issue":{"number":8},"comment":{"body":"/ci run"},"repository":{"full_name":"free5gc/go-upf"}}`
+	sendWebhook(engine, "issue_comment", "d2", issue, testWebhookSecret)
+	if n := len(queuedTasks(t)); n != 1 {
+		t.Fatalf("queued %d tasks, want only the PR comment", n)
+	}
+}
+
+func TestWebhookUntrustedAuthors(t *testing.T) {
+	engine := newWebhookTestEngine(t, "")
+
+	// fork 的 PR 只有可信任的作者會自動觸發
+	fork := `{"action":"opened","pull_request":{"number":42,"head":{"sha":"abc","repo":{"full_name":"mallory/go-upf"}},"author_association":"CONTRIBUTOR"},"repository":{"full_name":"free5gc/go-upf"}}`
+	if w := sendWebhook(engine, "pull_request", "d1", fork, testWebhookSecret); !strings.Contains(w.Body.String(), "untrusted author") {
+		t.Fatalf("fork PR: %d %s, want ignored", w.Code, w.Body)
+	}
+	deleted := strings.Replace(fork, `{"full_name":"mallory/go-upf"}`, "null", 1)
+	sendWebhook(engine, "pull_request", "d2", deleted, testWebhookSecret)
+	comment := `{"action":"created","issue":{"number":42,"pull_request":{}},"comment":{"body":"/ci run","author_association":"NONE"},"repository":{"full_name":"free5gc/go-upf"}}`
+	sendWebhook(engine, "issue_comment", "d3", comment, testWebhookSecret)
+	if n := len(queuedTasks(t)); n != 0 {
+		t.Fatalf("untrusted events queued %d tasks", n)
+	}
+
+	member := strings.Replace(fork, "CONTRIBUTOR", "MEMBER", 1)
+	if w := sendWebhook(engine, "pull_request", "d4", member, testWebhookSecret); w.Code != http.StatusAccepted {
+		t.Fatalf("fork PR by a member: %d %s, want 202", w.Code, w.Body)
+	}
+}
+
+// failingQueue 在 fail 為 true 時拒絕加入任務
+type failingQueue struct {
+	queue.TaskQueue
+	fail bool
+}
+
+func (q *failingQueue) PushTask(ctx context.Context, task *models.Task) error {
+	if q.fail {
+		return errors.New("connection refused")
+	}
+	return q.TaskQueue.PushTask(ctx, task)
+}
+
+func TestWebhookRedeliveryAfterFailure(t *testing.T) {
+	engine := newWebhookTestEngine(t, "")
+	q := &failingQueue{TaskQueue: TaskQ, fail: true}
+	TaskQ = q
+
+	if w := sendWebhook(engine, "pull_request", "d1", prOpened, testWebhookSecret); w.Code < 500 {
+		t.Fatalf("enqueue failure: %d %s, want 5xx", w.Code, w.Body)
+	}
+	// GitHub 以相同的 delivery ID 重送
+	q.fail = false
+	if w := sendWebhook(engine, "pull_request", "d1", prOpened, testWebhookSecret); w.Code != http.StatusAccepted {
+		t.Fatalf("redelivery: %d %s, want 202", w.Code, w.Body)
+	}
+	if w := sendWebhook(engine, "pull_request", "d1", prOpened, testWebhookSecret); !strings.Contains(w.Body.String(), "duplicate delivery") {
+		t.Fatalf("second redelivery: %s, want duplicate delivery", w.Body)
+	}
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"os"
+
+	"web_test/internal/logger"
+)
+
+//go:embed public
+var embeddedAssets embed.FS
+
+// staticDir 不為空時改由此目錄提供前端檔案，方便開發時修改後不必重新編譯
+var staticDir string
+
+// SetStaticDir 設定前端檔案目錄，空值使用編譯時嵌入的 internal/server/public
+func SetStaticDir(dir string) {
+	staticDir = dir
+}
+
+// assetsFS 回傳前端檔案的根目錄（含 index.html、preview.html 與 js/）
+func assetsFS() fs.FS {
+	if staticDir != "" {
+		if info, err := os.Stat(staticDir); err == nil && info.IsDir() {
+			return os.DirFS(staticDir)
+		}
+		logger.WebLog.Warnf("webserver.static_dir %q is not a directory, using the embedded frontend", staticDir)
+	}
+	sub, err := fs.Sub(embeddedAssets, "public")
+	if err != nil {
+		panic(err) // public 一定存在於嵌入的檔案中
+	}
+	return sub
+}
+
+// filesOnly 讓 http.FileServer 不列出目錄內容，與 gin.Static 的行為相同
+type filesOnly struct {
+	http.FileSystem
+}
+
+func (f filesOnly) Open(name string) (http.File, error) {
+	file, err := f.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return noReaddir{file}, nil
+}
+
+type noReaddir struct {
+	http.File
+}
+
+func (noReaddir) Readdir(int) ([]os.FileInfo, error) {
+	return nil, nil
+}
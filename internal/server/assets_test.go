@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedAssets(t *testing.T) {
+	engine := newV1TestEngine(t)
+	for path, want := range map[string]string{
+		"/":                    "/js/app.js",
+		"/some/spa/route":      "/js/app.js",
+		"/js/auth.js":          "fetch",
+		"/static/preview.html": "/js/preview.js",
+	} {
+		w := doRequest(engine, http.MethodGet, path, "")
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), want) {
+			t.Errorf("GET %s = %d, want a body containing %q", path, w.Code, want)
+		}
+	}
+	for _, dir := range []string{"/static/", "/static/js/", "/js/"} {
+		if w := doRequest(engine, http.MethodGet, dir, ""); strings.Contains(w.Body.String(), `href="app.js"`) ||
+			strings.Contains(w.Body.String(), `href="js/"`) {
+			t.Errorf("directory listing of %s is exposed: %s", dir, w.Body)
+		}
+	}
+}
+
+func TestStaticDirOverride(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("dev build"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetStaticDir("") })
+	SetStaticDir(dir)
+	engine := newV1TestEngine(t)
+	if w := doRequest(engine, http.MethodGet, "/", ""); w.Body.String() != "dev build" {
+		t.Errorf("index from static_dir = %d %q", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"web_test/internal/logger"
+	"web_test/pkg/models"
+)
+
+// sourceHeader 由命令列工具等客戶端宣告請求來源，未帶時視為網頁
+const sourceHeader = "X-Web-Test-Source"
+
+const maxAuditLimit = 1000
+
+func AuditRoute() []Route {
+	return []Route{
+		{
+			Name:        "get audit log",
+			Method:      http.MethodGet,
+			Pattern:     "",
+			HandlerFunc: GetAuditHandler,
+			Role:        models.RoleAdmin,
+			Response:    []models.AuditEntry{},
+			Query:       map[string]string{"offset": "略過的筆數", "limit": "回傳的筆數，預設 100，最多 1000", "tz": "顯示時間使用的 IANA 時區"},
+		},
+	}
+}
+
+// requestActor 回傳請求的身分：token 名稱，未驗證時為 anonymous@<IP>
+func requestActor(c *gin.Context) string {
+	if subject := c.GetString(ctxSubjectKey); subject != "" {
+		return subject
+	}
+	return "anonymous@" + c.ClientIP()
+}
+
+// requestSource 回傳請求宣告的來源，不合法或未帶時為 ui
+func requestSource(c *gin.Context) string {
+	if source := c.GetHeader(sourceHeader); models.ValidSource(source) {
+		return source
+	}
+	return models.SourceUI
+}
+
+// recordAudit 新增一筆稽核紀錄；寫入失敗只記錄 log，不影響原本的操作
+func recordAudit(ctx context.Context, actor, source, action, target, detail string) {
+	entry := &models.AuditEntry{
+		At:     time.Now().Unix(),
+		Actor:  actor,
+		Source: source,
+		Action: action,
+		Target: target,
+		Detail: detail,
+	}
+	logger.WebLog.Infof("Audit: %s (%s) %s %s %s", actor, source, action, target, detail)
+	if err := DB.AppendAudit(ctx, entry); err != nil {
+		logger.WebLog.Errorf("Failed to append audit entry %+v: %v", entry, err)
+	}
+}
+
+// auditRequest 以請求的身分與來源新增稽核紀錄
+func auditRequest(c *gin.Context, action, target, detail string) {
+	recordAudit(c.Request.Context(), requestActor(c), requestSource(c), action, target, detail)
+}
+
+// describeParams 將任務參數轉成 amf#12@abc1234 形式，用於稽核紀錄
+func describeParams(params []models.TaskParams) string {
+	s := ""
+	for i, p := range params {
+		if i > 0 {
+			s += " "
+		}
+		s += p.NF + "#" + p.PRVersion
+		if p.HeadSHA != "" {
+			s += "@" + shortSHA(p.HeadSHA)
+		}
+	}
+	return s
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// GetAuditHandler 回傳稽核紀錄，最新在前；?offset= 與 ?limit=（預設 100）分頁，?tz= 指定顯示時區
+func GetAuditHandler(c *gin.Context) {
+	loc, err := requestLocation(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid timezone: %v", err))
+		return
+	}
+	offset, err := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+	if err != nil || offset < 0 {
+		respondError(c, http.StatusBadRequest, "invalid offset")
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "100"), 10, 64)
+	if err != nil || limit <= 0 || limit > maxAuditLimit {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxAuditLimit))
+		return
+	}
+
+	entries, err := DB.GetAudit(c.Request.Context(), offset, offset+limit-1)
+	if err != nil {
+		logger.WebLog.Errorf("GetAuditHandler: %v", err)
+		respondError(c, http.StatusInternalServerError, "failed to retrieve audit log")
+		return
+	}
+	for _, entry := range entries {
+		entry.Time = formatUnix(entry.At, loc)
+	}
+	c.JSON(http.StatusOK, entries)
+}
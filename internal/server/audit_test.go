@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"web_test/internal/github/githubtest"
+	"web_test/pkg/models"
+	"web_test/pkg/queue"
+)
+
+func TestAuditLog(t *testing.T) {
+	gh := githubtest.NewServer()
+	defer gh.Close()
+	gh.AddPull("free5gc", "go-upf", githubtest.Pull{Number: 7, HeadSHA: "c0ffee1234"})
+
+	engine := newPRTestEngine(t, gh, []models.NFRepo{{NF: "upf", Owner: "free5gc", Repo: "go-upf"}})
+	oldQ := TaskQ
+	t.Cleanup(func() { TaskQ = oldQ })
+	TaskQ = queue.NewListQueue()
+	applyRoutes(engine.Group("/api/queue"), QueueRoute())
+	applyRoutes(engine.Group("/api/audit"), AuditRoute())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/queue/run-pr", strings.NewReader(`{"params":[["upf","7"]]}`))
+	req.Header.Set(sourceHeader, models.SourceCLI)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("run-pr: %d %s", w.Code, w.Body)
+	}
+	tasks, _ := TaskQ.GetTasks(context.Background())
+	task := tasks[0]
+	if task.SubmittedBy != "anonymous@192.0.2.1" || task.Source != models.SourceCLI {
+		t.Fatalf("task submitter = %q, source = %q", task.SubmittedBy, task.Source)
+	}
+
+	if w := doRequest(engine, http.MethodDelete, "/api/queue/delete/"+task.ID, ""); w.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", w.Code, w.Body)
+	}
+	if w := doRequest(engine, http.MethodPost, "/api/prs/clear?repo=free5gc/go-upf", ""); w.Code != http.StatusOK {
+		t.Fatalf("clear: %d %s", w.Code, w.Body)
+	}
+
+	w = doRequest(engine, http.MethodGet, "/api/audit", "")
+	var entries []models.AuditEntry
+	if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("decode audit: %v (%s)", err, w.Body)
+	}
+	want := []struct{ action, target, source, detail string }{
+		{models.AuditConfig, "pr_cache:free5gc/go-upf", models.SourceUI, "cleared"},
+		{models.AuditDelete, "task:" + task.ID, models.SourceUI, "upf#7@c0ffee1"},
+		{models.AuditEnqueue, "task:" + task.ID, models.SourceCLI, "upf#7@c0ffee1"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("audit entries = %+v, want %d", entries, len(want))
+	}
+	for i, e := range entries {
+		if e.Action != want[i].action || e.Target != want[i].target || e.Source != want[i].source ||
+			e.Detail != want[i].detail || e.Actor != "anonymous@192.0.2.1" || e.At == 0 {
+			t.Errorf("entry %d = %+v, want %+v", i, e, want[i])
+		}
+	}
+
+	if w := doRequest(engine, http.MethodGet, "/api/audit?limit=1&offset=2", ""); !strings.Contains(w.Body.String(), `"enqueue"`) ||
+		strings.Count(w.Body.String(), `"action"`) != 1 {
+		t.Errorf("paged audit = %s, want only the enqueue entry", w.Body)
+	}
+	if w := doRequest(engine, http.MethodGet, "/api/audit?limit=0", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("limit=0: %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"web_test/internal/logger"
+	"web_test/pkg/models"
+)
+
+// rolePublic 標記不需要 token 的路由（例如以 HMAC 驗證的 GitHub webhook）
+const rolePublic = "public"
+
+const (
+	tokenCookie   = "web_test_token" // 前端以 cookie 傳送 token，下載連結也能通過驗證
+	tokenPrefix   = "wt_"
+	ctxRoleKey    = "auth_role"
+	ctxSubjectKey = "auth_subject"
+)
+
+var (
+	authEnabled bool
+	adminToken  string // 設定檔中的管理員 token，用於建立第一組 token
+)
+
+// SetAuthConfig 設定是否啟用 API token 驗證；token 不為空時可直接作為 admin 使用
+func SetAuthConfig(enabled bool, token string) {
+	authEnabled = enabled
+	adminToken = token
+}
+
+// hashToken 回傳 token 的 SHA-256 十六進位字串
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// tokenID 由 token 的雜湊取出 ID，查詢時不需要掃描所有 token
+func tokenID(hash string) string {
+	return hash[:16]
+}
+
+func requestToken(c *gin.Context) string {
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	if cookie, err := c.Cookie(tokenCookie); err == nil {
+		return cookie
+	}
+	return ""
+}
+
+// TokenAuth 驗證請求帶的 token，並把角色存入 context 供路由檢查；
+// 沒有帶 token 時不擋下，由 requireRole 決定是否允許匿名存取
+func TokenAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !authEnabled || !strings.HasPrefix(c.Request.URL.Path, "/api") && c.Request.URL.Path != metricsPath {
+			c.Next()
+			return
+		}
+		token := requestToken(c)
+		if token == "" {
+			c.Next()
+			return
+		}
+		if adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1 {
+			c.Set(ctxRoleKey, models.RoleAdmin)
+			c.Set(ctxSubjectKey, "admin")
+			c.Next()
+			return
+		}
+
+		hash := hashToken(token)
+		stored, err := DB.GetToken(c.Request.Context(), tokenID(hash))
+		if err != nil {
+			logger.WebLog.Errorf("TokenAuth: %v", err)
+			respondError(c, http.StatusInternalServerError, "failed to verify token")
+			return
+		}
+		if stored == nil || subtle.ConstantTimeCompare([]byte(stored.Hash), []byte(hash)) != 1 {
+			c.Header("WWW-Authenticate", "Bearer")
+			respondError(c, http.StatusUnauthorized, "invalid token")
+			return
+		}
+		c.Set(ctxRoleKey, stored.Role)
+		c.Set(ctxSubjectKey, stored.Name)
+		c.Next()
+	}
+}
+
+// requireRole 檢查請求的角色是否具有 role 以上的權限；role 為空時視為 viewer
+func requireRole(role string) gin.HandlerFunc {
+	if role == "" {
+		role = models.RoleViewer
+	}
+	return func(c *gin.Context) {
+		if !authEnabled || role == rolePublic {
+			c.Next()
+			return
+		}
+		have := c.GetString(ctxRoleKey)
+		if have == "" {
+			c.Header("WWW-Authenticate", "Bearer")
+			respondError(c, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		if !models.RoleAllows(have, role) {
+			respondError(c, http.StatusForbidden, role+" role required")
+			return
+		}
+		c.Next()
+	}
+}
+
+// hasRole 回傳請求的角色是否具有 role 以上的權限，供同一路由內依角色決定行為；未啟用驗證時一律允許
+func hasRole(c *gin.Context, role string) bool {
+	return !authEnabled || models.RoleAllows(c.GetString(ctxRoleKey), role)
+}
+
+type createTokenRequest struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// createTokenResponse 只在建立時回傳 token 明文
+type createTokenResponse struct {
+	*models.APIToken
+	Token string `json:"token"`
+}
+
+// ListTokensHandler 列出所有 API token（不含雜湊）
+func ListTokensHandler(c *gin.Context) {
+	tokens, err := DB.ListTokens(c.Request.Context())
+	if err != nil {
+		logger.WebLog.Errorf("ListTokensHandler: %v", err)
+		respondError(c, http.StatusInternalServerError, "failed to list tokens")
+		return
+	}
+	for _, token := range tokens {
+		token.Hash = ""
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// CreateTokenHandler 建立新的 API token
+func CreateTokenHandler(c *gin.Context) {
+	var req createTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		respondError(c, http.StatusBadRequest, "name is required")
+		return
+	}
+	if !models.ValidRole(req.Role) {
+		respondError(c, http.StatusBadRequest, "role must be viewer, submitter or admin")
+		return
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		respondError(c, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
+	plain := tokenPrefix + hex.EncodeToString(buf)
+	hash := hashToken(plain)
+	token := &models.APIToken{
+		ID:        tokenID(hash),
+		Name:      strings.TrimSpace(req.Name),
+		Role:      req.Role,
+		Hash:      hash,
+		CreatedAt: time.Now().Unix(),
+	}
+	if err := DB.SaveToken(c.Request.Context(), token); err != nil {
+		logger.WebLog.Errorf("CreateTokenHandler: %v", err)
+		respondError(c, http.StatusInternalServerError, "failed to save token")
+		return
+	}
+	auditRequest(c, models.AuditConfig, "api_token:"+token.ID, fmt.Sprintf("created %s (%s)", token.Name, token.Role))
+
+	token.Hash = ""
+	c.JSON(http.StatusCreated, createTokenResponse{APIToken: token, Token: plain})
+}
+
+// DeleteTokenHandler 撤銷 API token
+func DeleteTokenHandler(c *gin.Context) {
+	id := c.Param("id")
+	token, err := DB.GetToken(c.Request.Context(), id)
+	if err != nil {
+		logger.WebLog.Errorf("DeleteTokenHandler: %v", err)
+		respondError(c, http.StatusInternalServerError, "failed to load token")
+		return
+	}
+	if token == nil {
+		respondError(c, http.StatusNotFound, "token not found")
+		return
+	}
+	if err := DB.DeleteToken(c.Request.Context(), id); err != nil {
+		logger.WebLog.Errorf("DeleteTokenHandler: %v", err)
+		respondError(c, http.StatusInternalServerError, "failed to delete token")
+		return
+	}
+	auditRequest(c, models.AuditConfig, "api_token:"+token.ID, "revoked "+token.Name)
+	c.JSON(http.StatusOK, statusResponse{Status: "revoked"})
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"web_test/pkg/database"
+	"web_test/pkg/queue"
+)
+
+func TestTokenAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	oldDB, oldQ, oldEnabled, oldAdmin := DB, TaskQ, authEnabled, adminToken
+	t.Cleanup(func() {
+		DB, TaskQ = oldDB, oldQ
+		SetAuthConfig(oldEnabled, oldAdmin)
+	})
+	TaskQ = queue.NewListQueue()
+	SetAuthConfig(true, "bootstrap")
+	engine := gin.New()
+	AddService(engine, database.NewMemoryDB())
+
+	call := func(method, path, token, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+	create := func(role string) createTokenResponse {
+		t.Helper()
+		w := call(http.MethodPost, "/api/admin/tokens", "bootstrap", `{"name":"`+role+`-bot","role":"`+role+`"}`)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create %s token: %d %s", role, w.Code, w.Body)
+		}
+		var resp createTokenResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Hash != "" || !strings.HasPrefix(resp.Token, tokenPrefix) {
+			t.Fatalf("create response = %+v, want plaintext token without hash", resp)
+		}
+		return resp
+	}
+	viewer, submitter := create("viewer"), create("submitter")
+
+	tests := []struct {
+		name, method, path, token string
+		want                      int
+	}{
+		{"anonymous", http.MethodGet, "/api/queue/list", "", http.StatusUnauthorized},
+		{"unknown token", http.MethodGet, "/api/queue/list", "wt_nope", http.StatusUnauthorized},
+		{"viewer reads", http.MethodGet, "/api/queue/list", viewer.Token, http.StatusOK},
+		{"viewer cannot delete", http.MethodDelete, "/api/queue/delete/1", viewer.Token, http.StatusForbidden},
+		{"submitter deletes", http.MethodDelete, "/api/queue/delete/1", submitter.Token, http.StatusNotFound},
+		{"submitter cannot clear cache", http.MethodPost, "/api/prs/clear", submitter.Token, http.StatusForbidden},
+		{"submitter cannot manage tokens", http.MethodGet, "/api/admin/tokens", submitter.Token, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if w := call(tt.method, tt.path, tt.token, ""); w.Code != tt.want {
+			t.Errorf("%s: %s %s = %d %s, want %d", tt.name, tt.method, tt.path, w.Code, w.Body, tt.want)
+		}
+	}
+
+	// webhook 以 secret 驗證，不需要 token
+	if w := call(http.MethodPost, "/api/webhooks/github", "", "{}"); strings.Contains(w.Body.String(), "authentication required") {
+		t.Errorf("webhook requires a token: %d %s", w.Code, w.Body)
+	}
+
+	w := call(http.MethodGet, "/api/admin/tokens", "bootstrap", "")
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), `"hash"`) {
+		t.Fatalf("list tokens = %d %s, want no hashes", w.Code, w.Body)
+	}
+	if w := call(http.MethodDelete, "/api/admin/tokens/"+viewer.ID, "bootstrap", ""); w.Code != http.StatusOK {
+		t.Fatalf("revoke: %d %s", w.Code, w.Body)
+	}
+	if w := call(http.MethodGet, "/api/queue/list", viewer.Token, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("revoked token: %d, want 401", w.Code)
+	}
+
+	// 前端以 cookie 傳送 token
+	req := httptest.NewRequest(http.MethodGet, "/api/history/", nil)
+	req.AddCookie(&http.Cookie{Name: tokenCookie, Value: submitter.Token})
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("cookie token: %d %s", rec.Code, rec.Body)
+	}
+}
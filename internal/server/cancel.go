@@ -0,0 +1,70 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	go_redis "github.com/redis/go-redis/v9"
+
+	"web_test/internal/logger"
+	"web_test/pkg/models"
+)
+
+// 取消任務的結果
+const (
+	cancelRemoved   = "removed"    // 任務還在佇列中，已直接移除
+	cancelRequested = "cancelling" // 任務執行中，executor 會在下次檢查時中止 run_task.sh
+)
+
+// CancelTaskHandler 取消任務：佇列中的任務直接移除，執行中的任務記錄取消要求交給 executor 中止；
+// 已結束的任務回傳 409
+func CancelTaskHandler(c *gin.Context) {
+	if TaskQ == nil {
+		respondError(c, http.StatusInternalServerError, "task queue is not initialized")
+		return
+	}
+	ctx := c.Request.Context()
+	taskID := c.Param("taskID")
+
+	tasks, err := TaskQ.GetTasks(ctx)
+	if err != nil {
+		logger.WebLog.Errorf("CancelTaskHandler: Failed to get tasks from queue: %v", err)
+		respondError(c, http.StatusInternalServerError, "failed to get tasks from queue")
+		return
+	}
+	for _, task := range tasks {
+		if task.ID != taskID {
+			continue
+		}
+		// executor 可能剛好取出任務，移除失敗時改依執行中的任務處理
+		if err := TaskQ.RemoveTask(ctx, taskID); err == nil {
+			auditRequest(c, models.AuditCancel, "task:"+taskID, describeParams(task.Params))
+			c.JSON(http.StatusOK, statusResponse{Status: cancelRemoved})
+			return
+		}
+	}
+
+	result, err := DB.GetResult(ctx, taskID)
+	if err != nil && !errors.Is(err, go_redis.Nil) {
+		logger.WebLog.Errorf("CancelTaskHandler: Failed to get result of task %s: %v", taskID, err)
+		respondError(c, http.StatusInternalServerError, "failed to retrieve task result")
+		return
+	}
+	if result == nil {
+		respondError(c, http.StatusNotFound, fmt.Sprintf("Task ID %s not found", taskID))
+		return
+	}
+	if result.Status != models.StatusRunning {
+		respondError(c, http.StatusConflict, fmt.Sprintf("task %s already finished with status %s", taskID, result.Status))
+		return
+	}
+	if err := DB.RequestCancel(ctx, taskID, requestActor(c)); err != nil {
+		logger.WebLog.Errorf("CancelTaskHandler: Failed to request cancel of task %s: %v", taskID, err)
+		respondError(c, http.StatusInternalServerError, "failed to request cancel")
+		return
+	}
+	auditRequest(c, models.AuditCancel, "task:"+taskID, describeParams(result.Params))
+	c.JSON(http.StatusAccepted, statusResponse{Status: cancelRequested})
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"web_test/pkg/models"
+)
+
+func TestCancelTask(t *testing.T) {
+	engine := newV1TestEngine(t)
+	ctx := context.Background()
+
+	if err := TaskQ.PushTask(ctx, &models.Task{ID: "1", Params: []models.TaskParams{{NF: "amf", PRVersion: "12"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if w := doRequest(engine, http.MethodPost, "/api/v1/queue/cancel/1", ""); w.Code != http.StatusOK {
+		t.Fatalf("cancel queued task: %d %s", w.Code, w.Body)
+	}
+	if tasks, _ := TaskQ.GetTasks(ctx); len(tasks) != 0 {
+		t.Fatalf("queue after cancel = %v, want empty", tasks)
+	}
+	if w := doRequest(engine, http.MethodPost, "/api/v1/queue/cancel/1", ""); w.Code != http.StatusNotFound {
+		t.Errorf("cancel removed task: %d %s", w.Code, w.Body)
+	}
+
+	if err := DB.SaveResult(ctx, &models.TaskResult{TaskID: "2", Status: models.StatusRunning}); err != nil {
+		t.Fatal(err)
+	}
+	if w := doRequest(engine, http.MethodPost, "/api/v1/queue/cancel/2", ""); w.Code != http.StatusAccepted {
+		t.Fatalf("cancel running task: %d %s", w.Code, w.Body)
+	}
+	if actor, err := DB.GetCancelRequest(ctx, "2"); err != nil || actor == "" {
+		t.Fatalf("cancel request of running task = %q, %v", actor, err)
+	}
+
+	if err := DB.SaveResult(ctx, &models.TaskResult{TaskID: "2", Status: models.StatusFailed}); err != nil {
+		t.Fatal(err)
+	}
+	if w := doRequest(engine, http.MethodPost, "/api/v1/queue/cancel/2", ""); w.Code != http.StatusConflict {
+		t.Errorf("cancel finished task: %d %s", w.Code, w.Body)
+	}
+
+	audit, err := DB.GetAudit(ctx, 0, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancels := 0
+	for _, entry := range audit {
+		if entry.Action == models.AuditCancel {
+			cancels++
+		}
+	}
+	if cancels != 2 {
+		t.Errorf("audit has %d cancel entries, want 2", cancels)
+	}
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"web_test/internal/events"
+	"web_test/pkg/models"
+)
+
+// eventBus 為佇列與任務狀態的事件來源，nil 表示停用 /api/events
+var eventBus *events.Bus
+
+// SetEventBus 設定 /api/events 串流的事件來源
+func SetEventBus(bus *events.Bus) {
+	eventBus = bus
+}
+
+// eventsHeartbeat 為沒有事件時送出註解行的間隔，避免 proxy 關閉閒置連線
+const eventsHeartbeat = 15 * time.Second
+
+// streamsClosed 在伺服器關閉時關閉，讓長連線的串流結束
+var streamsClosed = struct {
+	sync.Once
+	ch chan struct{}
+}{ch: make(chan struct{})}
+
+func closeEventStreams() {
+	streamsClosed.Do(func() { close(streamsClosed.ch) })
+}
+
+func EventsRoute() []Route {
+	return []Route{
+		{
+			Name:        "stream queue and task events",
+			Method:      http.MethodGet,
+			Pattern:     "",
+			HandlerFunc: StreamEventsHandler,
+			Role:        models.RoleViewer,
+			Produces:    "text/event-stream",
+			Query:       map[string]string{"last_event_id": "從此 ID 之後補送事件，與 Last-Event-ID header 相同"},
+		},
+	}
+}
+
+// StreamEventsHandler 以 Server-Sent Events 推送佇列與任務狀態的變化。
+// 每個事件的 event 欄位為事件類型，data 為 events.Event 的 JSON；重連時依 Last-Event-ID 補送遺漏的事件
+func StreamEventsHandler(c *gin.Context) {
+	if eventBus == nil {
+		respondError(c, http.StatusServiceUnavailable, "event stream is not enabled")
+		return
+	}
+	lastID := c.GetHeader("Last-Event-ID")
+	if lastID == "" {
+		lastID = c.Query("last_event_id")
+	}
+	var after uint64
+	if lastID != "" {
+		n, err := strconv.ParseUint(lastID, 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid last event ID")
+			return
+		}
+		after = n
+	}
+
+	missed, ch, cancel := eventBus.Subscribe(after)
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 關閉 nginx 的緩衝
+	c.Status(http.StatusOK)
+	fmt.Fprint(c.Writer, "retry: 3000\n\n")
+	for _, e := range missed {
+		writeEvent(c, e)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-streamsClosed.ch:
+			return
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			writeEvent(c, e)
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
+
+func writeEvent(c *gin.Context, e events.Event) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+}
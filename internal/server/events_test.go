@@ -0,0 +1,95 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"web_test/internal/events"
+	"web_test/pkg/models"
+)
+
+// readEvent 讀取下一個 SSE 事件，回傳 id 與 event 欄位
+func readEvent(t *testing.T, r *bufio.Reader) (id, typ string) {
+	t.Helper()
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			typ = strings.TrimPrefix(line, "event: ")
+		case line == "" && typ != "":
+			return id, typ
+		}
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	engine := newV1TestEngine(t)
+	bus := events.NewBus()
+	oldBus := eventBus
+	eventBus = bus
+	t.Cleanup(func() { eventBus = oldBus })
+	TaskQ = events.NewQueue(TaskQ, bus)
+
+	// 連線前的事件只在帶 Last-Event-ID 時補送
+	bus.Publish(events.Event{Type: events.TaskStatus, TaskID: "old"})
+
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); resp.StatusCode != http.StatusOK || ct != "text/event-stream" {
+		t.Fatalf("stream: %d %s", resp.StatusCode, ct)
+	}
+
+	body := bufio.NewReader(resp.Body)
+	waitFor(t, func() bool { return bus.Subscribers() == 1 })
+	if err := TaskQ.PushTask(ctx, &models.Task{ID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if id, typ := readEvent(t, body); id != "2" || typ != events.TaskQueued {
+		t.Errorf("event = %s %s, want 2 task.queued", id, typ)
+	}
+
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/events", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resume, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resume.Body.Close()
+	if id, typ := readEvent(t, bufio.NewReader(resume.Body)); id != "2" || typ != events.TaskQueued {
+		t.Errorf("resumed event = %s %s, want the missed event 2", id, typ)
+	}
+
+	if w := doRequest(engine, http.MethodGet, "/api/events?last_event_id=x", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid last_event_id: %d", w.Code)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -1,51 +1,111 @@
 package server
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
-	"time"
+	"sync"
 
+	"web_test/internal/github"
 	"web_test/internal/logger"
 	"web_test/pkg/models"
 )
 
-func FetchGitHubInfo(owner, repo string) (models.WorkerResponse, error) {
+// ghClient 為呼叫 GitHub API 使用的 client，預設為匿名存取
+var ghClient = github.NewClient("", "")
+
+// SetGitHubClient 設定呼叫 GitHub API 使用的 client
+func SetGitHubClient(client *github.Client) {
+	ghClient = client
+}
+
+// FetchGitHubInfo 取得 owner/repo 所有開啟中的 PR 與最新 release（取得失敗時為 nil）。
+// etag 不為空時以 If-None-Match 發出條件式請求，若內容未變更則回傳 NotModified。
+func FetchGitHubInfo(ctx context.Context, owner, repo, etag string) (models.WorkerResponse, error) {
 	logger.GitHubLog.Infof("Fetching %s/%s", owner, repo)
-	client := &http.Client{Timeout: 5 * time.Second}
 
-	var prs []models.PullRequest
-	prURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/pulls?state=open&per_page=20", owner, repo)
-	req, _ := http.NewRequest("GET", prURL, nil)
-	req.Header.Set("User-Agent", "Go-Worker")
-	res, err := client.Do(req)
+	pulls, err := ghClient.ListOpenPulls(ctx, owner, repo, etag)
 	if err != nil {
 		return models.WorkerResponse{}, err
 	}
-	defer res.Body.Close()
-	json.NewDecoder(res.Body).Decode(&prs)
+	if pulls.NotModified {
+		logger.GitHubLog.Infof("%s/%s not modified", owner, repo)
+		return models.WorkerResponse{ETag: etag, NotModified: true}, nil
+	}
 
-	relURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases?per_page=1", owner, repo)
-	reqRel, _ := http.NewRequest("GET", relURL, nil)
-	reqRel.Header.Set("User-Agent", "Go-Worker")
-	resRel, err := client.Do(reqRel)
+	// release 只用於摘要，失敗時不影響 PR 列表
 	var verStr string
-	if err == nil {
-		var rels []models.Release
-		defer resRel.Body.Close()
-		json.NewDecoder(resRel.Body).Decode(&rels)
-		if len(rels) > 0 {
-			name := rels[0].Name
-			if name == "" {
-				name = rels[0].TagName
-			}
-			verStr = " | Ver: " + name
+	rel, err := ghClient.LatestRelease(ctx, owner, repo)
+	if err != nil {
+		logger.GitHubLog.Warnf("Failed to fetch latest release of %s/%s: %v", owner, repo, err)
+	} else if rel != nil {
+		name := rel.Name
+		if name == "" {
+			name = rel.TagName
 		}
+		verStr = " | Ver: " + name
 	}
 
 	resp := models.WorkerResponse{
-		Summary: fmt.Sprintf("[%s/%s] PRs: %d%s", owner, repo, len(prs), verStr),
-		PRs:     prs,
+		Summary:       fmt.Sprintf("[%s/%s] PRs: %d%s", owner, repo, len(pulls.PRs), verStr),
+		PRs:           pulls.PRs,
+		LatestRelease: rel,
+		ETag:          pulls.ETag,
 	}
 	return resp, nil
 }
+
+// fillPullDetails 補上 PR 的 mergeable 狀態。
+// head commit 與快取相同的 PR 沿用快取的 mergeable 狀態與變更檔案，只有 head 改變或
+// GitHub 尚未算出 mergeable 狀態（空字串或 unknown）的 PR 以最多 maxConcurrentFetches 個並行請求重新查詢。
+// 變更檔案在挑選測試需要時才向 GitHub 取得（見 prChangedFiles）。
+// 查詢失敗只記錄警告，不影響 PR 列表的更新。
+func fillPullDetails(ctx context.Context, owner, repo string, prs []models.PullRequest, cached *models.PrCacheEntry) {
+	previous := make(map[int]models.PullRequest)
+	if cached != nil {
+		for _, pr := range cached.PRs {
+			previous[pr.Number] = pr
+		}
+	}
+
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, maxConcurrentFetches)
+	)
+	for i := range prs {
+		pr := &prs[i]
+		if old, ok := previous[pr.Number]; ok && old.HeadSHA == pr.HeadSHA {
+			pr.MergeableState = old.MergeableState
+			pr.ChangedFiles = old.ChangedFiles
+		}
+		if pr.MergeableState != "" && pr.MergeableState != "unknown" {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if detail, err := ghClient.GetPull(ctx, owner, repo, pr.Number); err == nil {
+				pr.MergeableState = detail.MergeableState
+			} else {
+				logger.GitHubLog.Warnf("Failed to fetch %s/%s#%d: %v", owner, repo, pr.Number, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// gitHubErrorStatus 將 GitHub 錯誤轉成回傳給前端的 HTTP 狀態碼
+func gitHubErrorStatus(err error) int {
+	var apiErr *github.APIError
+	switch {
+	case errors.Is(err, github.ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+		return http.StatusNotFound
+	default:
+		return http.StatusBadGateway
+	}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"web_test/internal/logger"
+	"web_test/pkg/models"
+)
+
+// resolveHeadSHAs 補上 params 中未知的 PR head commit，讓任務固定測試加入佇列當下的程式碼。
+// 優先向 GitHub 查詢；失敗時退回 PR 快取，兩者都沒有時回傳錯誤。
+func resolveHeadSHAs(ctx context.Context, params []models.TaskParams) error {
+	for i := range params {
+		param := &params[i]
+		if param.HeadSHA != "" {
+			continue
+		}
+		repo, ok := lookupNFRepo(param.NF)
+		if !ok {
+			return fmt.Errorf("unknown NF %q", param.NF)
+		}
+		number, err := strconv.Atoi(param.PRVersion)
+		if err != nil {
+			return fmt.Errorf("invalid PR number %q for %s", param.PRVersion, param.NF)
+		}
+
+		pr, err := ghClient.GetPull(ctx, repo.Owner, repo.Repo, number)
+		if err == nil {
+			param.HeadSHA = pr.Head.SHA
+			continue
+		}
+		cached, cacheErr := cachedPull(ctx, repo, number)
+		if cacheErr != nil || cached == nil || cached.HeadSHA == "" {
+			return fmt.Errorf("resolve head commit of %s #%d: %w", param.NF, number, err)
+		}
+		logger.WebLog.Warnf("Using cached head %s of %s #%d: %v", cached.HeadSHA, param.NF, number, err)
+		param.HeadSHA = cached.HeadSHA
+	}
+	return nil
+}
+
+// cachedPull 從 PR 快取找出 repo 的 PR，不在快取中時回傳 nil
+func cachedPull(ctx context.Context, repo models.NFRepo, number int) (*models.PullRequest, error) {
+	entry, err := DB.GetPrCache(ctx, models.PrCacheKey(repo.Owner, repo.Repo))
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	for i := range entry.PRs {
+		if entry.PRs[i].Number == number {
+			return &entry.PRs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// headChecker 依 PR 快取判斷結果測試的 commit 是否已不是 PR 目前的 head。
+// 同一個請求內重複使用，避免對同一 repo 重複讀取快取。
+type headChecker struct {
+	ctx     context.Context
+	entries map[string]*models.PrCacheEntry
+}
+
+func newHeadChecker(ctx context.Context) *headChecker {
+	return &headChecker{ctx: ctx, entries: make(map[string]*models.PrCacheEntry)}
+}
+
+// currentHead 回傳快取中 PR 目前的 head；PR 不在快取中（例如已關閉）時回傳空字串
+func (h *headChecker) currentHead(param models.TaskParams) string {
+	repo, ok := lookupNFRepo(param.NF)
+	if !ok {
+		return ""
+	}
+	key := models.PrCacheKey(repo.Owner, repo.Repo)
+	entry, ok := h.entries[key]
+	if !ok {
+		var err error
+		entry, err = DB.GetPrCache(h.ctx, key)
+		if err != nil {
+			logger.WebLog.Warnf("Failed to read PR cache of %s: %v", key, err)
+		}
+		h.entries[key] = entry
+	}
+	if entry == nil {
+		return ""
+	}
+	for _, pr := range entry.PRs {
+		if strconv.Itoa(pr.Number) == param.PRVersion {
+			return pr.HeadSHA
+		}
+	}
+	return ""
+}
+
+// staleParams 回傳 PR 已有新 commit 的參數，HeadSHA 為 PR 目前的 head
+func (h *headChecker) staleParams(params []models.TaskParams) []models.TaskParams {
+	var stale []models.TaskParams
+	for _, param := range params {
+		if param.HeadSHA == "" {
+			continue
+		}
+		if head := h.currentHead(param); head != "" && head != param.HeadSHA {
+			current := param
+			current.HeadSHA = head
+			stale = append(stale, current)
+		}
+	}
+	return stale
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"web_test/internal/github/githubtest"
+	"web_test/pkg/models"
+)
+
+func TestResolveHeadSHAs(t *testing.T) {
+	gh := githubtest.NewServer()
+	defer gh.Close()
+	gh.AddPull("free5gc", "go-upf", githubtest.Pull{Number: 7, HeadSHA: "aaa111"})
+	newPRTestEngine(t, gh, []models.NFRepo{{NF: "upf", Owner: "free5gc", Repo: "go-upf"}})
+	ctx := context.Background()
+
+	// PR 8 不在 GitHub 上（例如 API 暫時失敗），退回快取
+	DB.SavePrCache(ctx, &models.PrCacheEntry{
+		Owner: "free5gc", Repo: "go-upf",
+		PRs: []models.PullRequest{{Number: 8, HeadSHA: "bbb222"}},
+	})
+
+	params := []models.TaskParams{
+		{NF: "upf", PRVersion: "7"},
+		{NF: "upf", PRVersion: "8"},
+		{NF: "upf", PRVersion: "9", HeadSHA: "ccc333"},
+	}
+	if err := resolveHeadSHAs(ctx, params); err != nil {
+		t.Fatalf("resolveHeadSHAs: %v", err)
+	}
+	for i, want := range []string{"aaa111", "bbb222", "ccc333"} {
+		if params[i].HeadSHA != want {
+			t.Errorf("params[%d].HeadSHA = %q, want %q", i, params[i].HeadSHA, want)
+		}
+	}
+
+	err := resolveHeadSHAs(ctx, []models.TaskParams{{NF: "upf", PRVersion: "10"}})
+	if err == nil {
+		t.Fatal("unknown PR resolved")
+	}
+	if status := enqueueErrorStatus(fmt.Errorf("%w: %w", errHeadUnresolved, err)); status != http.StatusNotFound {
+		t.Errorf("status = %d, want 404 for a PR that does not exist", status)
+	}
+}
+
+func TestStaleParams(t *testing.T) {
+	gh := githubtest.NewServer()
+	defer gh.Close()
+	newPRTestEngine(t, gh, []models.NFRepo{{NF: "upf", Owner: "free5gc", Repo: "go-upf"}})
+	ctx := context.Background()
+
+	DB.SavePrCache(ctx, &models.PrCacheEntry{
+		Owner: "free5gc", Repo: "go-upf",
+		PRs: []models.PullRequest{{Number: 7, HeadSHA: "new"}, {Number: 8, HeadSHA: "same"}},
+	})
+
+	stale := newHeadChecker(ctx).staleParams([]models.TaskParams{
+		{NF: "upf", PRVersion: "7", HeadSHA: "old"},
+		{NF: "upf", PRVersion: "8", HeadSHA: "same"},
+		{NF: "upf", PRVersion: "9", HeadSHA: "closed"}, // 已不在快取中的 PR 無法判斷
+		{NF: "upf", PRVersion: "7"},                    // 舊結果沒有 head commit
+	})
+	if len(stale) != 1 || stale[0].PRVersion != "7" || stale[0].HeadSHA != "new" {
+		t.Fatalf("stale = %+v, want only PR 7 with its current head", stale)
+	}
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"web_test/internal/executor"
+)
+
+// readyTimeout 限制每個 readiness 檢查所花的時間
+const readyTimeout = 2 * time.Second
+
+// ExecutorProbe 為 readiness 檢查 executor 所需的方法
+type ExecutorProbe interface {
+	Status() executor.Status
+	CheckEnvironment() error
+}
+
+var (
+	// execProbe 為 nil 時（只啟動 web server）略過 executor 相關檢查
+	execProbe   ExecutorProbe
+	taskTimeout time.Duration
+)
+
+// QueueProbe 為 readiness 檢查共用佇列所需的方法
+type QueueProbe interface {
+	Ping(ctx context.Context) error
+}
+
+// queueProbe 不為 nil 時（queue.backend 為 redis）檢查佇列的連線；memory 佇列不需要檢查
+var queueProbe QueueProbe
+
+// SetQueueProbe 設定 readiness 檢查的佇列，nil 表示略過
+func SetQueueProbe(p QueueProbe) {
+	queueProbe = p
+}
+
+// SetExecutorProbe 設定 readiness 檢查的 executor 與任務逾時，執行超過 timeout 的任務視為卡住
+func SetExecutorProbe(p ExecutorProbe, timeout time.Duration) {
+	execProbe = p
+	taskTimeout = timeout
+}
+
+type readyCheck struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// checkResult 是單一 readiness 檢查的結果
+type checkResult struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"` // ok 或 fail
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type readyResponse struct {
+	Status string        `json:"status"` // 全部通過為 ok，否則為 fail
+	Checks []checkResult `json:"checks"`
+}
+
+// LivezHandler 只確認行程仍能處理請求
+func LivezHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, statusResponse{Status: "ok"})
+}
+
+// ReadyzHandler 檢查 ResultStore、共用佇列、executor 迴圈與 run_task.sh / ci-test，任一失敗時回傳 503
+func ReadyzHandler(c *gin.Context) {
+	checks := []readyCheck{
+		{"store", func(ctx context.Context) error { return DB.Ping(ctx) }},
+	}
+	if queueProbe != nil {
+		checks = append(checks, readyCheck{"queue", queueProbe.Ping})
+	}
+	if execProbe != nil {
+		checks = append(checks,
+			readyCheck{"executor", checkExecutor},
+			readyCheck{"run_task", func(context.Context) error { return execProbe.CheckEnvironment() }},
+		)
+	}
+
+	resp := readyResponse{Status: "ok"}
+	for _, check := range checks {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), readyTimeout)
+		start := time.Now()
+		err := check.fn(ctx)
+		cancel()
+		result := checkResult{Name: check.name, Status: "ok", LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+		if err != nil {
+			result.Status, result.Error = "fail", err.Error()
+			resp.Status = "fail"
+		}
+		resp.Checks = append(resp.Checks, result)
+	}
+	code := http.StatusOK
+	if resp.Status != "ok" {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, resp)
+}
+
+// checkExecutor 確認 executor 迴圈仍在執行，且目前任務沒有超過任務逾時
+func checkExecutor(context.Context) error {
+	status := execProbe.Status()
+	if !status.Running {
+		return fmt.Errorf("executor loop is not running")
+	}
+	if status.TaskID != "" && taskTimeout > 0 {
+		if elapsed := time.Since(status.StartedAt); elapsed > taskTimeout {
+			return fmt.Errorf("task %s has been running for %s, longer than the task timeout %s",
+				status.TaskID, elapsed.Round(time.Second), taskTimeout)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"web_test/internal/executor"
+	"web_test/pkg/database"
+)
+
+type fakeProbe struct {
+	status executor.Status
+	envErr error
+}
+
+func (p *fakeProbe) Status() executor.Status { return p.status }
+func (p *fakeProbe) CheckEnvironment() error { return p.envErr }
+
+func TestReadyz(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	oldDB, oldProbe, oldTimeout := DB, execProbe, taskTimeout
+	t.Cleanup(func() {
+		DB = oldDB
+		SetExecutorProbe(oldProbe, oldTimeout)
+	})
+	DB = database.NewMemoryDB()
+	engine := gin.New()
+	engine.GET("/livez", LivezHandler)
+	engine.GET("/readyz", ReadyzHandler)
+
+	ready := func() (int, readyResponse) {
+		t.Helper()
+		w := doRequest(engine, http.MethodGet, "/readyz", "")
+		var resp readyResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return w.Code, resp
+	}
+
+	// 只有 web server 時只檢查 ResultStore
+	SetExecutorProbe(nil, 0)
+	if code, resp := ready(); code != http.StatusOK || len(resp.Checks) != 1 || resp.Checks[0].Name != "store" {
+		t.Errorf("readyz without executor = %d %+v", code, resp)
+	}
+
+	probe := &fakeProbe{status: executor.Status{Running: true, TaskID: "3", StartedAt: time.Now().Add(-time.Minute)}}
+	SetExecutorProbe(probe, time.Hour)
+	if code, resp := ready(); code != http.StatusOK || resp.Status != "ok" || len(resp.Checks) != 3 {
+		t.Errorf("healthy readyz = %d %+v", code, resp)
+	}
+
+	probe.status.StartedAt = time.Now().Add(-2 * time.Hour)
+	probe.envErr = errors.New("run_task.sh: no such file")
+	code, resp := ready()
+	if code != http.StatusServiceUnavailable || resp.Status != "fail" {
+		t.Errorf("readyz with a wedged task = %d %+v", code, resp)
+	}
+	for _, check := range resp.Checks {
+		if (check.Name == "executor" || check.Name == "run_task") != (check.Status == "fail") {
+			t.Errorf("check %+v", check)
+		}
+	}
+
+	probe.status = executor.Status{}
+	if code, _ := ready(); code != http.StatusServiceUnavailable {
+		t.Errorf("readyz with a stopped executor = %d", code)
+	}
+	if w := doRequest(engine, http.MethodGet, "/livez", ""); w.Code != http.StatusOK {
+		t.Errorf("livez = %d", w.Code)
+	}
+}
+
+func TestProbeServer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	oldDB, oldProbe, oldTimeout := DB, execProbe, taskTimeout
+	t.Cleanup(func() {
+		DB = oldDB
+		SetExecutorProbe(oldProbe, oldTimeout)
+	})
+	SetExecutorProbe(&fakeProbe{status: executor.Status{Running: true}}, time.Hour)
+	ws := NewProbeServer("0", database.NewMemoryDB())
+
+	// 只提供探測與指標，且不需要驗證
+	for path, want := range map[string]int{
+		"/livez":     http.StatusOK,
+		"/readyz":    http.StatusOK,
+		"/metrics":   http.StatusOK,
+		"/api/queue": http.StatusNotFound,
+	} {
+		if w := doRequest(ws.engine, http.MethodGet, path, ""); w.Code != want {
+			t.Errorf("GET %s = %d, want %d", path, w.Code, want)
+		}
+	}
+}
+
+type fakeQueueProbe struct{ err error }
+
+func (p fakeQueueProbe) Ping(context.Context) error { return p.err }
+
+func TestReadyzQueue(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	oldDB, oldQueue, oldProbe, oldTimeout := DB, queueProbe, execProbe, taskTimeout
+	t.Cleanup(func() {
+		DB = oldDB
+		SetQueueProbe(oldQueue)
+		SetExecutorProbe(oldProbe, oldTimeout)
+	})
+	DB = database.NewMemoryDB()
+	SetExecutorProbe(nil, 0)
+	engine := gin.New()
+	engine.GET("/readyz", ReadyzHandler)
+
+	// Redis 佇列無法連線時 serve 與 work 都不算 ready
+	SetQueueProbe(fakeQueueProbe{err: errors.New("connection refused")})
+	w := doRequest(engine, http.MethodGet, "/readyz", "")
+	var resp readyResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusServiceUnavailable || len(resp.Checks) != 2 || resp.Checks[1].Name != "queue" || resp.Checks[1].Status != "fail" {
+		t.Errorf("readyz with a broken queue = %d %+v", w.Code, resp)
+	}
+	SetQueueProbe(fakeQueueProbe{})
+	if w := doRequest(engine, http.MethodGet, "/readyz", ""); w.Code != http.StatusOK {
+		t.Errorf("readyz with a healthy queue = %d", w.Code)
+	}
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"web_test/internal/logger"
+)
+
+// 背景更新 PR 快取工作的狀態
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobPartial   = "partial" // 更新所有 NF repo 時只有部分 repo 失敗
+	JobFailed    = "failed"
+)
+
+// fetchJobTimeout 限制單一背景工作向 GitHub 取資料的時間
+const fetchJobTimeout = 2 * time.Minute
+
+// fetchJobTTL 為已結束的工作保留供查詢的時間
+const fetchJobTTL = time.Hour
+
+// fetchJob 是背景更新單一 repo PR 快取的工作；owner 與 repo 皆為空時更新所有 NF repo
+type fetchJob struct {
+	ID         string `json:"id"`
+	Owner      string `json:"owner"`
+	Repo       string `json:"repo"`
+	Force      bool   `json:"force"`
+	Status     string `json:"status"`
+	Message    string `json:"message,omitempty"`     // 目前進度的說明
+	Error      string `json:"error,omitempty"`       // 失敗原因，快取維持原樣
+	StatusCode int    `json:"status_code,omitempty"` // 失敗時對應的 HTTP 狀態碼，例如 404、429
+	CreatedAt  int64  `json:"created_at"`            // UTC Unix 秒
+	StartedAt  int64  `json:"started_at,omitempty"`
+	FinishedAt int64  `json:"finished_at,omitempty"`
+
+	Repos  int               `json:"repos,omitempty"`  // 更新所有 NF repo 時的 repo 數
+	Errors map[string]string `json:"errors,omitempty"` // 更新所有 NF repo 時失敗的 NF 與原因
+
+	done chan struct{} // 工作結束時關閉
+}
+
+func (j *fetchJob) finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobPartial || j.Status == JobFailed
+}
+
+// fetchJobs 記錄所有背景工作；同一 repo 已有可沿用的未結束工作時不重複建立
+var fetchJobs = struct {
+	sync.Mutex
+	nextID int
+	byID   map[string]*fetchJob
+}{byID: make(map[string]*fetchJob)}
+
+// startFetchJob 建立背景工作更新 owner/repo 的 PR 快取並回傳工作快照。
+// 同一 repo 已有未結束的工作時回傳該工作；但未強制更新的工作可能因快取未過期而不向 GitHub 查詢，
+// 因此強制更新的要求只沿用強制更新的工作
+func startFetchJob(owner, repo string, force bool) fetchJob {
+	fetchJobs.Lock()
+	defer fetchJobs.Unlock()
+
+	now := time.Now()
+	for id, job := range fetchJobs.byID {
+		if job.finished() && now.Sub(time.Unix(job.FinishedAt, 0)) > fetchJobTTL {
+			delete(fetchJobs.byID, id)
+			continue
+		}
+		if !job.finished() && job.Owner == owner && job.Repo == repo && (job.Force || !force) {
+			return *job
+		}
+	}
+
+	fetchJobs.nextID++
+	job := &fetchJob{
+		ID:        strconv.Itoa(fetchJobs.nextID),
+		Owner:     owner,
+		Repo:      repo,
+		Force:     force,
+		Status:    JobQueued,
+		CreatedAt: now.Unix(),
+		done:      make(chan struct{}),
+	}
+	if owner == "" && repo == "" {
+		job.Repos = len(nfRepos)
+	}
+	fetchJobs.byID[job.ID] = job
+	go runFetchJob(job)
+	return *job
+}
+
+// getFetchJob 回傳工作的快照
+func getFetchJob(id string) (fetchJob, bool) {
+	fetchJobs.Lock()
+	defer fetchJobs.Unlock()
+	job, ok := fetchJobs.byID[id]
+	if !ok {
+		return fetchJob{}, false
+	}
+	return *job, true
+}
+
+// updateFetchJob 在鎖內修改工作狀態
+func updateFetchJob(job *fetchJob, update func(*fetchJob)) {
+	fetchJobs.Lock()
+	defer fetchJobs.Unlock()
+	update(job)
+}
+
+func runFetchJob(job *fetchJob) {
+	defer close(job.done)
+	updateFetchJob(job, func(j *fetchJob) {
+		j.Status = JobRunning
+		j.Message = "fetching pull requests from GitHub"
+		j.StartedAt = time.Now().Unix()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), fetchJobTimeout)
+	defer cancel()
+	if job.Owner == "" && job.Repo == "" {
+		runRefreshNFsJob(ctx, job)
+		return
+	}
+	err := refreshPrCache(ctx, job.Owner, job.Repo, job.Force)
+
+	updateFetchJob(job, func(j *fetchJob) {
+		j.FinishedAt = time.Now().Unix()
+		if err != nil {
+			j.Status = JobFailed
+			j.Message = ""
+			j.Error = err.Error()
+			j.StatusCode = gitHubErrorStatus(err)
+			return
+		}
+		j.Status = JobSucceeded
+		j.Message = "PR cache is up to date"
+	})
+	if err != nil {
+		logger.GitHubLog.Errorf("Job %s: failed to refresh PR cache of %s/%s: %v", job.ID, job.Owner, job.Repo, err)
+	} else {
+		logger.GitHubLog.Infof("Job %s: refreshed PR cache of %s/%s", job.ID, job.Owner, job.Repo)
+	}
+}
+
+// runRefreshNFsJob 更新所有 NF repo 的快取；全部失敗時工作失敗，部分失敗時狀態為 partial
+func runRefreshNFsJob(ctx context.Context, job *fetchJob) {
+	errs := refreshNFRepos(ctx, job.Force)
+
+	updateFetchJob(job, func(j *fetchJob) {
+		j.FinishedAt = time.Now().Unix()
+		j.Message = ""
+		if len(errs) > 0 {
+			j.Errors = make(map[string]string, len(errs))
+			for nf, err := range errs {
+				j.Errors[nf] = err.Error()
+			}
+		}
+		switch {
+		case len(errs) == 0:
+			j.Status = JobSucceeded
+			j.Message = "PR caches of all NF repos are up to date"
+		case len(errs) < j.Repos:
+			j.Status = JobPartial
+			j.Error = fmt.Sprintf("failed to refresh %d of %d NF repos", len(errs), j.Repos)
+		default:
+			j.Status = JobFailed
+			j.Error = "failed to refresh every NF repo"
+			for _, err := range errs {
+				j.StatusCode = gitHubErrorStatus(err)
+				break
+			}
+		}
+	})
+	logger.GitHubLog.Infof("Job %s: refreshed PR caches of %d NF repos, %d failed", job.ID, job.Repos, len(errs))
+}
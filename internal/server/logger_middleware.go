@@ -1,20 +1,29 @@
 package server
 
 import (
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"web_test/internal/logger"
+	"web_test/internal/metrics"
 )
 
 // GinLogger returns a gin middleware that logs requests via backend/logger.MainLog
+// and records their latency by route template.
 func GinLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		c.Next()
 		latency := time.Since(start)
 		status := c.Writer.Status()
+		// 以路由樣板作為 label，避免 task ID 等路徑參數讓 label 無限增加
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPRequestDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(status)).Observe(latency.Seconds())
 
 		logger.WebLog.WithFields(map[string]interface{}{
 			"method":  c.Request.Method,
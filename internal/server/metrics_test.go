@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	engine := newV1TestEngine(t)
+	doRequest(engine, http.MethodGet, "/api/download/task/404", "")
+
+	w := doRequest(engine, http.MethodGet, "/metrics", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("metrics: %d %s", w.Code, w.Body)
+	}
+	for _, want := range []string{
+		`web_test_http_request_duration_seconds_count{method="GET",route="/api/download/task/:taskID",status="404"}`,
+		"web_test_queue_length",
+		"go_goroutines",
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("metrics output is missing %s", want)
+		}
+	}
+
+	// 啟用驗證時 /metrics 也需要 token，Prometheus 以 bearer token 抓取
+	oldEnabled, oldAdmin := authEnabled, adminToken
+	t.Cleanup(func() { SetAuthConfig(oldEnabled, oldAdmin) })
+	SetAuthConfig(true, "scraper")
+	if w := doRequest(engine, http.MethodGet, "/metrics", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("metrics without a token while auth is enabled: %d", w.Code)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer scraper")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("metrics with a token: %d", w.Code)
+	}
+}
@@ -0,0 +1,251 @@
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+func MetaRoute() []Route {
+	return []Route{
+		{
+			Name:        "get OpenAPI document",
+			Method:      http.MethodGet,
+			Pattern:     "/openapi.json",
+			HandlerFunc: OpenAPIHandler,
+			Role:        rolePublic,
+			Response:    map[string]any{},
+		},
+	}
+}
+
+var openAPIDoc struct {
+	sync.Once
+	doc map[string]any
+}
+
+// OpenAPIHandler 回傳由路由表產生的 OpenAPI 3 文件
+func OpenAPIHandler(c *gin.Context) {
+	openAPIDoc.Do(func() { openAPIDoc.doc = buildOpenAPI(serviceGroups()) })
+	c.JSON(http.StatusOK, openAPIDoc.doc)
+}
+
+// buildOpenAPI 依路由表的 Request、Response 型別以 reflection 產生 OpenAPI 文件
+func buildOpenAPI(groups []serviceGroup) map[string]any {
+	b := &openAPIBuilder{schemas: map[string]any{}}
+	b.schemas["Error"] = b.structSchema(reflect.TypeOf(ErrorResponse{}))
+
+	paths := map[string]any{}
+	for _, group := range groups {
+		for _, route := range group.Routes {
+			path := openAPIPath(group.Prefix + route.Pattern)
+			item, _ := paths[path].(map[string]any)
+			if item == nil {
+				item = map[string]any{}
+				paths[path] = item
+			}
+			item[strings.ToLower(route.Method)] = b.operation(group.Tag, route)
+		}
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "free5gc web_test API",
+			"version": "v1",
+		},
+		"servers": []any{map[string]any{"url": apiV1Prefix}},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": b.schemas,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+// openAPIPath 將 gin 的 /:id 轉成 /{id}
+func openAPIPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+type openAPIBuilder struct {
+	schemas map[string]any
+}
+
+func (b *openAPIBuilder) operation(tag string, route Route) map[string]any {
+	op := map[string]any{
+		"summary":         route.Name,
+		"operationId":     operationID(route.Name),
+		"tags":            []string{tag},
+		"x-required-role": requiredRole(route.Role),
+	}
+	if route.Role == rolePublic {
+		op["security"] = []any{}
+	} else {
+		op["security"] = []any{map[string]any{"bearerAuth": []string{}}}
+	}
+
+	var params []any
+	for _, part := range strings.Split(route.Pattern, "/") {
+		if strings.HasPrefix(part, ":") {
+			params = append(params, map[string]any{
+				"name": part[1:], "in": "path", "required": true, "schema": map[string]any{"type": "string"},
+			})
+		}
+	}
+	names := make([]string, 0, len(route.Query))
+	for name := range route.Query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		params = append(params, map[string]any{
+			"name": name, "in": "query", "description": route.Query[name], "schema": map[string]any{"type": "string"},
+		})
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	if route.Request != nil {
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": b.schemaOf(reflect.TypeOf(route.Request))}},
+		}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]any{"description": http.StatusText(status)}
+	switch {
+	case route.Response != nil:
+		success["content"] = map[string]any{"application/json": map[string]any{"schema": b.schemaOf(reflect.TypeOf(route.Response))}}
+	case route.Produces != "":
+		success["content"] = map[string]any{route.Produces: map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}}}
+	}
+	op["responses"] = map[string]any{
+		strconv.Itoa(status): success,
+		"default": map[string]any{
+			"description": "Error",
+			"content":     map[string]any{"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Error"}}},
+		},
+	}
+	return op
+}
+
+func requiredRole(role string) string {
+	if role == "" {
+		return "viewer"
+	}
+	return role
+}
+
+// operationID 將 "get queue" 轉成 getQueue
+func operationID(name string) string {
+	words := strings.Fields(name)
+	for i, w := range words {
+		if i > 0 {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	return strings.Join(words, "")
+}
+
+// schemaOf 回傳型別的 JSON schema；具名的 struct 放在 components 並回傳 $ref
+func (b *openAPIBuilder) schemaOf(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": b.schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+		if _, ok := b.schemas[name]; !ok {
+			b.schemas[name] = map[string]any{} // 先佔位，避免遞迴型別無限展開
+			b.schemas[name] = b.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	default:
+		return map[string]any{}
+	}
+}
+
+func (b *openAPIBuilder) structSchema(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	b.addFields(t, props, &required)
+	schema := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+// addFields 依 encoding/json 的規則加入欄位，嵌入的 struct 會展開
+func (b *openAPIBuilder) addFields(t reflect.Type, props map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				b.addFields(ft, props, required)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = b.schemaOf(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"web_test/pkg/database"
+	"web_test/pkg/queue"
+)
+
+func newV1TestEngine(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	oldDB, oldQ := DB, TaskQ
+	t.Cleanup(func() { DB, TaskQ = oldDB, oldQ })
+	TaskQ = queue.NewListQueue()
+	engine := gin.New()
+	AddService(engine, database.NewMemoryDB())
+	return engine
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	engine := newV1TestEngine(t)
+	w := doRequest(engine, http.MethodGet, "/api/v1/openapi.json", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("openapi.json: %d %s", w.Code, w.Body)
+	}
+	var doc struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, group := range serviceGroups() {
+		for _, route := range group.Routes {
+			path := openAPIPath(group.Prefix + route.Pattern)
+			if _, ok := doc.Paths[path][strings.ToLower(route.Method)]; !ok {
+				t.Errorf("%s %s (%s) missing from the document", route.Method, path, route.Name)
+			}
+			if route.Response == nil && route.Produces == "" {
+				t.Errorf("route %q documents no response type", route.Name)
+			}
+		}
+	}
+	if op := string(doc.Paths["/queue/run-pr"]["post"]); !strings.Contains(op, `"#/components/schemas/RunPRRequest"`) ||
+		!strings.Contains(op, `"#/components/schemas/RunPRResponse"`) {
+		t.Errorf("run-pr operation = %s", op)
+	}
+	if _, ok := doc.Paths["/download/task/{taskID}"]; !ok {
+		t.Error("path parameters are not converted to {name}")
+	}
+
+	// 所有 $ref 都要指向 components 中的 schema
+	for _, m := range regexp.MustCompile(`"#/components/schemas/(\w+)"`).FindAllStringSubmatch(w.Body.String(), -1) {
+		if _, ok := doc.Components.Schemas[m[1]]; !ok {
+			t.Errorf("dangling $ref to %s", m[1])
+		}
+	}
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	engine := newV1TestEngine(t)
+
+	var v1 ErrorResponse
+	w := doRequest(engine, http.MethodGet, "/api/v1/download/task/404", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &v1); err != nil || w.Code != http.StatusNotFound ||
+		v1.Error.Code != CodeNotFound || v1.Error.Message == "" {
+		t.Errorf("v1 error = %d %s", w.Code, w.Body)
+	}
+	w = doRequest(engine, http.MethodPost, "/api/v1/queue/run-pr", `{"params":[]}`)
+	if err := json.Unmarshal(w.Body.Bytes(), &v1); err != nil || w.Code != http.StatusBadRequest || v1.Error.Code != CodeInvalidArgument {
+		t.Errorf("v1 bad request = %d %s", w.Code, w.Body)
+	}
+	w = doRequest(engine, http.MethodGet, "/api/v1/nope", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &v1); err != nil || v1.Error.Code != CodeNotFound {
+		t.Errorf("v1 unknown route = %d %s", w.Code, w.Body)
+	}
+
+	// 舊路徑保留字串的 error 欄位
+	var legacy legacyErrorResponse
+	w = doRequest(engine, http.MethodGet, "/api/download/task/404", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &legacy); err != nil || w.Code != http.StatusNotFound || legacy.Code != CodeNotFound || legacy.Error == "" {
+		t.Errorf("legacy error = %d %s", w.Code, w.Body)
+	}
+
+	if w := doRequest(engine, http.MethodGet, "/api/v1/queue/list", ""); w.Code != http.StatusOK {
+		t.Errorf("v1 queue list: %d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"web_test/internal/logger"
+	"web_test/pkg/models"
+)
+
+// GetTaskProgressHandler 回傳執行中任務的進度；已開始但還沒有進度標記時回傳空的進度，
+// 不是執行中的任務回傳 404
+func GetTaskProgressHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	taskID := c.Param("taskID")
+	if progress := taskProgress(ctx, taskID); progress != nil {
+		c.JSON(http.StatusOK, progress)
+		return
+	}
+	result, err := DB.GetResult(ctx, taskID)
+	if err != nil || result == nil || result.Status != models.StatusRunning {
+		respondError(c, http.StatusNotFound, "task is not running")
+		return
+	}
+	c.JSON(http.StatusOK, models.ProgressInfo{TaskID: taskID, Remaining: -1})
+}
+
+// taskProgress 取得任務進度，並扣除上次更新後經過的時間；沒有進度時回傳 nil
+func taskProgress(ctx context.Context, taskID string) *models.ProgressInfo {
+	progress, err := DB.GetProgress(ctx, taskID)
+	if err != nil {
+		logger.WebLog.Warnf("Failed to get progress of task %s: %v", taskID, err)
+		return nil
+	}
+	if progress != nil && progress.Remaining > 0 {
+		elapsed := time.Now().Unix() - progress.UpdatedAt
+		progress.Remaining = max(progress.Remaining-max(elapsed, 0), 0)
+	}
+	return progress
+}
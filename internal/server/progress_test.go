@@ -0,0 +1,46 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"web_test/pkg/models"
+)
+
+func TestTaskProgress(t *testing.T) {
+	engine := newV1TestEngine(t)
+	ctx := context.Background()
+
+	if w := doRequest(engine, http.MethodGet, "/api/queue/progress/1", ""); w.Code != http.StatusNotFound {
+		t.Errorf("progress of an unknown task: %d %s", w.Code, w.Body)
+	}
+
+	if err := DB.SaveResult(ctx, &models.TaskResult{TaskID: "1", Status: models.StatusRunning}); err != nil {
+		t.Fatal(err)
+	}
+	var progress models.ProgressInfo
+	w := doRequest(engine, http.MethodGet, "/api/queue/progress/1", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &progress); err != nil || w.Code != http.StatusOK || progress.Remaining != -1 {
+		t.Errorf("progress before any marker = %d %s", w.Code, w.Body)
+	}
+
+	saved := &models.ProgressInfo{TaskID: "1", Stage: models.StageTestAll, CurrentTest: "TestPaging", Completed: 2, Total: 4,
+		Percent: 50, Remaining: 300, UpdatedAt: time.Now().Unix() - 60}
+	if err := DB.SaveProgress(ctx, saved); err != nil {
+		t.Fatal(err)
+	}
+	w = doRequest(engine, http.MethodGet, "/api/v1/queue/progress/1", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &progress); err != nil || progress.CurrentTest != "TestPaging" ||
+		progress.Remaining < 230 || progress.Remaining > 240 {
+		t.Errorf("progress = %d %s, want about 240 seconds left", w.Code, w.Body)
+	}
+
+	var queue []models.TaskResult
+	w = doRequest(engine, http.MethodGet, "/api/queue/list", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &queue); err != nil || len(queue) != 1 || queue[0].Progress == nil || queue[0].Progress.Completed != 2 {
+		t.Errorf("queue list = %s, want the running task with its progress", w.Body)
+	}
+}
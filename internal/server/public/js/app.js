@@ -7,6 +7,15 @@ document.addEventListener("DOMContentLoaded", () => {
     const selectedTasksBody = document.getElementById("selected-tasks-body");
     const runAllBtn = document.getElementById("run-all-btn");
     const runMsg = document.getElementById("run-msg");
+    const baseRefInput = document.getElementById("base-ref-input");
+    const hideDraftsBox = document.getElementById("hide-drafts");
+    const prDetails = document.getElementById("pr-details");
+    const selectionBox = document.getElementById("test-selection");
+    const selectionSummary = document.getElementById("selection-summary");
+    const fullRunBox = document.getElementById("full-run");
+    const testBoxes = document.getElementById("test-checkboxes");
+    const envBoxes = document.getElementById("env-checkboxes");
+    const baseRefList = document.getElementById("base-ref-list");
 
     const queueBody = document.getElementById("queue-table-body");
     const historyList = document.getElementById("history-list");
@@ -14,36 +23,98 @@ document.addEventListener("DOMContentLoaded", () => {
     // 本地暫存的待執行任務列表
     let selectedTasks = [];
     let lastNfChangeAt = 0; // 用於控制空列表判斷的計時器
+    let currentRepo = "";   // 目前選擇的 owner/repo，用於讀取對應的 PR 快取
+    let selectionEdited = false; // 使用者是否修改過自動挑選的測試範圍
     const LOADING_TEXT = "載入中...";
 
     const sleep = (ms) => new Promise(resolve => setTimeout(resolve, ms));
 
     // ==========================================
-    // 1. NF 選擇變更 -> 觸發 GitHub 抓取
+    // 1. NF 列表 (依後端設定) 與 NF 選擇變更
     // ==========================================
+    let nfData = {}; // nf -> { owner, repo, prs, cached, stale }
+
+    async function loadNFs() {
+        try {
+            const res = await fetch("/api/prs/nfs");
+            const views = await res.json();
+            if (!Array.isArray(views)) return;
+
+            const firstLoad = Object.keys(nfData).length === 0;
+            nfData = {};
+            views.forEach(v => { nfData[v.nf] = v; });
+
+            if (firstLoad && nfSelect) {
+                nfSelect.innerHTML = '<option value="" disabled selected>-- 選擇 NF --</option>';
+                views.forEach(v => {
+                    const opt = document.createElement("option");
+                    opt.value = v.nf;
+                    opt.text = v.repo === v.nf ? v.nf : `${v.nf} (${v.repo})`;
+                    nfSelect.appendChild(opt);
+                });
+            }
+        } catch (e) { console.error("載入 NF 列表失敗:", e); }
+    }
+
+    // 等待背景工作結束並回傳其狀態，逾時則回傳最後一次的狀態
+    async function waitForJob(id, timeoutMs = 30000) {
+        const deadline = Date.now() + timeoutMs;
+        let job = { status: "queued" };
+        while (Date.now() < deadline) {
+            const res = await fetch(`/api/prs/jobs/${encodeURIComponent(id)}`);
+            job = await res.json().catch(() => ({}));
+            if (!res.ok) return { status: "failed", error: job.error || res.status };
+            if (job.status === "succeeded" || job.status === "partial" || job.status === "failed") return job;
+            await sleep(500);
+        }
+        return job;
+    }
+
+    // 測試基準：列出 free5gc 的 release，也可自行輸入分支或 commit
+    async function loadReleases() {
+        if (!baseRefList) return;
+        try {
+            const res = await fetch("/api/prs/releases");
+            const data = await res.json();
+            if (!res.ok || !Array.isArray(data.releases)) return;
+            baseRefList.innerHTML = "";
+            data.releases.forEach(rel => {
+                const opt = document.createElement("option");
+                opt.value = rel.tag_name;
+                if (rel.name && rel.name !== rel.tag_name) opt.label = rel.name;
+                baseRefList.appendChild(opt);
+            });
+        } catch (e) { console.error("載入 release 列表失敗:", e); }
+    }
+
     if (nfSelect) {
         nfSelect.addEventListener("change", async () => {
-            let repo = nfSelect.value;
-            if (repo === "upf") {
-                repo = "go-upf";
-            }
-            const owner = "free5gc";
+            const view = nfData[nfSelect.value];
+            if (!view) return;
+            currentRepo = `${view.owner}/${view.repo}`;
             lastNfChangeAt = Date.now();
             
             prSelect.innerHTML = `<option>${LOADING_TEXT}</option>`;
             
             try {
-                await fetch("/api/prs/clear", { method: "POST" });
-                // 呼叫後端抓取 (Worker 2)
-                await fetch("/api/prs/add_github", {
+                // 呼叫後端在背景抓取；快取未過期時後端不會重新呼叫 GitHub
+                const res = await fetch("/api/prs/add_github", {
                     method: "POST",
                     headers: { "Content-Type": "application/json" },
-                    body: JSON.stringify({ owner, repo })
+                    body: JSON.stringify({ owner: view.owner, repo: view.repo })
                 });
-                loadAll(); // 觸發刷新
-                // 簡單的輪詢等待結果 (或是依賴原本的 updatePRList 定時更新)
-                // 這裡為了體驗更好，稍微延遲後立即觸發一次更新
-                // setTimeout(updatePRList, 500);
+                const job = await res.json().catch(() => ({}));
+                if (!res.ok) {
+                    alert(`更新 ${currentRepo} 的 PR 失敗: ${job.error || res.status}`);
+                } else {
+                    const result = await waitForJob(job.id);
+                    if (result.status === "failed") {
+                        alert(`更新 ${currentRepo} 的 PR 失敗: ${result.error}`);
+                    }
+                }
+                await loadNFs();
+                updatePRList();
+                loadAll();
             } catch (e) { console.error(e); }
         });
     }
@@ -92,6 +163,63 @@ document.addEventListener("DOMContentLoaded", () => {
                 <td><button class="btn-del" data-local-id="${task.id}">刪除</button></td>
             `;
             selectedTasksBody.appendChild(tr);
+        });        previewSelection();
+    }
+
+    // 依待執行的 PR 向後端預覽自動挑選的測試範圍，並顯示可修改的勾選框
+    async function previewSelection() {
+        if (!selectionBox) return;
+        selectionEdited = false;
+        if (selectedTasks.length === 0) {
+            selectionBox.style.display = "none";
+            return;
+        }
+        try {
+            const params = selectedTasks.map(task => [task.nf, String(task.prNumber)]);
+            const res = await fetch("/api/queue/select-tests", {
+                method: "POST",
+                headers: { "Content-Type": "application/json" },
+                body: JSON.stringify({ params })
+            });
+            const view = await res.json();
+            if (!res.ok || !view.selection) {
+                // 未啟用測試挑選，一律完整測試
+                selectionBox.style.display = "none";
+                return;
+            }
+            const sel = view.selection;
+            const full = sel.full;
+            const reasons = Array.isArray(sel.reasons) ? sel.reasons.join("；") : "";
+            selectionSummary.textContent = full
+                ? `(自動: 完整測試${reasons ? "，" + reasons : ""})`
+                : `(自動: ${sel.tests.length}/${view.tests.length} 項測試${reasons ? "，" + reasons : ""})`;
+            fullRunBox.checked = full;
+            testBoxes.innerHTML = view.tests.map(name =>
+                `<label><input type="checkbox" name="sel-test" value="${escapeHTML(name)}" ${full || (sel.tests || []).includes(name) ? "checked" : ""}> ${escapeHTML(name)}</label>`).join("");
+            envBoxes.innerHTML = "環境: " + view.envs.map(name =>
+                `<label><input type="checkbox" name="sel-env" value="${escapeHTML(name)}" ${full || (sel.envs || []).includes(name) ? "checked" : ""}> ${escapeHTML(name)}</label>`).join(" ");
+            toggleSelectionBoxes();
+            selectionBox.style.display = "block";
+        } catch (e) { console.error("預覽測試範圍失敗:", e); }
+    }
+
+    function toggleSelectionBoxes() {
+        const disabled = fullRunBox.checked;
+        selectionBox.querySelectorAll('input[name="sel-test"], input[name="sel-env"]').forEach(box => { box.disabled = disabled; });
+    }
+
+    // 使用者修改後的測試範圍；未修改時回傳 null，由後端自動挑選
+    function editedSelection() {
+        if (!selectionEdited || !selectionBox) return null;
+        if (fullRunBox.checked) return { full: true };
+        const checked = name => Array.from(selectionBox.querySelectorAll(`input[name="${name}"]:checked`)).map(box => box.value);
+        return { full: false, tests: checked("sel-test"), envs: checked("sel-env") };
+    }
+
+    if (selectionBox) {
+        selectionBox.addEventListener("change", () => {
+            selectionEdited = true;
+            toggleSelectionBoxes();
         });
     }
 
@@ -102,8 +230,54 @@ document.addEventListener("DOMContentLoaded", () => {
             if (option && option.text === LOADING_TEXT) {
                 setTimeout(updatePRList, 600);
             }
+            renderPRDetails();
         });
     }
+    if (hideDraftsBox) {
+        hideDraftsBox.addEventListener("change", updatePRList);
+    }
+
+    function escapeHTML(str) {
+        return String(str).replace(/[&<>"']/g, c => ({ "&": "&amp;", "<": "&lt;", ">": "&gt;", '"': "&quot;", "'": "&#39;" }[c]));
+    }
+
+    // PR 選項：標題之外附上草稿標記、作者與 label
+    function createPROption(pr) {
+        const opt = document.createElement("option");
+        opt.value = pr.number;
+        const displayTitle = pr.title.length > 100 ? pr.title.substring(0, 100) + "..." : pr.title;
+        const draft = pr.draft ? "[草稿] " : "";
+        const author = pr.author ? ` (@${pr.author})` : "";
+        const labels = Array.isArray(pr.labels) && pr.labels.length ? ` [${pr.labels.join(", ")}]` : "";
+        opt.text = `#${pr.number}: ${draft}${displayTitle}${author}${labels}`;
+        opt.dataset.title = pr.title;
+        return opt;
+    }
+
+    // 顯示目前選擇的 PR 的 metadata 與變更檔案
+    function renderPRDetails() {
+        if (!prDetails) return;
+        const view = nfData[nfSelect.value];
+        const prs = view && Array.isArray(view.prs) ? view.prs : [];
+        const pr = prs.find(p => String(p.number) === prSelect.value);
+        if (!pr) {
+            prDetails.style.display = "none";
+            return;
+        }
+        const rows = [];
+        if (pr.author) rows.push(`作者: @${escapeHTML(pr.author)}`);
+        if (pr.head_sha) rows.push(`Head: ${escapeHTML(pr.head_sha.slice(0, 7))}${pr.base_ref ? ` → ${escapeHTML(pr.base_ref)}` : ""}`);
+        if (pr.mergeable_state) rows.push(`Mergeable: ${escapeHTML(pr.mergeable_state)}`);
+        if (pr.updated_at) rows.push(`更新時間: ${new Date(pr.updated_at * 1000).toLocaleString("zh-TW", { hour12: false })}`);
+        if (pr.draft) rows.push("草稿 (draft)");
+        const files = Array.isArray(pr.changed_files) ? pr.changed_files : [];
+        const fileLimit = 20;
+        const fileList = files.slice(0, fileLimit).map(f => `<li><code>${escapeHTML(f)}</code></li>`).join("");
+        const more = files.length > fileLimit ? `<li>... 另外 ${files.length - fileLimit} 個檔案</li>` : "";
+        prDetails.innerHTML = `<div>${rows.join(" ｜ ")}</div>` +
+            (files.length ? `<div style="margin-top: 6px;">變更檔案 (${files.length}):<ul style="margin: 4px 0; max-height: 150px; overflow-y: auto;">${fileList}${more}</ul></div>` : "");
+        prDetails.style.display = "block";
+    }
 
     // 監聽下區塊的刪除按鈕
     if (selectedTasksBody) {
@@ -132,13 +306,24 @@ document.addEventListener("DOMContentLoaded", () => {
                     String(task.prNumber)
                 ]);
 
-                await fetch("/api/queue/run-pr", {
+                const baseRef = baseRefInput ? baseRefInput.value.trim() : "";
+                const selection = editedSelection();
+                if (selection && !selection.full && selection.tests.length === 0) {
+                    runMsg.innerText = "請至少選擇一項測試";
+                    return;
+                }
+                const res = await fetch("/api/queue/run-pr", {
                     method: "POST",
                     headers: { "Content-Type": "application/json" },
-                    body: JSON.stringify({ params })
+                    body: JSON.stringify({ params, base_ref: baseRef, selection })
                 });
+                if (!res.ok) {
+                    const data = await res.json().catch(() => ({}));
+                    runMsg.innerText = "錯誤: " + (data.error || res.status);
+                    return;
+                }
 
-                runMsg.innerText = `已發送 ${selectedTasks.length} 個PR`;
+                runMsg.innerText = `已發送 ${selectedTasks.length} 個PR` + (baseRef ? ` (基準 ${baseRef})` : "");
                 selectedTasks = [];
                 renderSelectedTasks();
                 loadAll(); // 刷新佇列
@@ -155,7 +340,17 @@ document.addEventListener("DOMContentLoaded", () => {
     // ==========================================
     if (queueBody) {
         queueBody.addEventListener("click", async (e) => {
-            if (e.target.classList.contains("btn-del")) {
+            if (e.target.classList.contains("btn-cancel")) {
+                const id = e.target.dataset.id;
+                if (confirm(`確定要取消執行中的任務 ID ${id} 嗎?`)) {
+                    const res = await fetch(`/api/queue/cancel/${id}`, { method: "POST" });
+                    if (!res.ok) {
+                        const data = await res.json().catch(() => ({}));
+                        alert(`取消失敗: ${data.error || res.status}`);
+                    }
+                    loadAll();
+                }
+            } else if (e.target.classList.contains("btn-del")) {
                 if (e.target.disabled) return;
                 const id = e.target.dataset.id;
                 if(confirm(`確定要移除任務 ID ${id} 嗎?`)) {
@@ -184,8 +379,10 @@ document.addEventListener("DOMContentLoaded", () => {
                 await sleep(800);
             }
             
-            const res = await fetch("/api/prs");
-            const prs = await res.json();
+            if (!currentRepo) return;
+            const view = nfData[nfSelect.value];
+            const allPRs = view && Array.isArray(view.prs) ? view.prs : [];
+            const prs = hideDraftsBox && hideDraftsBox.checked ? allPRs.filter(pr => !pr.draft) : allPRs;
             
             // 清空選單
             const currentVal = prSelect.value;
@@ -209,14 +406,7 @@ document.addEventListener("DOMContentLoaded", () => {
             const displayLimit = 5;
             const initialPRs = prs.slice(0, displayLimit);
             
-            initialPRs.forEach(pr => {
-                const opt = document.createElement("option");
-                opt.value = pr.number;
-                let displayTitle = pr.title.length > 100 ? pr.title.substring(0, 100) + "..." : pr.title;
-                opt.text = `#${pr.number}: ${displayTitle}`;
-                opt.dataset.title = pr.title;
-                prSelect.appendChild(opt);
-            });
+            initialPRs.forEach(pr => prSelect.appendChild(createPROption(pr)));
 
             // 如果超過 5 個，加入 "..." 選項
             if (prs.length > displayLimit) {
@@ -243,14 +433,7 @@ document.addEventListener("DOMContentLoaded", () => {
 
                     // 加入剩餘的 PR
                     const remainingPRs = prs.slice(displayLimit);
-                    remainingPRs.forEach(pr => {
-                        const opt = document.createElement("option");
-                        opt.value = pr.number;
-                        let displayTitle = pr.title.length > 100 ? pr.title.substring(0, 100) + "..." : pr.title;
-                        opt.text = `#${pr.number}: ${displayTitle}`;
-                        opt.dataset.title = pr.title;
-                        prSelect.appendChild(opt);
-                    });
+                    remainingPRs.forEach(pr => prSelect.appendChild(createPROption(pr)));
                     
                     // 移除這個特殊的 onchange 處理器，恢復正常操作
                     this.onchange = null;
@@ -262,6 +445,7 @@ document.addEventListener("DOMContentLoaded", () => {
                 const exists = Array.from(prSelect.options).some(o => o.value === currentVal);
                 if (exists) prSelect.value = currentVal;
             }
+            renderPRDetails();
         } catch (e) {
             console.error("更新 PR 列表失敗:", e);
             prSelect.innerHTML = "<option>更新失敗</option>";
@@ -277,11 +461,57 @@ document.addEventListener("DOMContentLoaded", () => {
         return [];
     }
 
+    // 任務的測試基準，預設分支時不顯示
+    function formatBaseRef(task) {
+        const ref = task && task.base_ref;
+        return ref ? `<br><span style="color:#5e35b1; font-size:12px;">基準: ${ref}</span>` : "";
+    }
+
+    const sourceLabels = { ui: "網頁", webhook: "Webhook", schedule: "排程", cli: "CLI" };
+
+    // 任務的提交者與觸發來源，舊資料沒有時不顯示
+    function formatSubmitter(task) {
+        if (!task || !task.submitted_by) return "";
+        const source = sourceLabels[task.source] || task.source || "";
+        return `<br><span style="color:#888; font-size:12px;">由 ${escapeHTML(task.submitted_by)}${source ? ` (${source})` : ""} 提交</span>`;
+    }
+
+    // 任務的測試範圍，完整測試時不顯示
+    function formatSelection(task) {
+        const sel = task && task.selection;
+        if (!sel || sel.full) return "";
+        const tests = Array.isArray(sel.tests) ? sel.tests : [];
+        const envs = Array.isArray(sel.envs) && sel.envs.length ? sel.envs.join(", ") : "全部環境";
+        return `<br><span style="color:#00796b; font-size:12px;" title="${escapeHTML(tests.join(", "))}">` +
+            `${sel.manual ? "手動" : "自動"}挑選 ${tests.length} 項測試 (${escapeHTML(envs)})</span>`;
+    }
+
+    const stageLabels = {
+        pull: "拉取原始碼", fetch: "取得 PR", test_all: "testAll", retry: "重試失敗測試",
+        build: "建置", ulcl: "ULCL 測試", restore: "還原", collect: "整理結果",
+    };
+
+    // 執行中任務的進度：階段、目前測試、完成數與預估剩餘時間
+    function formatProgress(task) {
+        const p = task && task.progress;
+        if (!p) return "";
+        const parts = [];
+        if (p.stage) parts.push(stageLabels[p.stage] || escapeHTML(p.stage));
+        if (p.current_test) parts.push(escapeHTML(p.current_test));
+        if (p.total > 0) parts.push(`${p.completed}/${p.total} (${p.percent}%)`);
+        if (p.remaining >= 0) parts.push(`約剩 ${formatDuration(p.remaining)}`);
+        const bar = p.total > 0
+            ? `<div style="height:4px; background:#eee; margin-top:2px;"><div style="height:4px; width:${p.percent}%; background:#1976d2;"></div></div>`
+            : "";
+        return `<br><span style="color:#1976d2; font-size:12px;">${parts.join(" · ")}</span>${bar}`;
+    }
+
     function formatTaskLine(param) {
         if (!param) return `- [#-]`;
         const nf = param.nf || param.NF || "-";
         const pr = param.pr_number || param.prNumber || param.PRNumber || param.pr_version || param.prVersion || param.PRVersion || "-";
-        return `${nf} [#${pr}]`;
+        const sha = param.head_sha ? ` <span style="color:#888; font-size:12px;">@${param.head_sha.slice(0, 7)}</span>` : "";
+        return `${nf} [#${pr}]${sha}`;
     }
 
     // 更新排隊列表
@@ -315,9 +545,9 @@ document.addEventListener("DOMContentLoaded", () => {
             activeTasks.forEach((task) => {
                 const taskId = task.task_id || task.taskId || task.TaskID || task.id || "-";
                 const params = extractTaskParams(task);
-                const taskLabel = params.length
+                const taskLabel = (params.length
                     ? params.map(formatTaskLine).join("<br>")
-                    : (task.task_name || task.taskName || "-");
+                    : (task.task_name || task.taskName || "-")) + formatBaseRef(task) + formatSelection(task) + formatSubmitter(task) + formatProgress(task);
 
                 const rawStatus = (task.status || "").toLowerCase();
                 const statusLabel = rawStatus === "running"
@@ -330,6 +560,7 @@ document.addEventListener("DOMContentLoaded", () => {
                     : '<span class="spinner spinner-placeholder"></span>';
                 const statusCell = `<div class="running-task-row">${spinnerEl}<span>${statusLabel}</span></div>`;
                 const canDelete = rawStatus === "queueing" && taskId !== "-";
+                const canCancel = rawStatus === "running" && taskId !== "-";
 
                 queueBody.innerHTML += `
                     <tr>
@@ -337,7 +568,9 @@ document.addEventListener("DOMContentLoaded", () => {
                         <td>
                             ${canDelete
                                 ? `<button class="btn-del" data-id="${taskId}">移除</button>`
-                                : `<button class="btn-del" disabled style="opacity:0.4; cursor:not-allowed;">不可移除</button>`}
+                                : canCancel
+                                    ? `<button class="btn-del btn-cancel" data-id="${taskId}">取消</button>`
+                                    : `<button class="btn-del" disabled style="opacity:0.4; cursor:not-allowed;">不可移除</button>`}
                         </td>
                         <td style="text-align:center;">${statusCell}</td>
                     </tr>`;
@@ -357,12 +590,17 @@ document.addEventListener("DOMContentLoaded", () => {
             if (!records) return;
 
             records.forEach(r => {
-                const taskId = extractTaskId(r.task_name);
+                const taskId = r.task_id || extractTaskId(r.task_name);
                 const params = extractTaskParams(r);
-                const taskLabel = params.length
+                // PR 在測試後又有新的 commit 時標示結果已過時
+                const staleBadge = r.stale
+                    ? `<br><span style="color:#fb8c00; font-size:12px;" title="PR 已有新的 commit">⚠️ 已過時</span>`
+                    : "";
+                const taskLabel = (params.length
                     ? params.map(formatTaskLine).join("<br>")
-                    : (r.task_name || "-");
+                    : (r.task_name || "-")) + formatBaseRef(r) + formatSubmitter(r) + staleBadge;
                 const resultText = r.result || "-";
+                const durationText = r.duration ? `<br><span style="color:#888; font-size:12px;">${formatDuration(r.duration)}</span>` : "";
                 const lowerResult = resultText.toLowerCase();
                 const showDownload = taskId && lowerResult !== "success";
                 const downloadCell = showDownload
@@ -375,7 +613,7 @@ document.addEventListener("DOMContentLoaded", () => {
                 const resultColor = lowerResult === "failed" ? "#c62828" : (lowerResult === "running" ? "#fb8c00" : "green");
                 historyList.innerHTML += `
                     <tr>
-                        <td>${r.time}</td>
+                        <td>${r.time || "-"}${durationText}</td>
                         <td class="history-task-cell">${taskLabel}</td>
                         <td style='color:${resultColor}'>${resultText}</td>
                         <td>${previewCell}</td>
@@ -385,6 +623,13 @@ document.addEventListener("DOMContentLoaded", () => {
         } catch (e) {}
     }
 
+    function formatDuration(seconds) {
+        const h = Math.floor(seconds / 3600);
+        const m = Math.floor((seconds % 3600) / 60);
+        const s = seconds % 60;
+        return h > 0 ? `${h}h${m}m${s}s` : (m > 0 ? `${m}m${s}s` : `${s}s`);
+    }
+
     function extractTaskId(taskName) {
         if (!taskName) return null;
         const match = taskName.match(/(\d+)(?!.*\d)/); // capture last number in string
@@ -396,8 +641,42 @@ document.addEventListener("DOMContentLoaded", () => {
         loadHistory();
     }
 
-    // 啟動定時器
-    setInterval(loadAll, 1000);      // 狀態類每秒更新
-    setInterval(updatePRList, 1000); // 選單類每秒更新
-    loadAll(); 
+    // 佇列與任務狀態由 /api/events 推送，收到事件後合併成一次重新載入
+    let queueTimer = null;
+    let historyTimer = null;
+    function scheduleLoad(withHistory) {
+        if (!queueTimer) queueTimer = setTimeout(() => { queueTimer = null; loadQueue(); }, 200);
+        if (withHistory && !historyTimer) historyTimer = setTimeout(() => { historyTimer = null; loadHistory(); }, 200);
+    }
+
+    let streamConnected = false;
+    let lastUpdate = Date.now(); // 最近一次收到事件或輪詢的時間
+    if (window.EventSource) {
+        const stream = new EventSource("/api/events");
+        stream.onopen = () => { streamConnected = true; lastUpdate = Date.now(); loadAll(); }; // 重連後補齊斷線期間的變化
+        stream.onerror = () => { streamConnected = false; };                                    // 瀏覽器會自動重連，期間改用輪詢
+        ["task.queued", "task.removed", "task.started", "task.status", "task.progress"].forEach(type =>
+            stream.addEventListener(type, () => { lastUpdate = Date.now(); scheduleLoad(false); }));
+        stream.addEventListener("task.finished", () => { lastUpdate = Date.now(); scheduleLoad(true); });
+    }
+
+    // 啟動定時器：事件串流中斷時每 3 秒輪詢；連線中但 10 秒沒有事件時也更新，
+    // 避免 executor 在其他行程且事件沒有送達時畫面停住
+    setInterval(() => {
+        if (streamConnected && Date.now() - lastUpdate < 10000) return;
+        lastUpdate = Date.now();
+        loadAll();
+    }, 3000);
+    setInterval(loadAll, 30000);     // 保底的完整更新
+    setInterval(async () => { await loadNFs(); updatePRList(); }, 5000); // 選單類每 5 秒更新
+    loadAll();
+    // 先載入 NF 列表，再於背景同時更新所有 NF repo 的 PR 快取
+    loadNFs()
+        .then(() => fetch("/api/prs/refresh_nfs", { method: "POST" }))
+        .then(res => res.ok ? res.json() : null)
+        .then(job => job && job.id ? waitForJob(job.id, 120000) : null)
+        .then(job => { if (job && job.error) console.error("更新 NF 的 PR 快取失敗:", job.error, job.errors || {}); })
+        .catch(e => console.error("更新 NF 的 PR 快取失敗:", e))
+        .then(loadNFs);
+    loadReleases();
 });
\ No newline at end of file
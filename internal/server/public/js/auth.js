@@ -0,0 +1,28 @@
+// 伺服器啟用 token 驗證時，API 回傳 401 會要求輸入 token，
+// 並存成 web_test_token cookie（同源的 fetch 與下載連結都會帶上）後重送一次
+(function () {
+    const originalFetch = window.fetch.bind(window);
+
+    function setToken(token) {
+        document.cookie = `web_test_token=${encodeURIComponent(token)}; path=/; SameSite=Strict`;
+    }
+
+    window.fetch = async function (input, init) {
+        const res = await originalFetch(input, init);
+        const url = typeof input === "string" ? input : input.url;
+        if (res.status !== 401 || !url.startsWith("/api")) {
+            return res;
+        }
+        const token = window.prompt("此伺服器需要 API token，請輸入：");
+        if (!token) {
+            return res;
+        }
+        setToken(token.trim());
+        return originalFetch(input, init);
+    };
+
+    // 供 console 切換身分使用：webTestLogout()
+    window.webTestLogout = function () {
+        document.cookie = "web_test_token=; path=/; max-age=0; SameSite=Strict";
+    };
+})();
@@ -31,13 +31,32 @@ document.addEventListener("DOMContentLoaded", () => {
         if (!param) return "- [#-]";
         const nf = param.nf || param.NF || "-";
         const pr = param.pr_version || param.prVersion || param.PRVersion || param.pr_number || param.prNumber || param.PRNumber || "-";
-        return `${nf} [#${pr}]`;
+        const sha = param.head_sha ? ` @${param.head_sha.slice(0, 7)}` : "";
+        return `${nf} [#${pr}]${sha}`;
     }
 
     function buildTaskLabel(task) {
         const params = extractTaskParams(task);
         if (params.length) {
-            return params.map(formatTaskLine).join("\n");
+            const lines = params.map(formatTaskLine);
+            lines.push(`測試基準: free5gc ${task.base_ref || "預設分支"}`);
+            if (task.submitted_by) {
+                lines.push(`提交者: ${task.submitted_by}${task.source ? ` (${task.source})` : ""}`);
+            }
+            const sel = task.selection;
+            if (sel && !sel.full && Array.isArray(sel.tests)) {
+                const envs = Array.isArray(sel.envs) && sel.envs.length ? sel.envs.join(", ") : "全部環境";
+                lines.push(`測試範圍 (${sel.manual ? "手動" : "自動"}): ${sel.tests.join(", ")} @ ${envs}`);
+            } else {
+                lines.push("測試範圍: 完整測試");
+            }
+            // PR 在測試後又有新的 commit，結果可能已過時
+            if (task.stale && Array.isArray(task.stale_params)) {
+                task.stale_params.forEach(p => {
+                    lines.push(`⚠️ ${formatTaskLine({ ...p, head_sha: "" })} 已更新至 ${p.head_sha.slice(0, 7)}，此結果可能已過時`);
+                });
+            }
+            return lines.join("\n");
         }
         const fallbackId = task?.task_id || task?.taskId || taskId || "-";
         return `任務 #${fallbackId}`;
@@ -113,11 +132,23 @@ document.addEventListener("DOMContentLoaded", () => {
             running: "#fb8c00",
             queueing: "#757575"
         };
-        statusEl.textContent = status;
+        // 失敗原因判定（run_task.sh 結束碼）
+        const verdictText = {
+            pr: "PR 問題",
+            infra: "CI 環境問題",
+            unknown: "原因不明",
+            stale: "commit 已取不到"
+        };
+        statusEl.textContent = task.verdict && verdictText[task.verdict]
+            ? `${status} (${verdictText[task.verdict]})`
+            : status;
         statusEl.style.color = statusColorMap[normalized] || "#311b92";
 
         const ts = Number(task.timestamp);
-        if (ts) {
+        if (task.finished_time || task.started_time) {
+            // 由後端依設定時區格式化
+            timeEl.textContent = `${task.finished_time || task.started_time} (${task.timezone})`;
+        } else if (ts) {
             const date = new Date(ts * 1000);
             timeEl.textContent = date.toLocaleString("zh-TW", { hour12: false });
         } else {
@@ -135,7 +166,11 @@ document.addEventListener("DOMContentLoaded", () => {
         }
 
         if (failedEl) {
-            failedEl.textContent = failedTests.length > 0 ? `共 ${failedTests.length} 個失敗` : "-";
+            const flakyTests = Array.isArray(task.flaky_tests) ? task.flaky_tests : [];
+            const parts = [];
+            if (failedTests.length > 0) parts.push(`共 ${failedTests.length} 個失敗`);
+            if (flakyTests.length > 0) parts.push(`${flakyTests.length} 個重跑通過 (flaky): ${flakyTests.join(", ")}`);
+            failedEl.textContent = parts.length > 0 ? parts.join("；") : "-";
         }
 
         selectorEl.innerHTML = ''; 
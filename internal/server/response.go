@@ -0,0 +1,106 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"web_test/pkg/models"
+)
+
+// 錯誤代碼，依 HTTP 狀態碼決定，客戶端以此判斷錯誤種類而不需解析訊息
+const (
+	CodeInvalidArgument  = "invalid_argument"
+	CodeUnauthenticated  = "unauthenticated"
+	CodePermissionDenied = "permission_denied"
+	CodeNotFound         = "not_found"
+	CodeConflict         = "conflict"
+	CodeTooLarge         = "payload_too_large"
+	CodeRateLimited      = "rate_limited"
+	CodeInternal         = "internal"
+	CodeUpstream         = "upstream_error" // GitHub 等外部服務失敗
+	CodeUnavailable      = "unavailable"
+	CodeUpstreamTimeout  = "upstream_timeout"
+)
+
+var errorCodes = map[int]string{
+	http.StatusBadRequest:            CodeInvalidArgument,
+	http.StatusUnauthorized:          CodeUnauthenticated,
+	http.StatusForbidden:             CodePermissionDenied,
+	http.StatusNotFound:              CodeNotFound,
+	http.StatusConflict:              CodeConflict,
+	http.StatusRequestEntityTooLarge: CodeTooLarge,
+	http.StatusTooManyRequests:       CodeRateLimited,
+	http.StatusBadGateway:            CodeUpstream,
+	http.StatusServiceUnavailable:    CodeUnavailable,
+	http.StatusGatewayTimeout:        CodeUpstreamTimeout,
+}
+
+// errorCode 回傳 HTTP 狀態碼對應的錯誤代碼
+func errorCode(status int) string {
+	if code, ok := errorCodes[status]; ok {
+		return code
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeInvalidArgument
+}
+
+// ErrorBody 為 /api/v1 錯誤回應的內容
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ErrorResponse 為 /api/v1 統一的錯誤格式：{"error": {"code": ..., "message": ...}}
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// legacyErrorResponse 為 /api 舊路徑的錯誤格式，保留字串的 error 欄位供既有前端使用
+type legacyErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// isAPIV1 回傳請求是否來自 /api/v1，錯誤回應使用新的格式
+func isAPIV1(c *gin.Context) bool {
+	return strings.HasPrefix(c.Request.URL.Path, apiV1Prefix+"/")
+}
+
+// respondError 回傳錯誤並中止後續 handler
+func respondError(c *gin.Context, status int, message string) {
+	code := errorCode(status)
+	if isAPIV1(c) {
+		c.AbortWithStatusJSON(status, ErrorResponse{Error: ErrorBody{Code: code, Message: message}})
+		return
+	}
+	c.AbortWithStatusJSON(status, legacyErrorResponse{Error: message, Code: code})
+}
+
+// statusResponse 為沒有其他資料的操作結果
+type statusResponse struct {
+	Status string `json:"status"`
+}
+
+// runPRResponse 為 /api/v1 加入佇列的結果
+type runPRResponse struct {
+	TaskID    string                `json:"task_id"`
+	Status    string                `json:"status"` // queued
+	Selection *models.TestSelection `json:"selection"`
+}
+
+// legacyRunPRResponse 為 /api 舊路徑加入佇列的結果，保留給網頁顯示的 reply 訊息
+type legacyRunPRResponse struct {
+	runPRResponse
+	Reply string `json:"reply"`
+}
+
+// webhookResponse 為 webhook 的處理結果
+type webhookResponse struct {
+	Status string `json:"status"` // queued、ignored 或 pong
+	Reason string `json:"reason,omitempty"`
+	TaskID string `json:"task_id,omitempty"`
+}
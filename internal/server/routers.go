@@ -1,9 +1,12 @@
 package server
 
 import (
+	"io/fs"
 	"net/http"
 	"strings"
+	"web_test/internal/metrics"
 	"web_test/pkg/database"
+	"web_test/pkg/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,23 +17,61 @@ type Route struct {
 	Method      string
 	Pattern     string
 	HandlerFunc gin.HandlerFunc
+	// Role 為呼叫此路由所需的最低角色，空值為 viewer；rolePublic 不需要 token
+	Role string
+
+	// 以下只用於產生 OpenAPI 文件
+	Request  any               // request body 的型別（零值即可），nil 表示沒有 body
+	Response any               // 成功時回傳的型別，nil 表示回傳檔案或沒有內容
+	Status   int               // 成功時的狀態碼，0 為 200
+	Produces string            // 回傳檔案時的 content type
+	Query    map[string]string // query 參數名稱與說明
 }
 
 type Routes []Route
 
+// apiV1Prefix 為版本化 API 的路徑；/api 下的舊路徑保留給既有前端
+const apiV1Prefix = "/api/v1"
+
+// metricsPath 為 Prometheus 抓取指標的路徑
+const metricsPath = "/metrics"
+
+// serviceGroup 為一組掛在同一路徑下的路由
+type serviceGroup struct {
+	Prefix string
+	Tag    string // OpenAPI 的分類
+	Routes []Route
+}
+
+// serviceGroups 回傳 /api 與 /api/v1 共用的路由表
+func serviceGroups() []serviceGroup {
+	return []serviceGroup{
+		{Prefix: "/queue", Tag: "queue", Routes: QueueRoute()},
+		{Prefix: "/history", Tag: "history", Routes: HistoryRoute()},
+		{Prefix: "/prs", Tag: "prs", Routes: PrsRoute()},
+		{Prefix: "/download", Tag: "download", Routes: DownloadRoute()},
+		{Prefix: "/admin", Tag: "admin", Routes: AdminRoute()},
+		{Prefix: "/webhooks", Tag: "webhooks", Routes: WebhookRoute()},
+		{Prefix: "/audit", Tag: "audit", Routes: AuditRoute()},
+		{Prefix: "/events", Tag: "events", Routes: EventsRoute()},
+		{Prefix: "", Tag: "meta", Routes: MetaRoute()},
+	}
+}
+
 func applyRoutes(group *gin.RouterGroup, routes []Route) {
 	for _, route := range routes {
+		handlers := []gin.HandlerFunc{requireRole(route.Role), route.HandlerFunc}
 		switch route.Method {
 		case "GET":
-			group.GET(route.Pattern, route.HandlerFunc)
+			group.GET(route.Pattern, handlers...)
 		case "POST":
-			group.POST(route.Pattern, route.HandlerFunc)
+			group.POST(route.Pattern, handlers...)
 		case "PUT":
-			group.PUT(route.Pattern, route.HandlerFunc)
+			group.PUT(route.Pattern, handlers...)
 		case "PATCH":
-			group.PATCH(route.Pattern, route.HandlerFunc)
+			group.PATCH(route.Pattern, handlers...)
 		case "DELETE":
-			group.DELETE(route.Pattern, route.HandlerFunc)
+			group.DELETE(route.Pattern, handlers...)
 		}
 	}
 }
@@ -42,29 +83,39 @@ func AddService(engine *gin.Engine, rdb database.ResultStore) {
 	DB = rdb
 
 	// Attach middleware to engine
-	engine.Use(GinLogger())
+	engine.Use(GinLogger(), TokenAuth())
+
+	for _, group := range serviceGroups() {
+		// /api 舊路徑不提供 OpenAPI 文件等 v1 專用路由
+		if group.Prefix != "" {
+			applyRoutes(engine.Group("/api"+group.Prefix), group.Routes)
+		}
+		applyRoutes(engine.Group(apiV1Prefix+group.Prefix), group.Routes)
+	}
 
-	queueGroup := engine.Group("/api/queue")
-	applyRoutes(queueGroup, QueueRoute())
-	historyGroup := engine.Group("/api/history")
-	applyRoutes(historyGroup, HistoryRoute())
-	prsGroup := engine.Group("/api/prs")
-	applyRoutes(prsGroup, PrsRoute())
-	downloadGroup := engine.Group("/api/download")
-	applyRoutes(downloadGroup, DownloadRoute())
+	// Prometheus 指標不屬於 REST API，掛在根路徑
+	engine.GET(metricsPath, requireRole(models.RoleViewer), gin.WrapH(metrics.Handler()))
 
 	// serve static assets under a non-conflicting prefix
-	engine.Static("/static", "./internal/server/public")
-	engine.Static("/js", "./internal/server/public/js")
+	assets := assetsFS()
+	engine.StaticFS("/static", filesOnly{http.FS(assets)})
+	if js, err := fs.Sub(assets, "js"); err == nil {
+		engine.StaticFS("/js", filesOnly{http.FS(js)})
+	}
 	// For SPA frontends, fallback to index.html for non-API routes using NoRoute.
 	// This avoids registering a catch-all wildcard route which conflicts with /api.
 	engine.NoRoute(func(c *gin.Context) {
 		// if the path begins with /api, return 404 JSON to keep API semantics
 		if strings.HasPrefix(c.Request.URL.Path, "/api") {
-			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			respondError(c, http.StatusNotFound, "not found")
 			return
 		}
 		// Serve SPA entrypoint
-		c.File("./internal/server/public/index.html")
+		index, err := fs.ReadFile(assets, "index.html")
+		if err != nil {
+			respondError(c, http.StatusNotFound, "index.html not found")
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", index)
 	})
 }
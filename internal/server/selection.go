@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"web_test/internal/logger"
+	"web_test/internal/selection"
+	"web_test/pkg/models"
+)
+
+// testSelector 依 PR 變更的檔案挑選測試，nil 表示一律執行完整測試
+var testSelector *selection.Selector
+
+// SetTestSelector 設定挑選測試使用的規則，nil 表示停用
+func SetTestSelector(s *selection.Selector) {
+	testSelector = s
+}
+
+// selectTests 依 params 中每個 PR 變更的檔案挑選測試；停用時回傳 nil（完整測試）。
+// fetch 為 false 時只使用 PR 快取，不向 GitHub 查詢
+func selectTests(ctx context.Context, params []models.TaskParams, fetch bool) *models.TestSelection {
+	if testSelector == nil {
+		return nil
+	}
+	return testSelector.Select(changedFiles(ctx, params, fetch))
+}
+
+// changedFiles 回傳每個 NF 變更的檔案。PR 快取中 head 相同時使用快取，否則在 fetch 為 true 時向 GitHub 查詢；
+// 無法取得時該 NF 的值為 nil，代表變更未知
+func changedFiles(ctx context.Context, params []models.TaskParams, fetch bool) map[string][]string {
+	changes := make(map[string][]string)
+	for _, param := range params {
+		if files, ok := changes[param.NF]; ok && files == nil {
+			continue
+		}
+		files := prChangedFiles(ctx, param, fetch)
+		if files == nil {
+			changes[param.NF] = nil
+			continue
+		}
+		changes[param.NF] = append(changes[param.NF], files...)
+	}
+	return changes
+}
+
+func prChangedFiles(ctx context.Context, param models.TaskParams, fetch bool) []string {
+	repo, ok := lookupNFRepo(param.NF)
+	if !ok {
+		return nil
+	}
+	number, err := strconv.Atoi(param.PRVersion)
+	if err != nil {
+		return nil
+	}
+	if cached, err := cachedPull(ctx, repo, number); err == nil && cached != nil &&
+		cached.ChangedFiles != nil && (param.HeadSHA == "" || cached.HeadSHA == param.HeadSHA) {
+		return cached.ChangedFiles
+	}
+	if !fetch {
+		return nil
+	}
+	files, err := ghClient.ListPullFiles(ctx, repo.Owner, repo.Repo, number)
+	if err != nil {
+		logger.WebLog.Warnf("Failed to list changed files of %s #%d, running the full test set: %v", param.NF, number, err)
+		return nil
+	}
+	cachePullFiles(ctx, repo, number, param.HeadSHA, files)
+	return files
+}
+
+// cachePullFiles 將變更檔案寫回 PR 快取，之後挑選測試時不必再向 GitHub 查詢。
+// 快取中的 head 與 headSHA 不同時不寫入，避免把舊 commit 的檔案記在新的 head 上
+func cachePullFiles(ctx context.Context, repo models.NFRepo, number int, headSHA string, files []string) {
+	entry, err := DB.GetPrCache(ctx, models.PrCacheKey(repo.Owner, repo.Repo))
+	if err != nil || entry == nil {
+		return
+	}
+	for i := range entry.PRs {
+		pr := &entry.PRs[i]
+		if pr.Number != number || (headSHA != "" && pr.HeadSHA != headSHA) {
+			continue
+		}
+		pr.ChangedFiles = files
+		if err := DB.SavePrCache(ctx, entry); err != nil {
+			logger.WebLog.Warnf("Failed to cache changed files of %s/%s#%d: %v", repo.Owner, repo.Repo, number, err)
+		}
+		return
+	}
+}
+
+// validateSelection 檢查使用者指定的測試範圍
+func validateSelection(sel *models.TestSelection) error {
+	if sel.Full {
+		sel.Tests, sel.Envs = nil, nil
+		return nil
+	}
+	if testSelector == nil {
+		return errors.New("test selection is disabled")
+	}
+	return testSelector.Validate(sel)
+}
+
+// testSelectionView 為自動挑選的結果與可選的全部測試、環境
+type testSelectionView struct {
+	Selection *models.TestSelection `json:"selection"` // nil 代表停用，一律完整測試
+	Tests     []string              `json:"tests"`
+	Envs      []string              `json:"envs"`
+}
+
+// 預覽 PR 組合會挑選的測試，供使用者確認或修改。
+// viewer 只使用 PR 快取中的變更檔案；快取沒有時需 submitter 以上的角色才向 GitHub 查詢，否則視為變更未知
+func SelectTestsHandler(c *gin.Context) {
+	var req models.RunPRRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	params, err := parseRunParams(req.Params)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	view := testSelectionView{Tests: selection.DefaultTests, Envs: selection.DefaultEnvs}
+	if testSelector != nil {
+		view.Selection = selectTests(c.Request.Context(), params, hasRole(c, models.RoleSubmitter))
+		view.Tests, view.Envs = testSelector.Tests(), testSelector.Envs()
+	}
+	c.JSON(http.StatusOK, view)
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"web_test/internal/github/githubtest"
+	"web_test/internal/selection"
+	"web_test/pkg/models"
+	"web_test/pkg/queue"
+)
+
+func TestTaskTestSelection(t *testing.T) {
+	gh := githubtest.NewServer()
+	defer gh.Close()
+	gh.AddPull("free5gc", "go-upf", githubtest.Pull{Number: 7, HeadSHA: "abc", Files: []string{"internal/pfcp/node.go"}})
+	gh.AddPull("free5gc", "go-upf", githubtest.Pull{Number: 8, HeadSHA: "def", Files: []string{"go.mod"}})
+
+	engine := newPRTestEngine(t, gh, []models.NFRepo{{NF: "upf", Owner: "free5gc", Repo: "go-upf"}})
+	oldQ, oldSelector := TaskQ, testSelector
+	t.Cleanup(func() { TaskQ, testSelector = oldQ, oldSelector })
+	TaskQ = queue.NewListQueue()
+	applyRoutes(engine.Group("/api/queue"), QueueRoute())
+	applyRoutes(engine.Group("/api/v1/queue"), QueueRoute())
+
+	s, err := selection.NewSelector([]selection.Rule{
+		{Paths: []string{"go.mod"}, Full: true},
+		{NF: "upf", Paths: []string{"internal/pfcp/"}, Tests: []string{"TestPaging"}, Envs: []string{"ulcl-mp"}},
+	}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetTestSelector(s)
+
+	w := doRequest(engine, http.MethodPost, "/api/queue/select-tests", `{"params":[["upf","7"]]}`)
+	var view testSelectionView
+	if err := json.Unmarshal(w.Body.Bytes(), &view); err != nil {
+		t.Fatalf("decode select-tests: %v (%s)", err, w.Body)
+	}
+	if view.Selection == nil || view.Selection.Full || !reflect.DeepEqual(view.Selection.Tests, []string{"TestPaging"}) ||
+		len(view.Tests) != len(selection.DefaultTests) {
+		t.Fatalf("view = %+v, want TestPaging out of the full pool", view)
+	}
+
+	run := func(body string) *models.Task {
+		t.Helper()
+		if w := doRequest(engine, http.MethodPost, "/api/queue/run-pr", body); w.Code != http.StatusOK {
+			t.Fatalf("run-pr %s: %d %s", body, w.Code, w.Body)
+		}
+		tasks, _ := TaskQ.GetTasks(context.Background())
+		return tasks[len(tasks)-1]
+	}
+
+	if sel := run(`{"params":[["upf","7"]]}`).Selection; sel == nil || sel.Manual || !reflect.DeepEqual(sel.Envs, []string{"ulcl-mp"}) {
+		t.Errorf("auto selection = %+v, want TestPaging on ulcl-mp", sel)
+	}
+	if sel := run(`{"params":[["upf","7"],["upf","8"]]}`).Selection; sel == nil || !sel.Full {
+		t.Errorf("selection with go.mod change = %+v, want full", sel)
+	}
+	override := `{"params":[["upf","7"]],"selection":{"tests":["TestRegistration","TestPaging"],"envs":["ulcl-ti"]}}`
+	if sel := run(override).Selection; sel == nil || !sel.Manual || len(sel.Tests) != 2 {
+		t.Errorf("manual selection = %+v", sel)
+	}
+
+	// /api/v1 回傳固定欄位，舊路徑另外保留 reply 訊息
+	var v1 map[string]json.RawMessage
+	w = doRequest(engine, http.MethodPost, "/api/v1/queue/run-pr", `{"params":[["upf","7"]]}`)
+	if err := json.Unmarshal(w.Body.Bytes(), &v1); err != nil || string(v1["status"]) != `"queued"` || v1["task_id"] == nil || v1["reply"] != nil {
+		t.Errorf("v1 run-pr = %d %s, want task_id and status without reply", w.Code, w.Body)
+	}
+	var legacy legacyRunPRResponse
+	w = doRequest(engine, http.MethodPost, "/api/queue/run-pr", `{"params":[["upf","7"]]}`)
+	if err := json.Unmarshal(w.Body.Bytes(), &legacy); err != nil || legacy.Reply == "" || legacy.TaskID == "" {
+		t.Errorf("legacy run-pr = %d %s, want reply and task_id", w.Code, w.Body)
+	}
+
+	bad := `{"params":[["upf","7"]],"selection":{"tests":["TestPaging; reboot"]}}`
+	if w := doRequest(engine, http.MethodPost, "/api/queue/run-pr", bad); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown test: %d, want 400", w.Code)
+	}
+}
+
+func TestSelectTestsViewerUsesCache(t *testing.T) {
+	gh := githubtest.NewServer()
+	defer gh.Close()
+	gh.AddPull("free5gc", "go-upf", githubtest.Pull{Number: 7, HeadSHA: "abc", Files: []string{"internal/pfcp/node.go"}})
+	newPRTestEngine(t, gh, []models.NFRepo{{NF: "upf", Owner: "free5gc", Repo: "go-upf"}})
+	if err := refreshPrCache(context.Background(), "free5gc", "go-upf", false); err != nil {
+		t.Fatal(err)
+	}
+
+	oldSelector, oldEnabled, oldAdmin := testSelector, authEnabled, adminToken
+	t.Cleanup(func() {
+		testSelector = oldSelector
+		SetAuthConfig(oldEnabled, oldAdmin)
+	})
+	s, err := selection.NewSelector([]selection.Rule{
+		{NF: "upf", Paths: []string{"internal/pfcp/"}, Tests: []string{"TestPaging"}},
+	}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetTestSelector(s)
+	SetAuthConfig(true, "")
+	// 以 X-Role 代替 token 設定請求的角色
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) { c.Set(ctxRoleKey, c.GetHeader("X-Role")) })
+	applyRoutes(engine.Group("/api/queue"), QueueRoute())
+
+	preview := func(role string) *models.TestSelection {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/queue/select-tests", strings.NewReader(`{"params":[["upf","7"]]}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Role", role)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		var view testSelectionView
+		if err := json.Unmarshal(w.Body.Bytes(), &view); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("select-tests as %s: %d %s", role, w.Code, w.Body)
+		}
+		return view.Selection
+	}
+
+	// viewer 不向 GitHub 查詢，快取沒有變更檔案時視為未知
+	if sel := preview(models.RoleViewer); sel == nil || !sel.Full {
+		t.Errorf("viewer selection = %+v, want full", sel)
+	}
+	if n := countRequests(gh.Requests(), "/files"); n != 0 {
+		t.Fatalf("viewer preview made %d file requests", n)
+	}
+
+	// submitter 可向 GitHub 查詢並寫回快取，之後 viewer 也能使用
+	for _, role := range []string{models.RoleSubmitter, models.RoleViewer} {
+		if sel := preview(role); sel == nil || sel.Full || !reflect.DeepEqual(sel.Tests, []string{"TestPaging"}) {
+			t.Errorf("%s selection = %+v, want TestPaging", role, sel)
+		}
+	}
+	if n := countRequests(gh.Requests(), "/files"); n != 1 {
+		t.Errorf("file requests = %d, want 1", n)
+	}
+}
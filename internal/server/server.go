@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"web_test/internal/logger"
+	"web_test/internal/metrics"
 	"web_test/pkg/database"
 	"web_test/pkg/queue"
 
@@ -44,6 +45,8 @@ func NewWebServer(port string, database database.ResultStore, taskQueue queue.Ta
 
 	// 註冊路由
 	ws.setupRoutes()
+	// 關閉時結束 /api/events 的長連線，否則 Shutdown 會等到逾時
+	ws.server.RegisterOnShutdown(closeEventStreams)
 
 	return ws
 }
@@ -53,6 +56,9 @@ func (ws *WebServer) setupRoutes() {
 	ws.engine.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
+	// Kubernetes 等使用的 liveness / readiness probe，不需要 token
+	ws.engine.GET("/livez", LivezHandler)
+	ws.engine.GET("/readyz", ReadyzHandler)
 
 	// 使用 AddService 註冊所有 API 路由
 	AddService(ws.engine, ws.database)
@@ -87,3 +93,25 @@ func (ws *WebServer) Stop() {
 		logger.MainLog.Errorf("Could not close web server: %v", err)
 	}
 }
+
+// NewProbeServer 建立只提供 /livez、/readyz 與 /metrics 的伺服器，供只執行 executor（沒有 web server）的主機監控；
+// 不經過 token 驗證，請只開放給內部網路
+func NewProbeServer(port string, database database.ResultStore) *WebServer {
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+	ws := &WebServer{
+		port:     port,
+		engine:   engine,
+		database: database,
+		server: &http.Server{
+			Addr:    ":" + port,
+			Handler: engine,
+		},
+	}
+	DB = database
+
+	engine.GET("/livez", LivezHandler)
+	engine.GET("/readyz", ReadyzHandler)
+	engine.GET(metricsPath, gin.WrapH(metrics.Handler()))
+	return ws
+}
@@ -3,9 +3,44 @@ package server
 import (
 	"context"
 	"fmt"
+	"time"
 	"web_test/internal/logger"
+
+	"github.com/gin-gonic/gin"
 )
 
+// displayLocation 為 API 輸出時間字串的預設時區
+var displayLocation = time.UTC
+
+const displayTimeLayout = "2006-01-02 15:04:05"
+
+// SetDisplayTimezone 設定 API 輸出時間字串的預設時區（IANA 名稱，例如 Asia/Taipei）
+func SetDisplayTimezone(name string) error {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return err
+	}
+	displayLocation = loc
+	return nil
+}
+
+// requestLocation 回傳請求以 ?tz= 指定的時區，未指定時使用預設時區
+func requestLocation(c *gin.Context) (*time.Location, error) {
+	name := c.Query("tz")
+	if name == "" {
+		return displayLocation, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// formatUnix 將 UTC Unix 秒轉為指定時區的顯示字串，0 代表未知
+func formatUnix(ts int64, loc *time.Location) string {
+	if ts == 0 {
+		return ""
+	}
+	return time.Unix(ts, 0).In(loc).Format(displayTimeLayout)
+}
+
 // GenerateUniqueTaskID generates a unique task ID using a Redis counter.
 // It increments a counter in Redis and returns the new value.
 func GenerateUniqueTaskID() (int, error) {
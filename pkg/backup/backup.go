@@ -0,0 +1,282 @@
+// Package backup exports and restores all CI data held in a database.ResultStore
+// as a single versioned zip archive, independent of the storage backend.
+package backup
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"web_test/pkg/database"
+	"web_test/pkg/models"
+)
+
+const (
+	// FormatName identifies web_test archives in the manifest.
+	FormatName = "web_test-backup"
+	// FormatVersion is the archive layout written by Export.
+	// Import accepts archives up to this version.
+	//   1: pr_cache.json holds the single global PR list
+	//   2: pr_cache.json holds one PrCacheEntry per owner/repo
+	//   3: adds tokens.json, audit.json and test_durations.json
+	FormatVersion = 3
+)
+
+// Archive entry names.
+const (
+	manifestFile = "manifest.json"
+	resultsFile  = "results.json"
+	historyFile  = "history.json"
+	prCacheFile  = "pr_cache.json"
+	tokensFile   = "tokens.json"
+	auditFile    = "audit.json"
+	durationFile = "test_durations.json"
+)
+
+// ErrInvalidArchive is wrapped by every Import error caused by the archive itself
+// (not a zip, wrong format or version, missing or undecodable entries), as opposed
+// to errors writing to the store. Import reports it before touching the store.
+var ErrInvalidArchive = errors.New("invalid archive")
+
+// Manifest describes the content of an archive.
+type Manifest struct {
+	Format        string `json:"format"`
+	Version       int    `json:"version"`
+	CreatedAt     int64  `json:"created_at"`
+	Results       int    `json:"results"`
+	History       int    `json:"history"`
+	TaskIDCounter int    `json:"task_id_counter"`
+	PrCacheRepos  int    `json:"pr_cache_repos"`
+	Tokens        int    `json:"tokens"`
+	Audit         int    `json:"audit"`
+	TestDurations int    `json:"test_durations"`
+}
+
+// Export writes every result, the full history, the task ID counter, the
+// PR cache, the API tokens, the audit log and the recorded test durations of
+// store to w. Task logs are part of the results; tokens are stored as hashes only.
+func Export(ctx context.Context, store database.ResultStore, w io.Writer) (*Manifest, error) {
+	results, err := store.ListResults(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list results: %w", err)
+	}
+	history, err := store.GetHistory(ctx, 0, -1)
+	if err != nil {
+		return nil, fmt.Errorf("read history: %w", err)
+	}
+	counter, err := store.GetTaskIDCounter(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read task ID counter: %w", err)
+	}
+	prCache, err := store.ListPrCache(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read PR cache: %w", err)
+	}
+	tokens, err := store.ListTokens(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list tokens: %w", err)
+	}
+	audit, err := store.GetAudit(ctx, 0, -1)
+	if err != nil {
+		return nil, fmt.Errorf("read audit log: %w", err)
+	}
+	durations, err := store.GetTestDurations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read test durations: %w", err)
+	}
+
+	manifest := &Manifest{
+		Format:        FormatName,
+		Version:       FormatVersion,
+		CreatedAt:     time.Now().Unix(),
+		Results:       len(results),
+		History:       len(history),
+		TaskIDCounter: counter,
+		PrCacheRepos:  len(prCache),
+		Tokens:        len(tokens),
+		Audit:         len(audit),
+		TestDurations: len(durations),
+	}
+
+	zw := zip.NewWriter(w)
+	if err := writeJSON(zw, manifestFile, manifest); err != nil {
+		return nil, err
+	}
+	if err := writeJSON(zw, resultsFile, results); err != nil {
+		return nil, err
+	}
+	if err := writeJSON(zw, historyFile, history); err != nil {
+		return nil, err
+	}
+	if err := writeJSON(zw, prCacheFile, prCache); err != nil {
+		return nil, err
+	}
+	if err := writeJSON(zw, tokensFile, tokens); err != nil {
+		return nil, err
+	}
+	if err := writeJSON(zw, auditFile, audit); err != nil {
+		return nil, err
+	}
+	if err := writeJSON(zw, durationFile, durations); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("close archive: %w", err)
+	}
+	return manifest, nil
+}
+
+// Import restores an archive produced by Export into store.
+// It is idempotent: results are overwritten by task ID, the history is replaced,
+// the task ID counter is only ever raised, PR cache entries are overwritten by repo,
+// tokens by ID and test durations by name, and the audit log is replaced.
+// The version 1 global PR cache has no repository key and is skipped; archives
+// before version 3 leave tokens, the audit log and test durations untouched.
+func Import(ctx context.Context, store database.ResultStore, r io.ReaderAt, size int64) (*Manifest, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: open archive: %w", ErrInvalidArchive, err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var manifest Manifest
+	if err := readJSON(files, manifestFile, &manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Format != FormatName {
+		return nil, fmt.Errorf("%w: not a %s archive (format %q)", ErrInvalidArchive, FormatName, manifest.Format)
+	}
+	if manifest.Version < 1 || manifest.Version > FormatVersion {
+		return nil, fmt.Errorf("%w: unsupported archive version %d (supported: 1..%d)", ErrInvalidArchive, manifest.Version, FormatVersion)
+	}
+
+	// Decode every entry first so a broken archive leaves the store untouched.
+	var (
+		results   []*models.TaskResult
+		history   []*models.HistoryRecord
+		prCache   []*models.PrCacheEntry
+		tokens    []*models.APIToken
+		audit     []*models.AuditEntry
+		durations map[string]int64
+	)
+	if err := readJSON(files, resultsFile, &results); err != nil {
+		return nil, err
+	}
+	if err := readJSON(files, historyFile, &history); err != nil {
+		return nil, err
+	}
+	if manifest.Version >= 2 {
+		if err := readJSON(files, prCacheFile, &prCache); err != nil {
+			return nil, err
+		}
+	}
+	if manifest.Version >= 3 {
+		if err := readJSON(files, tokensFile, &tokens); err != nil {
+			return nil, err
+		}
+		if err := readJSON(files, auditFile, &audit); err != nil {
+			return nil, err
+		}
+		if err := readJSON(files, durationFile, &durations); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, result := range results {
+		if err := store.RestoreResult(ctx, result); err != nil {
+			return nil, fmt.Errorf("restore result %s: %w", result.TaskID, err)
+		}
+	}
+	if err := store.ReplaceHistory(ctx, history); err != nil {
+		return nil, fmt.Errorf("restore history: %w", err)
+	}
+	if err := store.RestoreTaskIDCounter(ctx, manifest.TaskIDCounter); err != nil {
+		return nil, fmt.Errorf("restore task ID counter: %w", err)
+	}
+	for _, entry := range prCache {
+		if err := store.SavePrCache(ctx, entry); err != nil {
+			return nil, fmt.Errorf("restore PR cache %s: %w", entry.Key(), err)
+		}
+	}
+	if manifest.Version >= 3 {
+		if err := importV3(ctx, store, tokens, audit, durations); err != nil {
+			return nil, err
+		}
+	}
+	return &manifest, nil
+}
+
+// importV3 restores the tokens, audit log and test durations added in version 3.
+func importV3(ctx context.Context, store database.ResultStore, tokens []*models.APIToken, audit []*models.AuditEntry, durations map[string]int64) error {
+	for _, token := range tokens {
+		if err := store.SaveToken(ctx, token); err != nil {
+			return fmt.Errorf("restore token %s: %w", token.ID, err)
+		}
+	}
+	// The audit log is append-only: clear it and append the entries oldest first,
+	// so importing the same archive again leaves the same log.
+	if err := store.TrimAudit(ctx, 0); err != nil {
+		return fmt.Errorf("clear audit log: %w", err)
+	}
+	for i := len(audit) - 1; i >= 0; i-- {
+		if err := store.AppendAudit(ctx, audit[i]); err != nil {
+			return fmt.Errorf("restore audit log: %w", err)
+		}
+	}
+	for name, seconds := range durations {
+		if err := store.SaveTestDuration(ctx, name, seconds); err != nil {
+			return fmt.Errorf("restore test duration %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func writeJSON(zw *zip.Writer, name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", name, err)
+	}
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+	if err != nil {
+		return fmt.Errorf("create %s: %w", name, err)
+	}
+	if _, err := fw.Write(data); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+func readJSON(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("%w: archive is missing %s", ErrInvalidArchive, name)
+	}
+	data, err := readAll(f)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: decode %s: %w", ErrInvalidArchive, name, err)
+	}
+	return nil
+}
+
+func readAll(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", f.Name, err)
+	}
+	return data, nil
+}
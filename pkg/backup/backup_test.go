@@ -0,0 +1,147 @@
+package backup_test
+
+import (
+	"errors"
+	"archive/zip"
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"web_test/pkg/backup"
+	"web_test/pkg/database"
+	"web_test/pkg/models"
+)
+
+// TestRoundTripAcrossBackends exports from the memory backend and imports into
+// Redis twice, checking that the restore converts backends and is idempotent.
+func TestRoundTripAcrossBackends(t *testing.T) {
+	ctx := context.Background()
+	src := database.NewMemoryDB()
+	for i := 0; i < 3; i++ {
+		if _, err := src.IncrementTaskID(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	params := []models.TaskParams{{NF: "smf", PRVersion: "34"}}
+	steps := []*models.TaskResult{
+		{TaskID: "1", Status: models.StatusRunning, Params: params},
+		{TaskID: "1", Status: models.StatusFailed, Params: params, FailedTests: []string{"TestPaging"}, Logs: []string{"boom"}},
+		{TaskID: "2", Status: models.StatusRunning, Params: params},
+		{TaskID: "2", Status: models.StatusSuccess, Params: params},
+		{TaskID: "3", Status: models.StatusRunning, Params: params},
+	}
+	for _, result := range steps {
+		if err := src.SaveResult(ctx, result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entry := &models.PrCacheEntry{Owner: "free5gc", Repo: "smf", PRs: []models.PullRequest{{Number: 34, Title: "fix"}}, ETag: `"abc"`}
+	if err := src.SavePrCache(ctx, entry); err != nil {
+		t.Fatal(err)
+	}
+	token := &models.APIToken{ID: "0123456789abcdef", Name: "ci-bot", Role: models.RoleSubmitter, Hash: "deadbeef", CreatedAt: 10}
+	if err := src.SaveToken(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+	for i, action := range []string{models.AuditEnqueue, models.AuditConfig} {
+		if err := src.AppendAudit(ctx, &models.AuditEntry{At: int64(i + 1), Actor: "alice", Action: action}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := src.SaveTestDuration(ctx, "TestPaging", 42); err != nil {
+		t.Fatal(err)
+	}
+
+	var archive bytes.Buffer
+	manifest, err := backup.Export(ctx, src, &archive)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if manifest.Results != 3 || manifest.History != 2 || manifest.TaskIDCounter != 3 || manifest.PrCacheRepos != 1 ||
+		manifest.Tokens != 1 || manifest.Audit != 2 || manifest.TestDurations != 1 {
+		t.Fatalf("manifest = %+v", manifest)
+	}
+
+	dst := database.NewRedisDB(miniredis.RunT(t).Addr(), "", 0)
+	for i := 0; i < 2; i++ {
+		data := archive.Bytes()
+		if _, err := backup.Import(ctx, dst, bytes.NewReader(data), int64(len(data))); err != nil {
+			t.Fatalf("Import #%d: %v", i+1, err)
+		}
+	}
+
+	got, err := dst.GetResult(ctx, "1")
+	if err != nil || got == nil || got.Status != models.StatusFailed || len(got.Logs) != 1 || got.Logs[0] != "boom" {
+		t.Fatalf("restored result 1 = %+v, %v", got, err)
+	}
+	history, err := dst.GetHistory(ctx, 0, -1)
+	if err != nil || len(history) != 2 || history[0].TaskName != "Test Task 2" {
+		t.Fatalf("restored history = %+v, %v", history, err)
+	}
+	running, err := dst.GetRunningTasks(ctx)
+	if err != nil || len(running) != 1 || running[0].TaskID != "3" {
+		t.Fatalf("restored running tasks = %+v, %v", running, err)
+	}
+	if id, err := dst.IncrementTaskID(ctx); err != nil || id != 4 {
+		t.Fatalf("IncrementTaskID after import = %d, %v, want 4", id, err)
+	}
+	if cache, err := dst.GetPrCache(ctx, "free5gc/smf"); err != nil || cache == nil || cache.ETag != `"abc"` || len(cache.PRs) != 1 {
+		t.Fatalf("restored PR cache = %+v, %v", cache, err)
+	}
+	if got, err := dst.GetToken(ctx, token.ID); err != nil || got == nil || *got != *token {
+		t.Fatalf("restored token = %+v, %v, want %+v", got, err, token)
+	}
+	audit, err := dst.GetAudit(ctx, 0, -1)
+	if err != nil || len(audit) != 2 || audit[0].Action != models.AuditConfig || audit[1].Action != models.AuditEnqueue {
+		t.Fatalf("restored audit log = %+v, %v, want both entries once, newest first", audit, err)
+	}
+	if durations, err := dst.GetTestDurations(ctx); err != nil || durations["TestPaging"] != 42 {
+		t.Fatalf("restored test durations = %v, %v", durations, err)
+	}
+}
+
+func TestImportRejectsForeignArchive(t *testing.T) {
+	_, err := backup.Import(context.Background(), database.NewMemoryDB(), bytes.NewReader([]byte("not a zip")), 9)
+	if !errors.Is(err, backup.ErrInvalidArchive) {
+		t.Fatalf("Import of a non-zip payload = %v, want ErrInvalidArchive", err)
+	}
+}
+
+// TestImportVersion2 restores an archive written before tokens, the audit log
+// and test durations were exported, leaving those untouched.
+func TestImportVersion2(t *testing.T) {
+	ctx := context.Background()
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	for name, content := range map[string]string{
+		"manifest.json": `{"format":"web_test-backup","version":2,"task_id_counter":5}`,
+		"results.json":  `[{"task_id":"5","status":"Success"}]`,
+		"history.json":  `[]`,
+		"pr_cache.json": `[{"owner":"free5gc","repo":"amf"}]`,
+	} {
+		fw, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := database.NewMemoryDB()
+	dst.AppendAudit(ctx, &models.AuditEntry{At: 1, Actor: "alice", Action: models.AuditConfig})
+	data := archive.Bytes()
+	manifest, err := backup.Import(ctx, dst, bytes.NewReader(data), int64(len(data)))
+	if err != nil || manifest.Version != 2 {
+		t.Fatalf("Import v2 = %+v, %v", manifest, err)
+	}
+	if got, _ := dst.GetResult(ctx, "5"); got == nil {
+		t.Error("v2 result not restored")
+	}
+	if audit, _ := dst.GetAudit(ctx, 0, -1); len(audit) != 1 {
+		t.Errorf("v2 import changed the audit log: %+v", audit)
+	}
+}
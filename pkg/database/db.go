@@ -2,12 +2,21 @@ package database
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"web_test/pkg/models"
 )
 
+// ErrIllegalTransition 表示 SaveResult 的狀態轉移不符合 models 定義的狀態機
+var ErrIllegalTransition = errors.New("illegal task status transition")
+
+func illegalTransition(taskID, from, to string) error {
+	return fmt.Errorf("%w: task %s %q -> %q", ErrIllegalTransition, taskID, from, to)
+}
+
 // ResultStore 定義結果儲存介面
 type ResultStore interface {
-	// 儲存任務結果
+	// 儲存任務結果，狀態轉移須符合 models.CanTransition，並以原子操作更新執行中集合與歷史紀錄
 	SaveResult(ctx context.Context, result *models.TaskResult) error
 	// 取得任務結果
 	GetResult(ctx context.Context, taskID string) (*models.TaskResult, error)
@@ -21,10 +30,65 @@ type ResultStore interface {
 	SaveHistory(ctx context.Context, record *models.HistoryRecord) error
 	// 取得所有任務歷史紀錄
 	GetHistory(ctx context.Context, start, end int64) ([]*models.HistoryRecord, error)
-	// 儲存PR快取
-	SavePrCache(ctx context.Context, Prs []byte) error
-	// 取得PR快取
-	GetPrCache(ctx context.Context) ([]byte, error)
-	// 清除PR快取
-	ClearPrCache(ctx context.Context) error
+	// 只保留最新的 n 筆歷史紀錄；新紀錄加在最前面，因此可與 SaveResult 同時執行
+	TrimHistory(ctx context.Context, n int64) error
+	// 儲存單一 owner/repo 的 PR 快取（覆寫同一 repo 的舊資料）
+	SavePrCache(ctx context.Context, entry *models.PrCacheEntry) error
+	// 取得 owner/repo 的 PR 快取，不存在時回傳 nil, nil
+	GetPrCache(ctx context.Context, repo string) (*models.PrCacheEntry, error)
+	// 列出所有 repo 的 PR 快取
+	ListPrCache(ctx context.Context) ([]*models.PrCacheEntry, error)
+	// 清除 owner/repo 的 PR 快取，repo 為空字串時清除全部
+	ClearPrCache(ctx context.Context, repo string) error
+	// 刪除舊版以單一 key 儲存的全域 PR 快取（沒有 owner/repo，無法轉成各 repo 的快取），回傳是否存在
+	DeleteLegacyPrCache(ctx context.Context) (bool, error)
+
+	// 儲存 API token（以 ID 覆寫）
+	SaveToken(ctx context.Context, token *models.APIToken) error
+	// 取得 API token，不存在時回傳 nil, nil
+	GetToken(ctx context.Context, id string) (*models.APIToken, error)
+	// 列出所有 API token
+	ListTokens(ctx context.Context) ([]*models.APIToken, error)
+	// 刪除 API token，不存在時不視為錯誤
+	DeleteToken(ctx context.Context, id string) error
+
+	// 新增一筆稽核紀錄；稽核紀錄只能新增
+	AppendAudit(ctx context.Context, entry *models.AuditEntry) error
+	// 取得稽核紀錄，最新在前，start/end 與 GetHistory 相同為包含兩端的索引
+	GetAudit(ctx context.Context, start, end int64) ([]*models.AuditEntry, error)
+	// 只保留最新的 n 筆稽核紀錄，供資料清理刪除過期的紀錄
+	TrimAudit(ctx context.Context, n int64) error
+
+	// 儲存執行中任務的進度（以任務 ID 覆寫）
+	SaveProgress(ctx context.Context, progress *models.ProgressInfo) error
+	// 取得任務進度，不存在時回傳 nil, nil
+	GetProgress(ctx context.Context, taskID string) (*models.ProgressInfo, error)
+	// 刪除任務進度，不存在時不視為錯誤
+	DeleteProgress(ctx context.Context, taskID string) error
+	// 記錄測試或 ULCL 環境的執行秒數，供預估剩餘時間
+	SaveTestDuration(ctx context.Context, name string, seconds int64) error
+	// 取得所有測試的執行秒數
+	GetTestDurations(ctx context.Context) (map[string]int64, error)
+
+	// 要求取消執行中的任務，actor 為要求取消的身分；executor 可能在另一台主機，因此經由 ResultStore 傳遞
+	RequestCancel(ctx context.Context, taskID, actor string) error
+	// 取得要求取消任務的身分，沒有要求時回傳空字串
+	GetCancelRequest(ctx context.Context, taskID string) (string, error)
+	// 清除取消要求，不存在時不視為錯誤
+	ClearCancel(ctx context.Context, taskID string) error
+
+	// 檢查後端是否可用，供 readiness 檢查
+	Ping(ctx context.Context) error
+
+	// 以下供備份與還原使用，不經過狀態機檢查
+	// 列出所有任務結果
+	ListResults(ctx context.Context) ([]*models.TaskResult, error)
+	// 直接寫入任務結果並同步執行中集合，不新增歷史紀錄
+	RestoreResult(ctx context.Context, result *models.TaskResult) error
+	// 以 records 取代全部歷史紀錄（順序與 GetHistory 相同，最新在前）
+	ReplaceHistory(ctx context.Context, records []*models.HistoryRecord) error
+	// 取得目前的任務 ID 計數
+	GetTaskIDCounter(ctx context.Context) (int, error)
+	// 將任務 ID 計數提高到至少 n，不會往回調
+	RestoreTaskIDCounter(ctx context.Context, n int) error
 }
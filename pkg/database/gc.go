@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+
+	"web_test/pkg/models"
+)
+
+// GCStats 為一次資料清理移除（或 dry run 時會移除）的筆數
+type GCStats struct {
+	Results int // 任務結果與其 log
+	History int // 歷史紀錄
+	PrCache int // PR 快取
+	Audit   int // 稽核紀錄
+}
+
+// CollectGarbage 清除在 before（UTC Unix 秒）之前結束的任務結果與歷史紀錄，
+// 以及在 before 之前就已過期、之後沒有再更新的 PR 快取。執行中與排隊中的任務不受影響；
+// 尚未遷移、沒有結束時間的歷史紀錄會保留。稽核紀錄另以 auditBefore 決定保留期限，0 表示全部保留。
+// dryRun 時只計算筆數不刪除，可重複執行。
+func CollectGarbage(ctx context.Context, store ResultStore, before, auditBefore int64, dryRun bool) (GCStats, error) {
+	var stats GCStats
+
+	results, err := store.ListResults(ctx)
+	if err != nil {
+		return stats, err
+	}
+	for _, result := range results {
+		if !models.IsTerminalStatus(result.Status) || finishedAt(result) >= before {
+			continue
+		}
+		if !dryRun {
+			if err := store.DeleteResult(ctx, result.TaskID, result.Status); err != nil {
+				return stats, err
+			}
+		}
+		stats.Results++
+	}
+
+	// 歷史紀錄最新在前，從第一筆過期的紀錄開始截斷；新紀錄加在最前面，截斷不會影響執行中的任務
+	history, err := store.GetHistory(ctx, 0, -1)
+	if err != nil {
+		return stats, err
+	}
+	keep := int64(len(history))
+	for i, record := range history {
+		if record.FinishedAt != 0 && record.FinishedAt < before {
+			keep = int64(i)
+			break
+		}
+	}
+	if removed := int64(len(history)) - keep; removed > 0 {
+		if !dryRun {
+			if err := store.TrimHistory(ctx, keep); err != nil {
+				return stats, err
+			}
+		}
+		stats.History = int(removed)
+	}
+
+	entries, err := store.ListPrCache(ctx)
+	if err != nil {
+		return stats, err
+	}
+	for _, entry := range entries {
+		if !entry.Expired(before) {
+			continue
+		}
+		if !dryRun {
+			if err := store.ClearPrCache(ctx, entry.Key()); err != nil {
+				return stats, err
+			}
+		}
+		stats.PrCache++
+	}
+
+	if auditBefore > 0 {
+		// 稽核紀錄與歷史紀錄相同，最新在前，從第一筆過期的紀錄開始截斷
+		audit, err := store.GetAudit(ctx, 0, -1)
+		if err != nil {
+			return stats, err
+		}
+		keep := int64(len(audit))
+		for i, entry := range audit {
+			if entry.At < auditBefore {
+				keep = int64(i)
+				break
+			}
+		}
+		if removed := int64(len(audit)) - keep; removed > 0 {
+			if !dryRun {
+				if err := store.TrimAudit(ctx, keep); err != nil {
+					return stats, err
+				}
+			}
+			stats.Audit = int(removed)
+		}
+	}
+	return stats, nil
+}
+
+// finishedAt 回傳任務的結束時間，舊資料沒有 FinishedAt 時使用最後更新時間
+func finishedAt(result *models.TaskResult) int64 {
+	if result.FinishedAt != 0 {
+		return result.FinishedAt
+	}
+	return result.Timestamp
+}
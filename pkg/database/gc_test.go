@@ -0,0 +1,72 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"web_test/pkg/database"
+	"web_test/pkg/models"
+)
+
+func TestCollectGarbage(t *testing.T) {
+	ctx := context.Background()
+	store := database.NewMemoryDB()
+
+	for _, result := range []*models.TaskResult{
+		{TaskID: "1", Status: models.StatusFailed, FinishedAt: 100, Timestamp: 100},
+		{TaskID: "2", Status: models.StatusSuccess, Timestamp: 150}, // 舊資料沒有 FinishedAt
+		{TaskID: "3", Status: models.StatusSuccess, FinishedAt: 300, Timestamp: 300},
+		{TaskID: "4", Status: models.StatusRunning, Timestamp: 50},
+	} {
+		if err := store.RestoreResult(ctx, result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	history := []*models.HistoryRecord{
+		{TaskID: "3", FinishedAt: 300},
+		{TaskID: "2", FinishedAt: 150},
+		{TaskID: "1", FinishedAt: 100},
+	}
+	if err := store.ReplaceHistory(ctx, history); err != nil {
+		t.Fatal(err)
+	}
+	store.SavePrCache(ctx, &models.PrCacheEntry{Owner: "free5gc", Repo: "amf", ExpiresAt: 100})
+	store.SavePrCache(ctx, &models.PrCacheEntry{Owner: "free5gc", Repo: "smf", ExpiresAt: 400})
+	for _, at := range []int64{50, 80, 120} {
+		store.AppendAudit(ctx, &models.AuditEntry{At: at, Actor: "alice", Action: models.AuditEnqueue})
+	}
+
+	// 稽核紀錄的保留期限與任務結果分開
+	want := database.GCStats{Results: 2, History: 2, PrCache: 1, Audit: 2}
+	stats, err := database.CollectGarbage(ctx, store, 200, 100, true)
+	if err != nil || stats != want {
+		t.Fatalf("dry run = %+v, %v, want %+v", stats, err, want)
+	}
+	if results, _ := store.ListResults(ctx); len(results) != 4 {
+		t.Fatalf("dry run removed results, %d left", len(results))
+	}
+
+	stats, err = database.CollectGarbage(ctx, store, 200, 100, false)
+	if err != nil || stats != want {
+		t.Fatalf("CollectGarbage = %+v, %v, want %+v", stats, err, want)
+	}
+	for id, exists := range map[string]bool{"1": false, "2": false, "3": true, "4": true} {
+		result, err := store.GetResult(ctx, id)
+		if err != nil || (result != nil) != exists {
+			t.Errorf("result %s after gc = %+v, %v, want exists %v", id, result, err, exists)
+		}
+	}
+	if got, _ := store.GetHistory(ctx, 0, -1); len(got) != 1 || got[0].TaskID != "3" {
+		t.Errorf("history after gc = %+v, want only task 3", got)
+	}
+	if entries, _ := store.ListPrCache(ctx); len(entries) != 1 || entries[0].Repo != "smf" {
+		t.Errorf("PR cache after gc = %+v, want only smf", entries)
+	}
+	if entries, _ := store.GetAudit(ctx, 0, -1); len(entries) != 1 || entries[0].At != 120 {
+		t.Errorf("audit after gc = %+v, want only the entry at 120", entries)
+	}
+
+	if stats, err := database.CollectGarbage(ctx, store, 200, 0, false); err != nil || stats != (database.GCStats{}) {
+		t.Errorf("second gc = %+v, %v, want nothing removed", stats, err)
+	}
+}
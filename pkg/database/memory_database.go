@@ -0,0 +1,499 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"web_test/pkg/models"
+)
+
+// MemoryDB implements the ResultStore interface in process memory.
+// It mirrors the semantics of RedisDB and is meant for tests and single-host development.
+type MemoryDB struct {
+	mu        sync.RWMutex
+	results   map[string][]byte
+	running   map[string]struct{}
+	history   [][]byte // newest first, like LPUSH
+	taskID    int
+	prCache   map[string][]byte // owner/repo -> PrCacheEntry JSON
+	tokens    map[string][]byte // token ID -> APIToken JSON
+	audit     [][]byte          // newest first, like history
+	progress  map[string][]byte // task ID -> ProgressInfo JSON
+	durations map[string]int64  // test name -> seconds
+	cancels   map[string]string // task ID -> actor who requested the cancel
+}
+
+// NewMemoryDB creates an empty MemoryDB.
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{
+		results:   make(map[string][]byte),
+		running:   make(map[string]struct{}),
+		prCache:   make(map[string][]byte),
+		tokens:    make(map[string][]byte),
+		progress:  make(map[string][]byte),
+		durations: make(map[string]int64),
+		cancels:   make(map[string]string),
+	}
+}
+
+// SaveResult stores a task result and updates the running set and history.
+// Like RedisDB, the status transition is validated and applied under a single lock.
+func (m *MemoryDB) SaveResult(ctx context.Context, result *models.TaskResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prev := ""
+	if cur, ok := m.results[result.TaskID]; ok {
+		var stored models.TaskResult
+		if json.Unmarshal(cur, &stored) == nil {
+			prev = stored.Status
+		}
+	}
+	if !models.CanTransition(prev, result.Status) {
+		return illegalTransition(result.TaskID, prev, result.Status)
+	}
+
+	var record []byte
+	if models.IsTerminalStatus(result.Status) {
+		if record, err = json.Marshal(newHistoryRecord(result)); err != nil {
+			return err
+		}
+	}
+
+	m.results[result.TaskID] = data
+	if result.Status == models.StatusRunning {
+		m.running[result.TaskID] = struct{}{}
+	} else {
+		delete(m.running, result.TaskID)
+	}
+	if record != nil {
+		m.history = append([][]byte{record}, m.history...)
+	}
+	return nil
+}
+
+// GetResult returns nil, nil when the task has no stored result.
+func (m *MemoryDB) GetResult(ctx context.Context, taskID string) (*models.TaskResult, error) {
+	m.mu.RLock()
+	data, ok := m.results[taskID]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, nil
+	}
+
+	var result models.TaskResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetRunningTasks returns the stored results of every task in the running set.
+func (m *MemoryDB) GetRunningTasks(ctx context.Context) ([]*models.TaskResult, error) {
+	m.mu.RLock()
+	ids := make([]string, 0, len(m.running))
+	for id := range m.running {
+		ids = append(ids, id)
+	}
+	m.mu.RUnlock()
+
+	var tasks []*models.TaskResult
+	for _, id := range ids {
+		result, err := m.GetResult(ctx, id)
+		if err != nil {
+			continue
+		}
+		if result != nil {
+			tasks = append(tasks, result)
+		}
+	}
+	return tasks, nil
+}
+
+// DeleteResult drops the running marker when status is "running", otherwise the stored result.
+func (m *MemoryDB) DeleteResult(ctx context.Context, taskID string, status string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if status == models.StatusRunning {
+		delete(m.running, taskID)
+	} else {
+		delete(m.results, taskID)
+	}
+	return nil
+}
+
+// IncrementTaskID returns the next task ID, starting from 1.
+func (m *MemoryDB) IncrementTaskID(ctx context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.taskID++
+	return m.taskID, nil
+}
+
+// SaveHistory prepends a history record.
+func (m *MemoryDB) SaveHistory(ctx context.Context, record *models.HistoryRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.history = append([][]byte{data}, m.history...)
+	m.mu.Unlock()
+	return nil
+}
+
+// GetHistory returns the records between start and end inclusive, using LRANGE index rules.
+func (m *MemoryDB) GetHistory(ctx context.Context, start, end int64) ([]*models.HistoryRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	from, to, ok := rangeBounds(int64(len(m.history)), start, end)
+	history := make([]*models.HistoryRecord, 0)
+	if !ok {
+		return history, nil
+	}
+	for _, item := range m.history[from : to+1] {
+		var record models.HistoryRecord
+		if err := json.Unmarshal(item, &record); err != nil {
+			continue
+		}
+		history = append(history, &record)
+	}
+	return history, nil
+}
+
+// SavePrCache saves the PR cache entry of one repository.
+func (m *MemoryDB) SavePrCache(ctx context.Context, entry *models.PrCacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prCache[entry.Key()] = data
+	return nil
+}
+
+// GetPrCache returns nil, nil when owner/repo is not cached.
+func (m *MemoryDB) GetPrCache(ctx context.Context, repo string) (*models.PrCacheEntry, error) {
+	m.mu.RLock()
+	data, ok := m.prCache[repo]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, nil
+	}
+	var entry models.PrCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// ListPrCache returns the PR cache entries of every repository.
+func (m *MemoryDB) ListPrCache(ctx context.Context) ([]*models.PrCacheEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entries := make([]*models.PrCacheEntry, 0, len(m.prCache))
+	for _, data := range m.prCache {
+		var entry models.PrCacheEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			continue
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, nil
+}
+
+// ClearPrCache removes the cached PR data of owner/repo, or of every repository when repo is empty.
+func (m *MemoryDB) ClearPrCache(ctx context.Context, repo string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if repo == "" {
+		m.prCache = make(map[string][]byte)
+	} else {
+		delete(m.prCache, repo)
+	}
+	return nil
+}
+
+// DeleteLegacyPrCache reports false: the memory backend never had the global PR cache.
+func (m *MemoryDB) DeleteLegacyPrCache(ctx context.Context) (bool, error) {
+	return false, nil
+}
+
+// SaveToken saves an API token keyed by its ID.
+func (m *MemoryDB) SaveToken(ctx context.Context, token *models.APIToken) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tokens[token.ID] = data
+	return nil
+}
+
+// GetToken returns nil, nil when the token does not exist.
+func (m *MemoryDB) GetToken(ctx context.Context, id string) (*models.APIToken, error) {
+	m.mu.RLock()
+	data, ok := m.tokens[id]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, nil
+	}
+	var token models.APIToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ListTokens returns every API token.
+func (m *MemoryDB) ListTokens(ctx context.Context) ([]*models.APIToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tokens := make([]*models.APIToken, 0, len(m.tokens))
+	for _, data := range m.tokens {
+		var token models.APIToken
+		if err := json.Unmarshal(data, &token); err != nil {
+			continue
+		}
+		tokens = append(tokens, &token)
+	}
+	return tokens, nil
+}
+
+// DeleteToken removes an API token.
+func (m *MemoryDB) DeleteToken(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.tokens, id)
+	return nil
+}
+
+// AppendAudit prepends an audit entry.
+func (m *MemoryDB) AppendAudit(ctx context.Context, entry *models.AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.audit = append([][]byte{data}, m.audit...)
+	return nil
+}
+
+// TrimAudit keeps only the newest n audit entries.
+func (m *MemoryDB) TrimAudit(ctx context.Context, n int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if int64(len(m.audit)) > n {
+		m.audit = m.audit[:max(n, 0)]
+	}
+	return nil
+}
+
+// GetAudit returns the entries between start and end inclusive, using LRANGE index rules.
+func (m *MemoryDB) GetAudit(ctx context.Context, start, end int64) ([]*models.AuditEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	from, to, ok := rangeBounds(int64(len(m.audit)), start, end)
+	entries := make([]*models.AuditEntry, 0)
+	if !ok {
+		return entries, nil
+	}
+	for _, item := range m.audit[from : to+1] {
+		var entry models.AuditEntry
+		if err := json.Unmarshal(item, &entry); err != nil {
+			continue
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, nil
+}
+
+// Ping always succeeds; the store lives in process memory.
+func (m *MemoryDB) Ping(ctx context.Context) error {
+	return nil
+}
+
+// SaveProgress saves the progress of a running task keyed by its ID.
+func (m *MemoryDB) SaveProgress(ctx context.Context, progress *models.ProgressInfo) error {
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.progress[progress.TaskID] = data
+	return nil
+}
+
+// GetProgress returns nil, nil when the task has no progress.
+func (m *MemoryDB) GetProgress(ctx context.Context, taskID string) (*models.ProgressInfo, error) {
+	m.mu.RLock()
+	data, ok := m.progress[taskID]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, nil
+	}
+	var progress models.ProgressInfo
+	if err := json.Unmarshal(data, &progress); err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+// DeleteProgress removes the progress of a task.
+func (m *MemoryDB) DeleteProgress(ctx context.Context, taskID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.progress, taskID)
+	return nil
+}
+
+// SaveTestDuration records how many seconds a test took.
+func (m *MemoryDB) SaveTestDuration(ctx context.Context, name string, seconds int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.durations[name] = seconds
+	return nil
+}
+
+// GetTestDurations returns a copy of the recorded seconds of every test.
+func (m *MemoryDB) GetTestDurations(ctx context.Context) (map[string]int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	durations := make(map[string]int64, len(m.durations))
+	for name, seconds := range m.durations {
+		durations[name] = seconds
+	}
+	return durations, nil
+}
+
+// RequestCancel marks a running task to be cancelled by the executor.
+func (m *MemoryDB) RequestCancel(ctx context.Context, taskID, actor string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cancels[taskID] = actor
+	return nil
+}
+
+// GetCancelRequest returns who requested the cancel, or "" when there is none.
+func (m *MemoryDB) GetCancelRequest(ctx context.Context, taskID string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.cancels[taskID], nil
+}
+
+// ClearCancel removes the cancel request of a task.
+func (m *MemoryDB) ClearCancel(ctx context.Context, taskID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.cancels, taskID)
+	return nil
+}
+
+// ListResults returns every stored task result.
+func (m *MemoryDB) ListResults(ctx context.Context) ([]*models.TaskResult, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := make([]*models.TaskResult, 0, len(m.results))
+	for _, data := range m.results {
+		var result models.TaskResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, err
+		}
+		results = append(results, &result)
+	}
+	return results, nil
+}
+
+// RestoreResult writes a result as-is and keeps the running set in sync, bypassing transition checks.
+func (m *MemoryDB) RestoreResult(ctx context.Context, result *models.TaskResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.results[result.TaskID] = data
+	if result.Status == models.StatusRunning {
+		m.running[result.TaskID] = struct{}{}
+	} else {
+		delete(m.running, result.TaskID)
+	}
+	return nil
+}
+
+// TrimHistory keeps only the newest n history records.
+func (m *MemoryDB) TrimHistory(ctx context.Context, n int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if int64(len(m.history)) > n {
+		m.history = m.history[:max(n, 0)]
+	}
+	return nil
+}
+
+// ReplaceHistory replaces the history; records are newest first.
+func (m *MemoryDB) ReplaceHistory(ctx context.Context, records []*models.HistoryRecord) error {
+	history := make([][]byte, 0, len(records))
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		history = append(history, data)
+	}
+	m.mu.Lock()
+	m.history = history
+	m.mu.Unlock()
+	return nil
+}
+
+// GetTaskIDCounter returns the last issued task ID, 0 when none was issued.
+func (m *MemoryDB) GetTaskIDCounter(ctx context.Context) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.taskID, nil
+}
+
+// RestoreTaskIDCounter raises the task ID counter to at least n.
+func (m *MemoryDB) RestoreTaskIDCounter(ctx context.Context, n int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if n > m.taskID {
+		m.taskID = n
+	}
+	return nil
+}
+
+// rangeBounds converts LRANGE style start/end (negative counts from the tail)
+// into slice bounds. ok is false when the range is empty.
+func rangeBounds(n, start, end int64) (int64, int64, bool) {
+	if start < 0 {
+		start += n
+	}
+	if end < 0 {
+		end += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end >= n {
+		end = n - 1
+	}
+	if n == 0 || start > end || start >= n {
+		return 0, 0, false
+	}
+	return start, end, true
+}
@@ -0,0 +1,14 @@
+package database_test
+
+import (
+	"testing"
+
+	"web_test/pkg/database"
+	"web_test/pkg/database/storetest"
+)
+
+func TestMemoryDB(t *testing.T) {
+	storetest.TestResultStore(t, func(t *testing.T) database.ResultStore {
+		return database.NewMemoryDB()
+	})
+}
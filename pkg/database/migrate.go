@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// legacyTimeLayout 是舊版 SaveHistory 寫入 HistoryRecord.Time 的格式
+const legacyTimeLayout = "2006-01-02 15:04:05"
+
+// LegacyLocation 是舊版紀錄寫入時使用的固定時區
+var LegacyLocation = func() *time.Location {
+	loc, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		return time.FixedZone("CST", 8*3600)
+	}
+	return loc
+}()
+
+// MigrateHistoryTimestamps 將舊版以字串儲存時間的歷史紀錄轉為 UTC Unix 時間戳。
+// 舊字串記錄的是任務結束時間，因此寫入 FinishedAt；若對應的任務結果仍存在，
+// 會一併補上 StartedAt 與 Duration。已轉換的紀錄不會再被修改，可重複執行。
+// 回傳轉換的筆數。
+func MigrateHistoryTimestamps(ctx context.Context, store ResultStore, legacy *time.Location) (int, error) {
+	history, err := store.GetHistory(ctx, 0, -1)
+	if err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for _, record := range history {
+		if record.Time == "" {
+			continue
+		}
+		if record.FinishedAt == 0 {
+			finished, err := time.ParseInLocation(legacyTimeLayout, record.Time, legacy)
+			if err != nil {
+				// 無法解析的紀錄保留原字串，避免遺失資料
+				continue
+			}
+			record.FinishedAt = finished.Unix()
+		}
+		if record.TaskID == "" {
+			record.TaskID = taskIDFromName(record.TaskName)
+		}
+		if record.TaskID != "" && record.StartedAt == 0 {
+			if result, err := store.GetResult(ctx, record.TaskID); err == nil && result != nil && result.StartedAt != 0 {
+				record.StartedAt = result.StartedAt
+				record.Duration = record.FinishedAt - result.StartedAt
+			}
+		}
+		record.Time = ""
+		migrated++
+	}
+
+	if migrated == 0 {
+		return 0, nil
+	}
+	if err := store.ReplaceHistory(ctx, history); err != nil {
+		return 0, err
+	}
+	return migrated, nil
+}
+
+// taskIDFromName 從 "Test Task <id>" 取出任務 ID
+func taskIDFromName(name string) string {
+	if id := strings.TrimPrefix(name, "Test Task "); id != name {
+		return id
+	}
+	return ""
+}
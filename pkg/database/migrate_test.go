@@ -0,0 +1,55 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"web_test/pkg/database"
+	"web_test/pkg/models"
+)
+
+func TestMigrateHistoryTimestamps(t *testing.T) {
+	ctx := context.Background()
+	store := database.NewMemoryDB()
+
+	started := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	if err := store.SaveResult(ctx, &models.TaskResult{TaskID: "4", Status: models.StatusRunning, StartedAt: started.Unix()}); err != nil {
+		t.Fatal(err)
+	}
+	// Legacy records were written newest first with Asia/Taipei strings.
+	legacy := []*models.HistoryRecord{
+		{Time: "2025-03-01 17:30:00", TaskName: "Test Task 4", Result: models.StatusFailed},
+		{Time: "not a time", TaskName: "Test Task 3", Result: models.StatusSuccess},
+		{TaskID: "2", FinishedAt: 100, TaskName: "Test Task 2", Result: models.StatusSuccess},
+	}
+	if err := store.ReplaceHistory(ctx, legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := database.MigrateHistoryTimestamps(ctx, store, database.LegacyLocation)
+	if err != nil || n != 1 {
+		t.Fatalf("MigrateHistoryTimestamps = %d, %v, want 1", n, err)
+	}
+
+	history, err := store.GetHistory(ctx, 0, -1)
+	if err != nil || len(history) != 3 {
+		t.Fatalf("GetHistory = %+v, %v", history, err)
+	}
+	finished := time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC).Unix()
+	got := history[0]
+	if got.Time != "" || got.TaskID != "4" || got.FinishedAt != finished ||
+		got.StartedAt != started.Unix() || got.Duration != 30*60 {
+		t.Fatalf("migrated record = %+v", got)
+	}
+	if history[1].Time != "not a time" || history[1].FinishedAt != 0 {
+		t.Fatalf("unparsable record must be kept as is, got %+v", history[1])
+	}
+	if history[2].FinishedAt != 100 {
+		t.Fatalf("already migrated record changed: %+v", history[2])
+	}
+
+	if n, err := database.MigrateHistoryTimestamps(ctx, store, database.LegacyLocation); err != nil || n != 0 {
+		t.Fatalf("second migration = %d, %v, want 0", n, err)
+	}
+}
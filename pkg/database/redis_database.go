@@ -4,7 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"time"
+	"strconv"
 	"web_test/pkg/models"
 
 	"github.com/redis/go-redis/v9"
@@ -33,46 +33,111 @@ const (
 	runningTasksSetKey = "running_tasks"
 	taskIDCounterKey   = "task_id_counter"
 	historyListKey     = "task_history_list" // Use a list for history to maintain order
-	prCacheKey         = "pr_cache"
+	prCacheHashKey     = "pr_cache_repos"    // field: owner/repo, value: PrCacheEntry JSON
+	legacyPrCacheKey   = "pr_cache"          // PR list of the last fetched repo, replaced by prCacheHashKey
+	apiTokensHashKey   = "api_tokens"        // field: token ID, value: APIToken JSON
+	auditListKey       = "audit_log"         // newest first, like the history list
+	progressHashKey    = "task_progress"     // field: task ID, value: ProgressInfo JSON
+	testDurationsKey   = "test_durations"    // field: test name, value: seconds
+	cancelHashKey      = "task_cancel"       // field: task ID, value: actor who requested the cancel
 )
 
-var taipeiLocation = func() *time.Location {
-	loc, err := time.LoadLocation("Asia/Taipei")
-	if err != nil {
-		return time.FixedZone("CST", 8*3600)
+// newHistoryRecord builds the history entry written when a task leaves the running state.
+// Times are stored as UTC Unix seconds; formatting for display happens in the API.
+func newHistoryRecord(result *models.TaskResult) *models.HistoryRecord {
+	finishedAt := result.FinishedAt
+	if finishedAt == 0 {
+		finishedAt = result.Timestamp
+	}
+	return &models.HistoryRecord{
+		TaskID:      result.TaskID,
+		StartedAt:   result.StartedAt,
+		FinishedAt:  finishedAt,
+		Duration:    result.Duration,
+		Params:      result.Params,
+		BaseRef:     result.BaseRef,
+		SubmittedBy: result.SubmittedBy,
+		Source:      result.Source,
+		TaskName:    fmt.Sprintf("Test Task %s", result.TaskID),
+		Result:      result.Status,
 	}
-	return loc
-}()
+}
+
+// saveResultScript validates the status transition and applies the result,
+// running-set and history updates in one atomic step.
+// KEYS: results hash, running set, history list.
+// ARGV: task ID, result JSON, "1" if the task is running, history JSON ("" for none), allowed previous statuses...
+// Returns {1, prev} on success and {0, prev} when the transition is rejected.
+var saveResultScript = redis.NewScript(`
+local prev = ""
+local cur = redis.call("HGET", KEYS[1], ARGV[1])
+if cur then
+	local ok, decoded = pcall(cjson.decode, cur)
+	if ok and type(decoded) == "table" and type(decoded["status"]) == "string" then
+		prev = decoded["status"]
+	end
+end
+
+local allowed = false
+for i = 5, #ARGV do
+	if ARGV[i] == prev then
+		allowed = true
+		break
+	end
+end
+if not allowed then
+	return {0, prev}
+end
+
+redis.call("HSET", KEYS[1], ARGV[1], ARGV[2])
+if ARGV[3] == "1" then
+	redis.call("SADD", KEYS[2], ARGV[1])
+else
+	redis.call("SREM", KEYS[2], ARGV[1])
+end
+if ARGV[4] ~= "" then
+	redis.call("LPUSH", KEYS[3], ARGV[4])
+end
+return {1, prev}
+`)
 
 // SaveResult saves a task result to Redis.
+// The status transition is checked and applied atomically by saveResultScript.
 func (r *RedisDB) SaveResult(ctx context.Context, result *models.TaskResult) error {
 	data, err := json.Marshal(result)
 	if err != nil {
 		return err
 	}
 
-	if err = r.client.HSet(ctx, taskResultsHashKey, result.TaskID, data).Err(); err != nil {
-		return err
-	}
-
-	// If the task is running, add it to the running tasks set
-	if result.Status == "running" {
-		if err := r.client.SAdd(ctx, runningTasksSetKey, result.TaskID).Err(); err != nil {
+	// Finished tasks are also appended to the history list
+	var history []byte
+	if models.IsTerminalStatus(result.Status) {
+		if history, err = json.Marshal(newHistoryRecord(result)); err != nil {
 			return err
 		}
-	} else {
-		// If the task is completed or failed, remove it from the running tasks set
-		if err := r.client.SRem(ctx, runningTasksSetKey, result.TaskID).Err(); err != nil {
-			return err
-		}
-		r.SaveHistory(ctx, &models.HistoryRecord{
-			Time:     time.Unix(result.Timestamp, 0).In(taipeiLocation).Format("2006-01-02 15:04:05"),
-			Params:   result.Params,
-			TaskName: fmt.Sprintf("Test Task %s", result.TaskID),
-			Result:   result.Status,
-		})
 	}
 
+	running := ""
+	if result.Status == models.StatusRunning {
+		running = "1"
+	}
+	args := []interface{}{result.TaskID, data, running, history}
+	for _, prev := range models.AllowedPrevious(result.Status) {
+		args = append(args, prev)
+	}
+
+	reply, err := saveResultScript.Run(ctx, r.client,
+		[]string{taskResultsHashKey, runningTasksSetKey, historyListKey}, args...).Slice()
+	if err != nil {
+		return err
+	}
+	if len(reply) != 2 {
+		return fmt.Errorf("unexpected reply from save script: %v", reply)
+	}
+	if ok, _ := reply[0].(int64); ok != 1 {
+		prev, _ := reply[1].(string)
+		return illegalTransition(result.TaskID, prev, result.Status)
+	}
 	return nil
 }
 
@@ -117,7 +182,7 @@ func (r *RedisDB) GetRunningTasks(ctx context.Context) ([]*models.TaskResult, er
 
 // DeleteResult deletes a task result from Redis.
 func (r *RedisDB) DeleteResult(ctx context.Context, taskID string, status string) error {
-	if status == "running" {
+	if status == models.StatusRunning {
 		return r.client.SRem(ctx, runningTasksSetKey, taskID).Err()
 	} else {
 		return r.client.HDel(ctx, taskResultsHashKey, taskID).Err()
@@ -157,26 +222,217 @@ func (r *RedisDB) GetHistory(ctx context.Context, start, end int64) ([]*models.H
 	return history, nil
 }
 
-// SavePrCache saves the PRs cache to Redis.
-func (r *RedisDB) SavePrCache(ctx context.Context, Prs []byte) error {
-	return r.client.Set(ctx, prCacheKey, Prs, 0).Err()
+// SavePrCache saves the PR cache entry of one repository.
+func (r *RedisDB) SavePrCache(ctx context.Context, entry *models.PrCacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return r.client.HSet(ctx, prCacheHashKey, entry.Key(), data).Err()
+}
+
+// GetPrCache retrieves the PR cache entry of owner/repo.
+func (r *RedisDB) GetPrCache(ctx context.Context, repo string) (*models.PrCacheEntry, error) {
+	data, err := r.client.HGet(ctx, prCacheHashKey, repo).Bytes()
+	if err == redis.Nil {
+		return nil, nil // Entry does not exist
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entry models.PrCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// ListPrCache retrieves the PR cache entries of every repository.
+func (r *RedisDB) ListPrCache(ctx context.Context) ([]*models.PrCacheEntry, error) {
+	values, err := r.client.HVals(ctx, prCacheHashKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*models.PrCacheEntry, 0, len(values))
+	for _, value := range values {
+		var entry models.PrCacheEntry
+		if err := json.Unmarshal([]byte(value), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, nil
+}
+
+// ClearPrCache removes the cached PR data of owner/repo, or of every repository when repo is empty.
+func (r *RedisDB) ClearPrCache(ctx context.Context, repo string) error {
+	if repo == "" {
+		return r.client.Del(ctx, prCacheHashKey).Err()
+	}
+	return r.client.HDel(ctx, prCacheHashKey, repo).Err()
+}
+
+// DeleteLegacyPrCache removes the global PR cache written by older versions.
+func (r *RedisDB) DeleteLegacyPrCache(ctx context.Context) (bool, error) {
+	n, err := r.client.Del(ctx, legacyPrCacheKey).Result()
+	return n > 0, err
+}
+
+// SaveToken saves an API token keyed by its ID.
+func (r *RedisDB) SaveToken(ctx context.Context, token *models.APIToken) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	return r.client.HSet(ctx, apiTokensHashKey, token.ID, data).Err()
+}
+
+// GetToken returns nil, nil when the token does not exist.
+func (r *RedisDB) GetToken(ctx context.Context, id string) (*models.APIToken, error) {
+	data, err := r.client.HGet(ctx, apiTokensHashKey, id).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var token models.APIToken
+	if err := json.Unmarshal([]byte(data), &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ListTokens returns every API token.
+func (r *RedisDB) ListTokens(ctx context.Context) ([]*models.APIToken, error) {
+	values, err := r.client.HVals(ctx, apiTokensHashKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]*models.APIToken, 0, len(values))
+	for _, value := range values {
+		var token models.APIToken
+		if err := json.Unmarshal([]byte(value), &token); err != nil {
+			continue
+		}
+		tokens = append(tokens, &token)
+	}
+	return tokens, nil
+}
+
+// DeleteToken removes an API token.
+func (r *RedisDB) DeleteToken(ctx context.Context, id string) error {
+	return r.client.HDel(ctx, apiTokensHashKey, id).Err()
+}
+
+// AppendAudit prepends an audit entry to the audit list.
+func (r *RedisDB) AppendAudit(ctx context.Context, entry *models.AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return r.client.LPush(ctx, auditListKey, data).Err()
+}
+
+// TrimAudit keeps only the newest n audit entries.
+func (r *RedisDB) TrimAudit(ctx context.Context, n int64) error {
+	if n <= 0 {
+		return r.client.Del(ctx, auditListKey).Err()
+	}
+	return r.client.LTrim(ctx, auditListKey, 0, n-1).Err()
 }
 
-// GetPrCache retrieves the PRs cache from Redis.
-func (r *RedisDB) GetPrCache(ctx context.Context) ([]byte, error) {
-	data, err := r.client.Get(ctx, prCacheKey).Bytes()
+// GetAudit returns audit entries in [start, end], newest first.
+func (r *RedisDB) GetAudit(ctx context.Context, start, end int64) ([]*models.AuditEntry, error) {
+	data, err := r.client.LRange(ctx, auditListKey, start, end).Result()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*models.AuditEntry, 0, len(data))
+	for _, item := range data {
+		var entry models.AuditEntry
+		if err := json.Unmarshal([]byte(item), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, nil
+}
+
+// Ping checks the connection to Redis.
+func (r *RedisDB) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+// SaveProgress saves the progress of a running task keyed by its ID.
+func (r *RedisDB) SaveProgress(ctx context.Context, progress *models.ProgressInfo) error {
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	return r.client.HSet(ctx, progressHashKey, progress.TaskID, data).Err()
+}
+
+// GetProgress returns nil, nil when the task has no progress.
+func (r *RedisDB) GetProgress(ctx context.Context, taskID string) (*models.ProgressInfo, error) {
+	data, err := r.client.HGet(ctx, progressHashKey, taskID).Result()
 	if err == redis.Nil {
-		return nil, nil // Key does not exist
+		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	return data, nil
+	var progress models.ProgressInfo
+	if err := json.Unmarshal([]byte(data), &progress); err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+// DeleteProgress removes the progress of a task.
+func (r *RedisDB) DeleteProgress(ctx context.Context, taskID string) error {
+	return r.client.HDel(ctx, progressHashKey, taskID).Err()
+}
+
+// SaveTestDuration records how many seconds a test took.
+func (r *RedisDB) SaveTestDuration(ctx context.Context, name string, seconds int64) error {
+	return r.client.HSet(ctx, testDurationsKey, name, seconds).Err()
+}
+
+// GetTestDurations returns the recorded seconds of every test.
+func (r *RedisDB) GetTestDurations(ctx context.Context) (map[string]int64, error) {
+	values, err := r.client.HGetAll(ctx, testDurationsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	durations := make(map[string]int64, len(values))
+	for name, value := range values {
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		durations[name] = seconds
+	}
+	return durations, nil
 }
 
-// ClearPrCache removes the cached PR data.
-func (r *RedisDB) ClearPrCache(ctx context.Context) error {
-	return r.client.Del(ctx, prCacheKey).Err()
+// RequestCancel marks a running task to be cancelled by the executor.
+func (r *RedisDB) RequestCancel(ctx context.Context, taskID, actor string) error {
+	return r.client.HSet(ctx, cancelHashKey, taskID, actor).Err()
+}
+
+// GetCancelRequest returns who requested the cancel, or "" when there is none.
+func (r *RedisDB) GetCancelRequest(ctx context.Context, taskID string) (string, error) {
+	actor, err := r.client.HGet(ctx, cancelHashKey, taskID).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return actor, err
+}
+
+// ClearCancel removes the cancel request of a task.
+func (r *RedisDB) ClearCancel(ctx context.Context, taskID string) error {
+	return r.client.HDel(ctx, cancelHashKey, taskID).Err()
 }
 
 func (r *RedisDB) IncrementTaskID(ctx context.Context) (int, error) {
@@ -186,3 +442,92 @@ func (r *RedisDB) IncrementTaskID(ctx context.Context) (int, error) {
 	}
 	return int(result), nil
 }
+
+// ListResults returns every stored task result.
+func (r *RedisDB) ListResults(ctx context.Context) ([]*models.TaskResult, error) {
+	values, err := r.client.HVals(ctx, taskResultsHashKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*models.TaskResult, 0, len(values))
+	for _, value := range values {
+		var result models.TaskResult
+		if err := json.Unmarshal([]byte(value), &result); err != nil {
+			return nil, err
+		}
+		results = append(results, &result)
+	}
+	return results, nil
+}
+
+// RestoreResult writes a result as-is and keeps the running set in sync, bypassing transition checks.
+func (r *RedisDB) RestoreResult(ctx context.Context, result *models.TaskResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, taskResultsHashKey, result.TaskID, data)
+		if result.Status == models.StatusRunning {
+			pipe.SAdd(ctx, runningTasksSetKey, result.TaskID)
+		} else {
+			pipe.SRem(ctx, runningTasksSetKey, result.TaskID)
+		}
+		return nil
+	})
+	return err
+}
+
+// TrimHistory keeps only the newest n history records.
+func (r *RedisDB) TrimHistory(ctx context.Context, n int64) error {
+	if n <= 0 {
+		return r.client.Del(ctx, historyListKey).Err()
+	}
+	return r.client.LTrim(ctx, historyListKey, 0, n-1).Err()
+}
+
+// ReplaceHistory atomically replaces the history list; records are newest first.
+func (r *RedisDB) ReplaceHistory(ctx context.Context, records []*models.HistoryRecord) error {
+	items := make([]interface{}, 0, len(records))
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		items = append(items, data)
+	}
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, historyListKey)
+		if len(items) > 0 {
+			// RPush keeps the newest-first order used by LPush in SaveHistory
+			pipe.RPush(ctx, historyListKey, items...)
+		}
+		return nil
+	})
+	return err
+}
+
+// GetTaskIDCounter returns the last issued task ID, 0 when none was issued.
+func (r *RedisDB) GetTaskIDCounter(ctx context.Context) (int, error) {
+	n, err := r.client.Get(ctx, taskIDCounterKey).Int()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return n, err
+}
+
+// restoreCounterScript raises the counter to ARGV[1] but never lowers it.
+var restoreCounterScript = redis.NewScript(`
+local cur = tonumber(redis.call("GET", KEYS[1]) or "0")
+local n = tonumber(ARGV[1])
+if n > cur then
+	redis.call("SET", KEYS[1], n)
+end
+return 1
+`)
+
+// RestoreTaskIDCounter raises the task ID counter to at least n.
+func (r *RedisDB) RestoreTaskIDCounter(ctx context.Context, n int) error {
+	return restoreCounterScript.Run(ctx, r.client, []string{taskIDCounterKey}, n).Err()
+}
@@ -0,0 +1,59 @@
+package database_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"web_test/pkg/database"
+	"web_test/pkg/database/storetest"
+	"web_test/pkg/models"
+)
+
+// redisTestDB is the logical DB used when WEB_TEST_REDIS_ADDR points at a real server.
+// It is flushed before every subtest.
+const redisTestDB = 15
+
+// TestRedisDB runs the conformance suite against miniredis, or against the
+// Redis/KVRocks server in WEB_TEST_REDIS_ADDR when it is set.
+func TestRedisDB(t *testing.T) {
+	addr := os.Getenv("WEB_TEST_REDIS_ADDR")
+	storetest.TestResultStore(t, func(t *testing.T) database.ResultStore {
+		if addr == "" {
+			return database.NewRedisDB(miniredis.RunT(t).Addr(), "", 0)
+		}
+
+		client := redis.NewClient(&redis.Options{Addr: addr, DB: redisTestDB, Protocol: 2})
+		defer client.Close()
+		if err := client.FlushDB(context.Background()).Err(); err != nil {
+			t.Fatalf("flush %s: %v", addr, err)
+		}
+		return database.NewRedisDB(addr, "", redisTestDB)
+	})
+}
+
+func TestRedisDeleteLegacyPrCache(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	mr.Set("pr_cache", `[{"number":1}]`)
+	db := database.NewRedisDB(mr.Addr(), "", 0)
+	if err := db.SavePrCache(ctx, &models.PrCacheEntry{Owner: "free5gc", Repo: "amf"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if deleted, err := db.DeleteLegacyPrCache(ctx); err != nil || !deleted {
+		t.Fatalf("DeleteLegacyPrCache = %v, %v, want true, nil", deleted, err)
+	}
+	if mr.Exists("pr_cache") {
+		t.Fatal("legacy pr_cache key still exists")
+	}
+	if deleted, err := db.DeleteLegacyPrCache(ctx); err != nil || deleted {
+		t.Fatalf("second DeleteLegacyPrCache = %v, %v, want false, nil", deleted, err)
+	}
+	if entry, err := db.GetPrCache(ctx, "free5gc/amf"); err != nil || entry == nil {
+		t.Fatalf("per-repo cache removed with the legacy key: %+v, %v", entry, err)
+	}
+}
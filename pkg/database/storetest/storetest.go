@@ -0,0 +1,682 @@
+// Package storetest provides a conformance suite for database.ResultStore implementations.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"web_test/pkg/database"
+	"web_test/pkg/models"
+)
+
+// NewStoreFunc returns an empty store for a single subtest.
+type NewStoreFunc func(t *testing.T) database.ResultStore
+
+// TestResultStore runs every conformance check against stores produced by newStore.
+// Each subtest receives its own empty store.
+func TestResultStore(t *testing.T, newStore NewStoreFunc) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s database.ResultStore)
+	}{
+		{"Ping", testPing},
+		{"GetResultMiss", testGetResultMiss},
+		{"SaveAndGetResult", testSaveAndGetResult},
+		{"RunningSet", testRunningSet},
+		{"DeleteRunningKeepsResult", testDeleteRunningKeepsResult},
+		{"DeleteResult", testDeleteResult},
+		{"Transitions", testTransitions},
+		{"RejectsIllegalTransitions", testRejectsIllegalTransitions},
+		{"ConcurrentTransitions", testConcurrentTransitions},
+		{"IncrementTaskID", testIncrementTaskID},
+		{"ConcurrentIncrementTaskID", testConcurrentIncrementTaskID},
+		{"HistoryOrdering", testHistoryOrdering},
+		{"HistoryRange", testHistoryRange},
+		{"PrCache", testPrCache},
+		{"Tokens", testTokens},
+		{"Audit", testAudit},
+		{"Progress", testProgress},
+		{"Cancel", testCancel},
+		{"ListAndRestoreResults", testListAndRestoreResults},
+		{"ReplaceHistory", testReplaceHistory},
+		{"TrimHistory", testTrimHistory},
+		{"TaskIDCounter", testTaskIDCounter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func testPing(t *testing.T, s database.ResultStore) {
+	if err := s.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+}
+
+func testGetResultMiss(t *testing.T, s database.ResultStore) {
+	result, err := s.GetResult(context.Background(), "missing")
+	if err != nil {
+		t.Fatalf("GetResult on miss returned error: %v", err)
+	}
+	if result != nil {
+		t.Fatalf("GetResult on miss = %+v, want nil", result)
+	}
+}
+
+func testSaveAndGetResult(t *testing.T, s database.ResultStore) {
+	ctx := context.Background()
+	want := &models.TaskResult{
+		TaskID:      "7",
+		Status:      models.StatusFailed,
+		Params:      []models.TaskParams{{NF: "amf", PRVersion: "12"}},
+		Logs:        []string{"log body"},
+		FailedTests: []string{"TestRegistration"},
+		Timestamp:   1700000000,
+	}
+	mustSave(t, s, &models.TaskResult{TaskID: "7", Status: models.StatusRunning})
+	mustSave(t, s, want)
+
+	got, err := s.GetResult(ctx, "7")
+	if err != nil {
+		t.Fatalf("GetResult: %v", err)
+	}
+	if got == nil {
+		t.Fatal("GetResult returned nil after save")
+	}
+	if got.Status != want.Status || got.Timestamp != want.Timestamp ||
+		len(got.Params) != 1 || got.Params[0] != want.Params[0] ||
+		len(got.Logs) != 1 || got.Logs[0] != want.Logs[0] ||
+		len(got.FailedTests) != 1 || got.FailedTests[0] != want.FailedTests[0] {
+		t.Fatalf("GetResult = %+v, want %+v", got, want)
+	}
+}
+
+func testRunningSet(t *testing.T, s database.ResultStore) {
+	ctx := context.Background()
+	mustSave(t, s, &models.TaskResult{TaskID: "1", Status: models.StatusRunning, Timestamp: 1})
+	mustSave(t, s, &models.TaskResult{TaskID: "2", Status: models.StatusRunning, Timestamp: 2})
+
+	if ids := runningIDs(t, s); fmt.Sprint(ids) != "[1 2]" {
+		t.Fatalf("running tasks = %v, want [1 2]", ids)
+	}
+	if history := mustHistory(t, s, 0, -1); len(history) != 0 {
+		t.Fatalf("running results must not be added to history, got %d records", len(history))
+	}
+
+	mustSave(t, s, &models.TaskResult{TaskID: "1", Status: models.StatusSuccess, Timestamp: 3})
+	if ids := runningIDs(t, s); fmt.Sprint(ids) != "[2]" {
+		t.Fatalf("running tasks after completion = %v, want [2]", ids)
+	}
+	history := mustHistory(t, s, 0, -1)
+	if len(history) != 1 || history[0].Result != models.StatusSuccess || history[0].TaskName != "Test Task 1" {
+		t.Fatalf("history after completion = %+v", history)
+	}
+	if history[0].TaskID != "1" || history[0].FinishedAt != 3 || history[0].Time != "" {
+		t.Fatalf("history must store the task ID and Unix timestamps only, got %+v", history[0])
+	}
+
+	got, err := s.GetResult(ctx, "1")
+	if err != nil || got == nil || got.Status != models.StatusSuccess {
+		t.Fatalf("GetResult after completion = %+v, %v", got, err)
+	}
+}
+
+func testDeleteRunningKeepsResult(t *testing.T, s database.ResultStore) {
+	ctx := context.Background()
+	mustSave(t, s, &models.TaskResult{TaskID: "5", Status: models.StatusRunning, Timestamp: 1})
+
+	if err := s.DeleteResult(ctx, "5", models.StatusRunning); err != nil {
+		t.Fatalf("DeleteResult running: %v", err)
+	}
+	if ids := runningIDs(t, s); len(ids) != 0 {
+		t.Fatalf("running tasks after delete = %v, want none", ids)
+	}
+	got, err := s.GetResult(ctx, "5")
+	if err != nil || got == nil {
+		t.Fatalf("DeleteResult with status running must keep the stored result, got %+v, %v", got, err)
+	}
+}
+
+func testDeleteResult(t *testing.T, s database.ResultStore) {
+	ctx := context.Background()
+	mustSave(t, s, &models.TaskResult{TaskID: "9", Status: models.StatusRunning, Timestamp: 1})
+	mustSave(t, s, &models.TaskResult{TaskID: "9", Status: models.StatusFailed, Timestamp: 2})
+
+	if err := s.DeleteResult(ctx, "9", models.StatusFailed); err != nil {
+		t.Fatalf("DeleteResult: %v", err)
+	}
+	got, err := s.GetResult(ctx, "9")
+	if err != nil || got != nil {
+		t.Fatalf("GetResult after delete = %+v, %v, want nil, nil", got, err)
+	}
+	if err := s.DeleteResult(ctx, "does-not-exist", models.StatusFailed); err != nil {
+		t.Fatalf("DeleteResult on a missing task must not fail: %v", err)
+	}
+}
+
+func testTransitions(t *testing.T, s database.ResultStore) {
+	mustSave(t, s, &models.TaskResult{TaskID: "q", Status: models.StatusQueueing})
+	if ids := runningIDs(t, s); len(ids) != 0 {
+		t.Fatalf("queued task is in the running set: %v", ids)
+	}
+	mustSave(t, s, &models.TaskResult{TaskID: "q", Status: models.StatusRunning})
+	mustSave(t, s, &models.TaskResult{TaskID: "q", Status: models.StatusFailed})
+
+	got, err := s.GetResult(context.Background(), "q")
+	if err != nil || got == nil || got.Status != models.StatusFailed {
+		t.Fatalf("GetResult after queued -> running -> failed = %+v, %v", got, err)
+	}
+}
+
+func testRejectsIllegalTransitions(t *testing.T, s database.ResultStore) {
+	ctx := context.Background()
+	mustSave(t, s, &models.TaskResult{TaskID: "1", Status: models.StatusRunning, Timestamp: 1})
+	mustSave(t, s, &models.TaskResult{TaskID: "1", Status: models.StatusSuccess, Timestamp: 2})
+
+	illegal := []*models.TaskResult{
+		{TaskID: "1", Status: models.StatusRunning},
+		{TaskID: "1", Status: models.StatusFailed},
+		{TaskID: "1", Status: models.StatusQueueing},
+		{TaskID: "2", Status: models.StatusSuccess},
+		{TaskID: "2", Status: "bogus"},
+	}
+	for _, result := range illegal {
+		err := s.SaveResult(ctx, result)
+		if !errors.Is(err, database.ErrIllegalTransition) {
+			t.Errorf("SaveResult(%s, %s) = %v, want ErrIllegalTransition", result.TaskID, result.Status, err)
+		}
+	}
+
+	got, err := s.GetResult(ctx, "1")
+	if err != nil || got == nil || got.Status != models.StatusSuccess || got.Timestamp != 2 {
+		t.Fatalf("terminal result was modified by a rejected save: %+v, %v", got, err)
+	}
+	if got, _ := s.GetResult(ctx, "2"); got != nil {
+		t.Fatalf("rejected save created a result: %+v", got)
+	}
+	if ids := runningIDs(t, s); len(ids) != 0 {
+		t.Fatalf("rejected save touched the running set: %v", ids)
+	}
+	if history := mustHistory(t, s, 0, -1); len(history) != 1 {
+		t.Fatalf("rejected saves must not add history, got %d records", len(history))
+	}
+}
+
+func testConcurrentTransitions(t *testing.T, s database.ResultStore) {
+	const workers = 8
+	ctx := context.Background()
+	mustSave(t, s, &models.TaskResult{TaskID: "race", Status: models.StatusRunning})
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		accepted int
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			status := models.StatusSuccess
+			if w%2 == 1 {
+				status = models.StatusFailed
+			}
+			err := s.SaveResult(ctx, &models.TaskResult{TaskID: "race", Status: status})
+			switch {
+			case err == nil:
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			case !errors.Is(err, database.ErrIllegalTransition):
+				t.Errorf("SaveResult: %v", err)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if accepted != 1 {
+		t.Fatalf("%d concurrent terminal saves were accepted, want exactly 1", accepted)
+	}
+	if history := mustHistory(t, s, 0, -1); len(history) != 1 {
+		t.Fatalf("history has %d records, want 1", len(history))
+	}
+	if ids := runningIDs(t, s); len(ids) != 0 {
+		t.Fatalf("running set after completion = %v, want none", ids)
+	}
+}
+
+func testIncrementTaskID(t *testing.T, s database.ResultStore) {
+	ctx := context.Background()
+	for want := 1; want <= 3; want++ {
+		got, err := s.IncrementTaskID(ctx)
+		if err != nil {
+			t.Fatalf("IncrementTaskID: %v", err)
+		}
+		if got != want {
+			t.Fatalf("IncrementTaskID = %d, want %d", got, want)
+		}
+	}
+}
+
+func testConcurrentIncrementTaskID(t *testing.T, s database.ResultStore) {
+	const workers, perWorker = 8, 25
+	ctx := context.Background()
+
+	var (
+		mu   sync.Mutex
+		seen = make(map[int]bool)
+		wg   sync.WaitGroup
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				id, err := s.IncrementTaskID(ctx)
+				if err != nil {
+					t.Errorf("IncrementTaskID: %v", err)
+					return
+				}
+				mu.Lock()
+				if seen[id] {
+					t.Errorf("IncrementTaskID returned duplicate id %d", id)
+				}
+				seen[id] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for id := 1; id <= workers*perWorker; id++ {
+		if !seen[id] {
+			t.Fatalf("IncrementTaskID skipped id %d", id)
+		}
+	}
+}
+
+func testHistoryOrdering(t *testing.T, s database.ResultStore) {
+	for i := 1; i <= 3; i++ {
+		mustSave(t, s, &models.TaskResult{TaskID: fmt.Sprint(i), Status: models.StatusRunning})
+		mustSave(t, s, &models.TaskResult{
+			TaskID:    fmt.Sprint(i),
+			Status:    models.StatusSuccess,
+			Timestamp: int64(i),
+		})
+	}
+
+	history := mustHistory(t, s, 0, -1)
+	var names []string
+	for _, record := range history {
+		names = append(names, record.TaskName)
+	}
+	if fmt.Sprint(names) != "[Test Task 3 Test Task 2 Test Task 1]" {
+		t.Fatalf("history must be newest first, got %v", names)
+	}
+}
+
+func testHistoryRange(t *testing.T, s database.ResultStore) {
+	ctx := context.Background()
+	for i := 1; i <= 5; i++ {
+		record := &models.HistoryRecord{TaskName: fmt.Sprintf("task-%d", i), Result: models.StatusSuccess}
+		if err := s.SaveHistory(ctx, record); err != nil {
+			t.Fatalf("SaveHistory: %v", err)
+		}
+	}
+
+	cases := []struct {
+		start, end int64
+		want       string
+	}{
+		{0, 1, "[task-5 task-4]"},
+		{1, 2, "[task-4 task-3]"},
+		{3, 100, "[task-2 task-1]"},
+		{-2, -1, "[task-2 task-1]"},
+		{10, 20, "[]"},
+	}
+	for _, c := range cases {
+		var names []string
+		for _, record := range mustHistory(t, s, c.start, c.end) {
+			names = append(names, record.TaskName)
+		}
+		if got := fmt.Sprint(names); got != c.want {
+			t.Errorf("GetHistory(%d, %d) = %s, want %s", c.start, c.end, got, c.want)
+		}
+	}
+}
+
+func testPrCache(t *testing.T, s database.ResultStore) {
+	ctx := context.Background()
+	entry, err := s.GetPrCache(ctx, "free5gc/amf")
+	if err != nil || entry != nil {
+		t.Fatalf("GetPrCache on empty store = %+v, %v, want nil, nil", entry, err)
+	}
+
+	amf := &models.PrCacheEntry{
+		Owner: "free5gc", Repo: "amf", ETag: `W/"1"`, FetchedAt: 10, ExpiresAt: 20,
+		PRs: []models.PullRequest{{Number: 1, Title: "fix"}},
+	}
+	smf := &models.PrCacheEntry{
+		Owner: "free5gc", Repo: "smf", FetchedAt: 11, ExpiresAt: 21,
+		PRs: []models.PullRequest{{Number: 2, Title: "feat"}, {Number: 3, Title: "docs"}},
+	}
+	for _, e := range []*models.PrCacheEntry{amf, smf} {
+		if err := s.SavePrCache(ctx, e); err != nil {
+			t.Fatalf("SavePrCache(%s): %v", e.Key(), err)
+		}
+	}
+
+	entry, err = s.GetPrCache(ctx, "free5gc/amf")
+	if err != nil || entry == nil || entry.ETag != amf.ETag || entry.ExpiresAt != 20 || len(entry.PRs) != 1 {
+		t.Fatalf("GetPrCache(free5gc/amf) = %+v, %v", entry, err)
+	}
+	entry, err = s.GetPrCache(ctx, "free5gc/smf")
+	if err != nil || entry == nil || len(entry.PRs) != 2 {
+		t.Fatalf("saving a second repo must not overwrite the first, got %+v, %v", entry, err)
+	}
+	if entries, err := s.ListPrCache(ctx); err != nil || len(entries) != 2 {
+		t.Fatalf("ListPrCache = %+v, %v, want 2 entries", entries, err)
+	}
+
+	if err := s.ClearPrCache(ctx, "free5gc/amf"); err != nil {
+		t.Fatalf("ClearPrCache(free5gc/amf): %v", err)
+	}
+	if entry, err := s.GetPrCache(ctx, "free5gc/amf"); err != nil || entry != nil {
+		t.Fatalf("GetPrCache after clearing the repo = %+v, %v", entry, err)
+	}
+	if entry, err := s.GetPrCache(ctx, "free5gc/smf"); err != nil || entry == nil {
+		t.Fatalf("clearing one repo removed another: %+v, %v", entry, err)
+	}
+
+	if err := s.ClearPrCache(ctx, ""); err != nil {
+		t.Fatalf("ClearPrCache(all): %v", err)
+	}
+	if entries, err := s.ListPrCache(ctx); err != nil || len(entries) != 0 {
+		t.Fatalf("ListPrCache after clearing all = %+v, %v", entries, err)
+	}
+	if deleted, err := s.DeleteLegacyPrCache(ctx); err != nil || deleted {
+		t.Fatalf("DeleteLegacyPrCache without a legacy cache = %v, %v, want false, nil", deleted, err)
+	}
+}
+
+func testTokens(t *testing.T, s database.ResultStore) {
+	ctx := context.Background()
+	if token, err := s.GetToken(ctx, "missing"); err != nil || token != nil {
+		t.Fatalf("GetToken on miss = %+v, %v, want nil, nil", token, err)
+	}
+
+	ci := &models.APIToken{ID: "a1", Name: "ci", Role: models.RoleSubmitter, Hash: "a1ff", CreatedAt: 10}
+	ops := &models.APIToken{ID: "b2", Name: "ops", Role: models.RoleAdmin, Hash: "b2ff", CreatedAt: 11}
+	for _, token := range []*models.APIToken{ci, ops} {
+		if err := s.SaveToken(ctx, token); err != nil {
+			t.Fatalf("SaveToken(%s): %v", token.ID, err)
+		}
+	}
+	if got, err := s.GetToken(ctx, "a1"); err != nil || got == nil || *got != *ci {
+		t.Fatalf("GetToken(a1) = %+v, %v, want %+v", got, err, ci)
+	}
+	if tokens, err := s.ListTokens(ctx); err != nil || len(tokens) != 2 {
+		t.Fatalf("ListTokens = %+v, %v, want 2 tokens", tokens, err)
+	}
+
+	if err := s.DeleteToken(ctx, "a1"); err != nil {
+		t.Fatalf("DeleteToken(a1): %v", err)
+	}
+	if err := s.DeleteToken(ctx, "a1"); err != nil {
+		t.Fatalf("deleting a missing token: %v", err)
+	}
+	if got, err := s.GetToken(ctx, "a1"); err != nil || got != nil {
+		t.Fatalf("GetToken after delete = %+v, %v", got, err)
+	}
+	if tokens, err := s.ListTokens(ctx); err != nil || len(tokens) != 1 || tokens[0].ID != "b2" {
+		t.Fatalf("ListTokens after delete = %+v, %v", tokens, err)
+	}
+}
+
+func testAudit(t *testing.T, s database.ResultStore) {
+	ctx := context.Background()
+	if entries, err := s.GetAudit(ctx, 0, -1); err != nil || len(entries) != 0 {
+		t.Fatalf("GetAudit on empty store = %v, %v", entries, err)
+	}
+	for i, action := range []string{models.AuditEnqueue, models.AuditDelete, models.AuditConfig} {
+		entry := &models.AuditEntry{At: int64(i + 1), Actor: "ci-bot", Source: models.SourceCLI, Action: action, Target: fmt.Sprintf("task:%d", i)}
+		if err := s.AppendAudit(ctx, entry); err != nil {
+			t.Fatalf("AppendAudit(%s): %v", action, err)
+		}
+	}
+
+	entries, err := s.GetAudit(ctx, 0, -1)
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("GetAudit = %v, %v, want 3 entries", entries, err)
+	}
+	if entries[0].Action != models.AuditConfig || entries[2].Action != models.AuditEnqueue || entries[2].Actor != "ci-bot" {
+		t.Fatalf("GetAudit order = %+v, want newest first", entries)
+	}
+	if entries, err := s.GetAudit(ctx, 1, 1); err != nil || len(entries) != 1 || entries[0].Action != models.AuditDelete {
+		t.Fatalf("GetAudit(1, 1) = %+v, %v", entries, err)
+	}
+
+	if err := s.TrimAudit(ctx, 2); err != nil {
+		t.Fatalf("TrimAudit(2): %v", err)
+	}
+	if entries, err := s.GetAudit(ctx, 0, -1); err != nil || len(entries) != 2 || entries[1].Action != models.AuditDelete {
+		t.Fatalf("GetAudit after TrimAudit(2) = %+v, %v, want the newest 2", entries, err)
+	}
+	if err := s.TrimAudit(ctx, 0); err != nil {
+		t.Fatalf("TrimAudit(0): %v", err)
+	}
+	if entries, err := s.GetAudit(ctx, 0, -1); err != nil || len(entries) != 0 {
+		t.Fatalf("TrimAudit(0) left %d entries", len(entries))
+	}
+}
+
+func testProgress(t *testing.T, s database.ResultStore) {
+	ctx := context.Background()
+	if progress, err := s.GetProgress(ctx, "1"); err != nil || progress != nil {
+		t.Fatalf("GetProgress on miss = %+v, %v, want nil, nil", progress, err)
+	}
+	want := &models.ProgressInfo{TaskID: "1", Stage: models.StageTestAll, CurrentTest: "TestPaging", Completed: 3, Total: 20, Percent: 15, Remaining: 600, UpdatedAt: 10}
+	if err := s.SaveProgress(ctx, want); err != nil {
+		t.Fatalf("SaveProgress: %v", err)
+	}
+	if got, err := s.GetProgress(ctx, "1"); err != nil || got == nil || *got != *want {
+		t.Fatalf("GetProgress = %+v, %v, want %+v", got, err, want)
+	}
+	if err := s.DeleteProgress(ctx, "1"); err != nil {
+		t.Fatalf("DeleteProgress: %v", err)
+	}
+	if got, err := s.GetProgress(ctx, "1"); err != nil || got != nil {
+		t.Fatalf("GetProgress after delete = %+v, %v", got, err)
+	}
+
+	if err := s.SaveTestDuration(ctx, "TestPaging", 40); err != nil {
+		t.Fatalf("SaveTestDuration: %v", err)
+	}
+	if err := s.SaveTestDuration(ctx, "TestPaging", 50); err != nil {
+		t.Fatalf("SaveTestDuration: %v", err)
+	}
+	if durations, err := s.GetTestDurations(ctx); err != nil || len(durations) != 1 || durations["TestPaging"] != 50 {
+		t.Fatalf("GetTestDurations = %v, %v, want TestPaging: 50", durations, err)
+	}
+}
+
+func testCancel(t *testing.T, s database.ResultStore) {
+	ctx := context.Background()
+	if actor, err := s.GetCancelRequest(ctx, "1"); err != nil || actor != "" {
+		t.Fatalf("GetCancelRequest on miss = %q, %v, want empty", actor, err)
+	}
+	if err := s.RequestCancel(ctx, "1", "alice"); err != nil {
+		t.Fatalf("RequestCancel: %v", err)
+	}
+	if actor, err := s.GetCancelRequest(ctx, "1"); err != nil || actor != "alice" {
+		t.Fatalf("GetCancelRequest = %q, %v, want alice", actor, err)
+	}
+	if actor, err := s.GetCancelRequest(ctx, "2"); err != nil || actor != "" {
+		t.Fatalf("GetCancelRequest of another task = %q, %v, want empty", actor, err)
+	}
+	if err := s.ClearCancel(ctx, "1"); err != nil {
+		t.Fatalf("ClearCancel: %v", err)
+	}
+	if err := s.ClearCancel(ctx, "1"); err != nil {
+		t.Fatalf("ClearCancel twice: %v", err)
+	}
+	if actor, err := s.GetCancelRequest(ctx, "1"); err != nil || actor != "" {
+		t.Fatalf("GetCancelRequest after clear = %q, %v", actor, err)
+	}
+}
+
+func testListAndRestoreResults(t *testing.T, s database.ResultStore) {
+	ctx := context.Background()
+	if results, err := s.ListResults(ctx); err != nil || len(results) != 0 {
+		t.Fatalf("ListResults on empty store = %v, %v", results, err)
+	}
+
+	// RestoreResult bypasses the state machine and never writes history
+	restored := []*models.TaskResult{
+		{TaskID: "1", Status: models.StatusSuccess, Timestamp: 1},
+		{TaskID: "2", Status: models.StatusRunning, Timestamp: 2},
+	}
+	for _, result := range restored {
+		if err := s.RestoreResult(ctx, result); err != nil {
+			t.Fatalf("RestoreResult(%s): %v", result.TaskID, err)
+		}
+	}
+	if ids := runningIDs(t, s); fmt.Sprint(ids) != "[2]" {
+		t.Fatalf("running tasks after restore = %v, want [2]", ids)
+	}
+	if history := mustHistory(t, s, 0, -1); len(history) != 0 {
+		t.Fatalf("RestoreResult must not write history, got %d records", len(history))
+	}
+
+	if err := s.RestoreResult(ctx, &models.TaskResult{TaskID: "2", Status: models.StatusFailed, Timestamp: 3}); err != nil {
+		t.Fatalf("RestoreResult over running result: %v", err)
+	}
+	if ids := runningIDs(t, s); len(ids) != 0 {
+		t.Fatalf("running tasks after restoring a terminal result = %v, want none", ids)
+	}
+
+	results, err := s.ListResults(ctx)
+	if err != nil {
+		t.Fatalf("ListResults: %v", err)
+	}
+	statuses := make(map[string]string)
+	for _, result := range results {
+		statuses[result.TaskID] = result.Status
+	}
+	if len(statuses) != 2 || statuses["1"] != models.StatusSuccess || statuses["2"] != models.StatusFailed {
+		t.Fatalf("ListResults = %v", statuses)
+	}
+}
+
+func testReplaceHistory(t *testing.T, s database.ResultStore) {
+	ctx := context.Background()
+	if err := s.SaveHistory(ctx, &models.HistoryRecord{TaskName: "old"}); err != nil {
+		t.Fatalf("SaveHistory: %v", err)
+	}
+
+	records := []*models.HistoryRecord{{TaskName: "newest"}, {TaskName: "middle"}, {TaskName: "oldest"}}
+	for i := 0; i < 2; i++ {
+		if err := s.ReplaceHistory(ctx, records); err != nil {
+			t.Fatalf("ReplaceHistory: %v", err)
+		}
+		var names []string
+		for _, record := range mustHistory(t, s, 0, -1) {
+			names = append(names, record.TaskName)
+		}
+		if fmt.Sprint(names) != "[newest middle oldest]" {
+			t.Fatalf("history after ReplaceHistory #%d = %v", i+1, names)
+		}
+	}
+
+	if err := s.ReplaceHistory(ctx, nil); err != nil {
+		t.Fatalf("ReplaceHistory(nil): %v", err)
+	}
+	if history := mustHistory(t, s, 0, -1); len(history) != 0 {
+		t.Fatalf("ReplaceHistory(nil) left %d records", len(history))
+	}
+}
+
+func testTrimHistory(t *testing.T, s database.ResultStore) {
+	ctx := context.Background()
+	for _, name := range []string{"oldest", "middle", "newest"} {
+		if err := s.SaveHistory(ctx, &models.HistoryRecord{TaskName: name}); err != nil {
+			t.Fatalf("SaveHistory: %v", err)
+		}
+	}
+	if err := s.TrimHistory(ctx, 5); err != nil {
+		t.Fatalf("TrimHistory(5): %v", err)
+	}
+	if history := mustHistory(t, s, 0, -1); len(history) != 3 {
+		t.Fatalf("TrimHistory above the length left %d records, want 3", len(history))
+	}
+	if err := s.TrimHistory(ctx, 2); err != nil {
+		t.Fatalf("TrimHistory(2): %v", err)
+	}
+	var names []string
+	for _, record := range mustHistory(t, s, 0, -1) {
+		names = append(names, record.TaskName)
+	}
+	if fmt.Sprint(names) != "[newest middle]" {
+		t.Fatalf("history after TrimHistory(2) = %v", names)
+	}
+	if err := s.TrimHistory(ctx, 0); err != nil {
+		t.Fatalf("TrimHistory(0): %v", err)
+	}
+	if history := mustHistory(t, s, 0, -1); len(history) != 0 {
+		t.Fatalf("TrimHistory(0) left %d records", len(history))
+	}
+}
+
+func testTaskIDCounter(t *testing.T, s database.ResultStore) {
+	ctx := context.Background()
+	if n, err := s.GetTaskIDCounter(ctx); err != nil || n != 0 {
+		t.Fatalf("GetTaskIDCounter on empty store = %d, %v, want 0", n, err)
+	}
+	if err := s.RestoreTaskIDCounter(ctx, 41); err != nil {
+		t.Fatalf("RestoreTaskIDCounter: %v", err)
+	}
+	if err := s.RestoreTaskIDCounter(ctx, 10); err != nil {
+		t.Fatalf("RestoreTaskIDCounter lower: %v", err)
+	}
+	if n, err := s.GetTaskIDCounter(ctx); err != nil || n != 41 {
+		t.Fatalf("GetTaskIDCounter = %d, %v, want 41 (counter must never go back)", n, err)
+	}
+	if id, err := s.IncrementTaskID(ctx); err != nil || id != 42 {
+		t.Fatalf("IncrementTaskID after restore = %d, %v, want 42", id, err)
+	}
+}
+
+func mustSave(t *testing.T, s database.ResultStore, result *models.TaskResult) {
+	t.Helper()
+	if err := s.SaveResult(context.Background(), result); err != nil {
+		t.Fatalf("SaveResult(%s, %s): %v", result.TaskID, result.Status, err)
+	}
+}
+
+func mustHistory(t *testing.T, s database.ResultStore, start, end int64) []*models.HistoryRecord {
+	t.Helper()
+	history, err := s.GetHistory(context.Background(), start, end)
+	if err != nil {
+		t.Fatalf("GetHistory(%d, %d): %v", start, end, err)
+	}
+	return history
+}
+
+func runningIDs(t *testing.T, s database.ResultStore) []string {
+	t.Helper()
+	tasks, err := s.GetRunningTasks(context.Background())
+	if err != nil {
+		t.Fatalf("GetRunningTasks: %v", err)
+	}
+	ids := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.TaskID)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -4,17 +4,37 @@ import (
 	"encoding/json"
 
 	"github.com/sirupsen/logrus"
+
+	"web_test/internal/selection"
+	"web_test/pkg/models"
 )
 
 type Config struct {
 	App       AppConfig      `yaml:"app" valid:"required"`
+	Database  DatabaseConfig `yaml:"database"`
+	Queue     QueueConfig    `yaml:"queue"`
 	Redis     RedisConfig    `yaml:"redis" valid:"required"`
 	WebServer WebServer      `yaml:"webserver" valid:"required"`
 	Executor  ExecutorConfig `yaml:"executor"`
+	GitHub    GitHubConfig   `yaml:"github"`
+	// TestSelection 依 PR 變更的檔案挑選要執行的測試與 ULCL 環境
+	TestSelection TestSelectionConfig `yaml:"test_selection"`
 }
 
 type AppConfig struct {
 	LogLevel string `yaml:"log_level" valid:"required"`
+	Timezone string `yaml:"timezone"` // API 顯示時間使用的 IANA 時區，資料一律以 UTC Unix 時間儲存
+}
+
+// DatabaseConfig 選擇 ResultStore 後端
+type DatabaseConfig struct {
+	Backend string `yaml:"backend"` // "redis"（預設）或 "memory"
+}
+
+// QueueConfig 選擇任務佇列後端
+type QueueConfig struct {
+	// "memory"（預設）只能在同一個行程內共用，serve 與 work 分開執行時須使用 "redis"
+	Backend string `yaml:"backend"`
 }
 
 type RedisConfig struct {
@@ -24,14 +44,57 @@ type RedisConfig struct {
 }
 
 type WebServer struct {
-	Port string `yaml:"port" valid:"required"`
+	Port      string     `yaml:"port" valid:"required"`
+	PublicURL string     `yaml:"public_url"` // 對外網址，用於回報到 GitHub 的預覽頁連結
+	Auth      AuthConfig `yaml:"auth"`
+	// StaticDir 不為空時由此目錄提供前端檔案（開發用），否則使用編譯時嵌入的檔案
+	StaticDir string `yaml:"static_dir"`
+}
+
+// AuthConfig 設定 /api 的 token 驗證
+type AuthConfig struct {
+	Enabled    bool   `yaml:"enabled"`              // 啟用後 /api（webhook 除外）需要 Bearer token
+	AdminToken string `yaml:"admin_token" json:"-"` // 具 admin 權限的固定 token，未設定時讀取 WEB_TEST_ADMIN_TOKEN
 }
 
 type ExecutorConfig struct {
 	TaskTimeout string `yaml:"task_timeout"`
 	RetryDelay  string `yaml:"retry_delay"`
+	// 以下路徑空值時使用預設值，相對路徑以工作目錄為準
+	Script  string `yaml:"script"`   // run_task.sh，預設為工作目錄下的 run_task.sh
+	LogsDir string `yaml:"logs_dir"` // 測試 log 與 failures.json，預設為 run_task.sh 所在目錄的 logs
+	CIDir   string `yaml:"ci_dir"`   // ci-test 目錄，預設為 CI_WORK_DIR 或 run_task.sh 所在目錄的 ci-test
+	// ProbePort 不為空時，只執行 executor（work）的行程在此 port 提供 /livez、/readyz 與 /metrics
+	ProbePort string `yaml:"probe_port"`
 }
 
+type TestSelectionConfig struct {
+	Enabled bool             `yaml:"enabled"` // 停用時一律執行完整測試
+	Tests   []string         `yaml:"tests"`   // 可選的全部測試，預設為 run_task.sh 的 TEST_POOL
+	Envs    []string         `yaml:"envs"`    // 可選的全部 ULCL 環境，預設為 run_task.sh 的 TEST_ENVS
+	Rules   []selection.Rule `yaml:"rules"`   // 不符合任何規則的檔案會讓任務執行完整測試
+}
+
+type GitHubConfig struct {
+	BaseURL        string          `yaml:"base_url"`                // REST API 位址，GitHub Enterprise 為 https://<host>/api/v3
+	Token          string          `yaml:"token" json:"-"`          // API token，未設定時讀取 GITHUB_TOKEN 環境變數
+	PrCacheTTL     string          `yaml:"pr_cache_ttl"`            // PR 快取有效時間，過期後以 ETag 條件式更新
+	WebhookSecret  string          `yaml:"webhook_secret" json:"-"` // 驗證 X-Hub-Signature-256，未設定時讀取 GITHUB_WEBHOOK_SECRET
+	TriggerLabel   string          `yaml:"trigger_label"`           // 不為空時，只有帶此 label 的 PR 會自動觸發
+	TriggerCommand string          `yaml:"trigger_command"`         // PR 留言觸發 CI 的指令，預設 "/ci run"
+	ReportResults  bool            `yaml:"report_results"`          // 任務結束時回報 commit status 與 PR 摘要留言（需 token）
+	StatusContext  string          `yaml:"status_context"`          // commit status 的 context 名稱
+	Owner          string          `yaml:"owner"`                   // NF repo 的預設 owner
+	BaseRepo       string          `yaml:"base_repo"`               // ci-operation.sh pull 取出的 free5gc 主 repo，UI 由此列出可選的 release
+	NFRepos        []models.NFRepo `yaml:"nf_repos"`                // 可測試的 NF 與其 repo，省略 owner/repo 時使用預設值
+}
+
+// defaultNFs 為未設定 nf_repos 時使用的 free5gc NF 列表
+var defaultNFs = []string{"amf", "ausf", "chf", "nef", "nrf", "nssf", "pcf", "smf", "udm", "udr", "upf", "webconsole"}
+
+// defaultNFRepoNames 為 repo 名稱與 NF 名稱不同的例外
+var defaultNFRepoNames = map[string]string{"upf": "go-upf"}
+
 // Print 輸出載入的設定
 func (c *Config) Print() {
 	b, err := json.MarshalIndent(c, "", "  ")
@@ -1,85 +1,317 @@
-package factory
-
-import (
-	"fmt"
-	"os"
-
-	"web_test/internal/executor"
-	"web_test/internal/server"
-	"web_test/pkg/database"
-	"web_test/pkg/queue"
-
-	"github.com/pkg/errors"
-	"gopkg.in/yaml.v3"
-)
-
-// Factory 負責依賴注入的容器
-type Factory struct {
-	cfg *Config
-}
-
-// ReadConfig 讀取 YAML 設定檔
-func ReadConfig(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
-	}
-
-	cfg := &Config{}
-	if err := yaml.Unmarshal(data, cfg); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
-	}
-	if cfg.Executor.TaskTimeout == "" {
-		cfg.Executor.TaskTimeout = "300s"
-	}
-	if cfg.Executor.RetryDelay == "" {
-		cfg.Executor.RetryDelay = "1s"
-	}
-
-	cfg.Print()
-	return cfg, nil
-}
-
-// InitConfigFactory 負責底層讀檔與解析
-func InitConfigFactory(path string, cfg *Config) error {
-
-	content, err := os.ReadFile(path)
-	if err != nil {
-		return errors.Errorf("[Factory] ReadFile error: %+v", err)
-	}
-
-	// 注意：這裡用 yaml.Unmarshal，所以 Struct Tag 必須是 `yaml:"..."`
-	if err := yaml.Unmarshal(content, cfg); err != nil {
-		return errors.Errorf("[Factory] Unmarshal error: %+v", err)
-	}
-
-	return nil
-}
-
-func NewFactory(cfg *Config) *Factory {
-	return &Factory{
-		cfg: cfg,
-	}
-}
-
-func (f *Factory) NewDB() database.ResultStore {
-	return database.NewRedisDB(
-		f.cfg.Redis.Addr,
-		f.cfg.Redis.Password,
-		f.cfg.Redis.DB,
-	)
-}
-
-func (f *Factory) NewTaskQueue() queue.TaskQueue {
-	return queue.NewQueue()
-}
-
-func (f *Factory) NewTaskExecutor(redisDB database.ResultStore, taskQueue queue.TaskQueue) *executor.TaskExecutor {
-	var store database.ResultStore = redisDB
-	exec := executor.NewTaskExecutor(store, taskQueue)
-	return exec
-}
-
-func (f *Factory) NewWebServer(redisDB database.ResultStore, taskQueue queue.TaskQueue) *server.WebServer {
-	return server.NewWebServer(f.cfg.WebServer.Port, redisDB, taskQueue)
-}
+package factory
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"web_test/internal/events"
+	"web_test/internal/executor"
+	"web_test/internal/github"
+	"web_test/internal/logger"
+	"web_test/internal/metrics"
+	"web_test/internal/reporter"
+	"web_test/internal/selection"
+	"web_test/internal/server"
+	"web_test/pkg/database"
+	"web_test/pkg/models"
+	"web_test/pkg/queue"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultTaskTimeout 為 executor.task_timeout 的預設值，需涵蓋完整測試（testAll 與所有 ULCL 環境）
+const defaultTaskTimeout = "3h"
+
+// ResultStore 後端名稱
+const (
+	BackendRedis  = "redis"
+	BackendMemory = "memory"
+)
+
+// Factory 負責依賴注入的容器
+type Factory struct {
+	cfg *Config
+	bus *events.Bus // 執行器與 web server 共用的事件匯流排，分開執行時由 RunEventRelay 轉送
+	// exec 為 NewTaskExecutor 建立的 executor，供 web server 的 readiness 檢查
+	exec *executor.TaskExecutor
+}
+
+// ReadConfig 讀取 YAML 設定檔
+func ReadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cfg := &Config{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	if cfg.Executor.TaskTimeout == "" {
+		cfg.Executor.TaskTimeout = defaultTaskTimeout
+	}
+	if cfg.Executor.RetryDelay == "" {
+		cfg.Executor.RetryDelay = "1s"
+	}
+	if cfg.GitHub.PrCacheTTL == "" {
+		cfg.GitHub.PrCacheTTL = "10m"
+	}
+	if cfg.GitHub.BaseURL == "" {
+		cfg.GitHub.BaseURL = github.DefaultBaseURL
+	}
+	if cfg.GitHub.Token == "" {
+		cfg.GitHub.Token = os.Getenv(github.TokenEnv)
+	}
+	if cfg.GitHub.WebhookSecret == "" {
+		cfg.GitHub.WebhookSecret = os.Getenv("GITHUB_WEBHOOK_SECRET")
+	}
+	if cfg.WebServer.Auth.AdminToken == "" {
+		cfg.WebServer.Auth.AdminToken = os.Getenv("WEB_TEST_ADMIN_TOKEN")
+	}
+	if cfg.GitHub.TriggerCommand == "" {
+		cfg.GitHub.TriggerCommand = "/ci run"
+	}
+	if cfg.GitHub.Owner == "" {
+		cfg.GitHub.Owner = "free5gc"
+	}
+	if cfg.GitHub.BaseRepo == "" {
+		cfg.GitHub.BaseRepo = "free5gc/free5gc"
+	}
+	if len(cfg.GitHub.NFRepos) == 0 {
+		for _, nf := range defaultNFs {
+			cfg.GitHub.NFRepos = append(cfg.GitHub.NFRepos, models.NFRepo{NF: nf, Repo: defaultNFRepoNames[nf]})
+		}
+	}
+	for i := range cfg.GitHub.NFRepos {
+		repo := &cfg.GitHub.NFRepos[i]
+		if repo.Owner == "" {
+			repo.Owner = cfg.GitHub.Owner
+		}
+		if repo.Repo == "" {
+			repo.Repo = repo.NF
+		}
+	}
+	if cfg.App.Timezone == "" {
+		cfg.App.Timezone = "Asia/Taipei"
+	}
+	if cfg.Database.Backend == "" {
+		cfg.Database.Backend = BackendRedis
+	}
+	if cfg.Queue.Backend == "" {
+		cfg.Queue.Backend = BackendMemory
+	}
+
+	cfg.Print()
+	return cfg, nil
+}
+
+// InitConfigFactory 負責底層讀檔與解析
+func InitConfigFactory(path string, cfg *Config) error {
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Errorf("[Factory] ReadFile error: %+v", err)
+	}
+
+	// 注意：這裡用 yaml.Unmarshal，所以 Struct Tag 必須是 `yaml:"..."`
+	if err := yaml.Unmarshal(content, cfg); err != nil {
+		return errors.Errorf("[Factory] Unmarshal error: %+v", err)
+	}
+
+	return nil
+}
+
+func NewFactory(cfg *Config) *Factory {
+	return &Factory{
+		cfg: cfg,
+		bus: events.NewBus(),
+	}
+}
+
+// Config 回傳 Factory 使用的設定
+func (f *Factory) Config() *Config {
+	return f.cfg
+}
+
+// NewDB 依設定的後端建立 ResultStore
+func (f *Factory) NewDB() database.ResultStore {
+	db, err := f.NewDBBackend(f.cfg.Database.Backend)
+	if err != nil {
+		logger.MainLog.Warnf("%v, falling back to %s", err, BackendRedis)
+		db, _ = f.NewDBBackend(BackendRedis)
+	}
+	return metrics.NewStore(db)
+}
+
+// NewDBBackend 建立指定後端的 ResultStore，供維護指令在服務行程外開啟
+func (f *Factory) NewDBBackend(backend string) (database.ResultStore, error) {
+	switch backend {
+	case BackendRedis:
+		return database.NewRedisDB(
+			f.cfg.Redis.Addr,
+			f.cfg.Redis.Password,
+			f.cfg.Redis.DB,
+		), nil
+	case BackendMemory:
+		return database.NewMemoryDB(), nil
+	default:
+		return nil, errors.Errorf("unknown database backend %q", backend)
+	}
+}
+
+// NewTaskQueue 依 queue.backend 建立任務佇列，設定錯誤時使用 memory
+func (f *Factory) NewTaskQueue() queue.TaskQueue {
+	var taskQueue queue.TaskQueue
+	switch f.cfg.Queue.Backend {
+	case BackendRedis:
+		redisQueue := queue.NewRedisQueue(f.cfg.Redis.Addr, f.cfg.Redis.Password, f.cfg.Redis.DB)
+		server.SetQueueProbe(redisQueue)
+		taskQueue = redisQueue
+	default:
+		if f.cfg.Queue.Backend != BackendMemory {
+			logger.MainLog.Warnf("Unknown queue backend %q, falling back to %s", f.cfg.Queue.Backend, BackendMemory)
+		}
+		taskQueue = queue.NewQueue()
+	}
+	metrics.SetQueueLength(func() (int, error) {
+		tasks, err := taskQueue.GetTasks(context.Background())
+		return len(tasks), err
+	})
+	return taskQueue
+}
+
+// SharedQueue 回報佇列是否可跨行程共用，web server 與 executor 分開執行時需要
+func (f *Factory) SharedQueue() bool {
+	return f.cfg.Queue.Backend == BackendRedis
+}
+
+// RunEventRelay 在 queue.backend 為 redis 時經由 Redis pub/sub 轉送事件，讓分開執行的 web server 與 executor
+// 收到彼此發布的事件，直到 ctx 結束；memory 佇列只能在單一行程使用，不需要轉送
+func (f *Factory) RunEventRelay(ctx context.Context) {
+	if !f.SharedQueue() {
+		return
+	}
+	events.NewRedisRelay(f.cfg.Redis.Addr, f.cfg.Redis.Password, f.cfg.Redis.DB).Run(ctx, f.bus)
+}
+
+func (f *Factory) NewTaskExecutor(redisDB database.ResultStore, taskQueue queue.TaskQueue) *executor.TaskExecutor {
+	store := events.NewStore(redisDB, f.bus)
+	exec := executor.NewTaskExecutor(store, events.NewQueue(taskQueue, f.bus))
+	exec.SetPaths(executor.Paths{
+		Script:  f.cfg.Executor.Script,
+		LogsDir: f.cfg.Executor.LogsDir,
+		CIDir:   f.cfg.Executor.CIDir,
+	})
+	if r := f.NewReporter(); r != nil {
+		exec.SetReporter(r)
+	}
+	f.exec = exec
+	return exec
+}
+
+// NewReporter 依設定建立結果回報對象，未啟用時回傳 nil
+func (f *Factory) NewReporter() reporter.Reporter {
+	if !f.cfg.GitHub.ReportResults {
+		return nil
+	}
+	client := f.NewGitHubClient()
+	if !client.Authenticated() {
+		logger.MainLog.Warnf("github.report_results is enabled but no GitHub token is configured, results will not be reported")
+		return nil
+	}
+	return reporter.NewGitHubReporter(client, f.cfg.GitHub.NFRepos, f.cfg.WebServer.PublicURL, f.cfg.GitHub.StatusContext)
+}
+
+func (f *Factory) NewWebServer(redisDB database.ResultStore, taskQueue queue.TaskQueue) *server.WebServer {
+	if err := server.SetDisplayTimezone(f.cfg.App.Timezone); err != nil {
+		logger.MainLog.Warnf("Invalid app.timezone %q, using UTC: %v", f.cfg.App.Timezone, err)
+	}
+	if ttl, err := time.ParseDuration(f.cfg.GitHub.PrCacheTTL); err == nil {
+		server.SetPrCacheTTL(ttl)
+	} else {
+		logger.MainLog.Warnf("Invalid github.pr_cache_ttl %q: %v", f.cfg.GitHub.PrCacheTTL, err)
+	}
+	server.SetStaticDir(f.cfg.WebServer.StaticDir)
+	server.SetNFRepos(f.cfg.GitHub.NFRepos)
+	server.SetBaseRepo(f.cfg.GitHub.BaseRepo)
+	server.SetTestSelector(f.NewTestSelector())
+	server.SetGitHubClient(f.NewGitHubClient())
+	server.SetWebhookConfig(f.cfg.GitHub.WebhookSecret, f.cfg.GitHub.TriggerLabel, f.cfg.GitHub.TriggerCommand)
+	if auth := f.cfg.WebServer.Auth; auth.Enabled && auth.AdminToken == "" {
+		logger.MainLog.Warnf("webserver.auth is enabled without admin_token, only tokens already stored can access the API")
+	}
+	server.SetAuthConfig(f.cfg.WebServer.Auth.Enabled, f.cfg.WebServer.Auth.AdminToken)
+	server.SetEventBus(f.bus)
+	if f.exec != nil {
+		server.SetExecutorProbe(f.exec, f.TaskTimeout())
+	}
+	return server.NewWebServer(f.cfg.WebServer.Port, events.NewStore(redisDB, f.bus), events.NewQueue(taskQueue, f.bus))
+}
+
+// NewProbeServer 建立只執行 executor 的行程使用的 /livez、/readyz 與 /metrics 伺服器，
+// executor.probe_port 未設定時回傳 nil。需在 NewTaskExecutor 之後呼叫
+func (f *Factory) NewProbeServer(db database.ResultStore) *server.WebServer {
+	if f.cfg.Executor.ProbePort == "" {
+		return nil
+	}
+	if f.exec != nil {
+		server.SetExecutorProbe(f.exec, f.TaskTimeout())
+	}
+	return server.NewProbeServer(f.cfg.Executor.ProbePort, db)
+}
+
+// TaskTimeout 回傳 executor.task_timeout，設定錯誤時使用預設值
+func (f *Factory) TaskTimeout() time.Duration {
+	timeout, err := time.ParseDuration(f.cfg.Executor.TaskTimeout)
+	if err != nil {
+		logger.MainLog.Warnf("Invalid executor.task_timeout %q, using %s: %v", f.cfg.Executor.TaskTimeout, defaultTaskTimeout, err)
+		timeout, _ = time.ParseDuration(defaultTaskTimeout)
+	}
+	return timeout
+}
+
+// NewTestSelector 依 test_selection 建立測試挑選規則，停用或設定錯誤時回傳 nil（完整測試）
+func (f *Factory) NewTestSelector() *selection.Selector {
+	cfg := f.cfg.TestSelection
+	if !cfg.Enabled {
+		return nil
+	}
+	s, err := selection.NewSelector(cfg.Rules, cfg.Tests, cfg.Envs)
+	if err != nil {
+		logger.MainLog.Errorf("Invalid test_selection, running the full test set: %v", err)
+		return nil
+	}
+	return s
+}
+
+// NewGitHubClient 建立 GitHub API client
+func (f *Factory) NewGitHubClient() *github.Client {
+	client := github.NewClient(f.cfg.GitHub.BaseURL, f.cfg.GitHub.Token)
+	if !client.Authenticated() {
+		logger.MainLog.Warnf("No GitHub token configured (github.token or %s), API calls are limited to 60 per hour", github.TokenEnv)
+	}
+	return client
+}
+
+// MigrateStore 執行所有資料遷移，可重複執行
+func (f *Factory) MigrateStore(ctx context.Context, db database.ResultStore) error {
+	n, err := database.MigrateHistoryTimestamps(ctx, db, database.LegacyLocation)
+	if err != nil {
+		return errors.Wrap(err, "migrate history timestamps")
+	}
+	if n > 0 {
+		logger.MainLog.Infof("Migrated %d history records to Unix timestamps", n)
+	}
+	// PR 快取已改為各 repo 分開儲存，舊的全域快取不再被讀取
+	deleted, err := db.DeleteLegacyPrCache(ctx)
+	if err != nil {
+		return errors.Wrap(err, "delete legacy PR cache")
+	}
+	if deleted {
+		logger.MainLog.Info("Deleted the legacy global PR cache, PR lists are now cached per repo")
+	}
+	return nil
+}
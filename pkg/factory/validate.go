@@ -0,0 +1,95 @@
+package factory
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"web_test/internal/selection"
+)
+
+// Validate 檢查 ReadConfig 補上預設值後的設定，回傳所有錯誤；啟動時這些錯誤只會記錄警告並改用預設值，
+// 因此部署前應以 `web_test config validate` 確認
+func (c *Config) Validate() []error {
+	var errs []error
+	addErr := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	for _, backend := range []struct{ name, value string }{
+		{"database.backend", c.Database.Backend},
+		{"queue.backend", c.Queue.Backend},
+	} {
+		if backend.value != BackendRedis && backend.value != BackendMemory {
+			addErr("%s: unknown backend %q, want %s or %s", backend.name, backend.value, BackendRedis, BackendMemory)
+		}
+	}
+	if (c.Database.Backend == BackendRedis || c.Queue.Backend == BackendRedis) && c.Redis.Addr == "" {
+		addErr("redis.addr is required when database.backend or queue.backend is %s", BackendRedis)
+	}
+
+	if err := validPort(c.WebServer.Port); err != nil {
+		addErr("webserver.port: %v", err)
+	}
+	if c.Executor.ProbePort != "" {
+		if err := validPort(c.Executor.ProbePort); err != nil {
+			addErr("executor.probe_port: %v", err)
+		}
+	}
+
+	for _, duration := range []struct{ name, value string }{
+		{"executor.task_timeout", c.Executor.TaskTimeout},
+		{"executor.retry_delay", c.Executor.RetryDelay},
+		{"github.pr_cache_ttl", c.GitHub.PrCacheTTL},
+	} {
+		if d, err := time.ParseDuration(duration.value); err != nil || d < 0 {
+			addErr("%s: invalid duration %q", duration.name, duration.value)
+		}
+	}
+	if _, err := time.LoadLocation(c.App.Timezone); err != nil {
+		addErr("app.timezone: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, repo := range c.GitHub.NFRepos {
+		if repo.NF == "" {
+			addErr("github.nf_repos: entry without nf")
+			continue
+		}
+		if seen[repo.NF] {
+			addErr("github.nf_repos: duplicate nf %q", repo.NF)
+		}
+		seen[repo.NF] = true
+	}
+
+	if ts := c.TestSelection; ts.Enabled {
+		if _, err := selection.NewSelector(ts.Rules, ts.Tests, ts.Envs); err != nil {
+			addErr("test_selection: %v", err)
+		}
+	}
+	return errs
+}
+
+// ValidateSplit 檢查以 serve 與 work 分開執行時的設定：兩個行程只能經由 Redis 共用佇列與 ResultStore，
+// memory 後端的任務結果、進度與取消要求只存在於寫入的行程內
+func (c *Config) ValidateSplit() []error {
+	var errs []error
+	for _, backend := range []struct{ name, value string }{
+		{"database.backend", c.Database.Backend},
+		{"queue.backend", c.Queue.Backend},
+	} {
+		if backend.value != BackendRedis {
+			errs = append(errs, fmt.Errorf("%s: serve and work need %s to share it, %q only lives inside one process",
+				backend.name, BackendRedis, backend.value))
+		}
+	}
+	return errs
+}
+
+func validPort(port string) error {
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	return nil
+}
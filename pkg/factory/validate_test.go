@@ -0,0 +1,76 @@
+package factory
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	// 專案附的 config.yml 必須通過檢查
+	cfg, err := ReadConfig("../../config.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := cfg.Validate(); len(errs) != 0 {
+		t.Fatalf("config.yml: %v", errs)
+	}
+
+	path := filepath.Join(t.TempDir(), "bad.yml")
+	bad := `
+database: { backend: mysql }
+queue: { backend: redis }
+webserver: { port: "80a" }
+executor: { task_timeout: "3 hours", probe_port: "70000" }
+app: { timezone: "Mars/Olympus" }
+github: { nf_repos: [{ nf: amf }, { nf: amf }] }
+`
+	if err := os.WriteFile(path, []byte(bad), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = ReadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msgs []string
+	for _, err := range cfg.Validate() {
+		msgs = append(msgs, err.Error())
+	}
+	got := strings.Join(msgs, "\n")
+	for _, want := range []string{
+		`database.backend: unknown backend "mysql"`,
+		"redis.addr is required",
+		`webserver.port: invalid port "80a"`,
+		`executor.probe_port: invalid port "70000"`,
+		`executor.task_timeout: invalid duration "3 hours"`,
+		"app.timezone:",
+		`duplicate nf "amf"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Validate errors missing %q:\n%s", want, got)
+		}
+	}
+	if len(msgs) != 7 {
+		t.Errorf("Validate returned %d errors, want 7:\n%s", len(msgs), got)
+	}
+}
+
+func TestValidateSplit(t *testing.T) {
+	cfg := &Config{}
+	cfg.Database.Backend, cfg.Queue.Backend = BackendRedis, BackendRedis
+	if errs := cfg.ValidateSplit(); len(errs) != 0 {
+		t.Fatalf("redis store and queue: %v", errs)
+	}
+
+	// 任務結果只存在 executor 行程內時，web server 看不到結果、進度與取消要求
+	cfg.Database.Backend = BackendMemory
+	errs := cfg.ValidateSplit()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "database.backend") {
+		t.Fatalf("memory store: %v, want a database.backend error", errs)
+	}
+	cfg.Queue.Backend = BackendMemory
+	if errs := cfg.ValidateSplit(); len(errs) != 2 {
+		t.Fatalf("memory store and queue: %v, want 2 errors", errs)
+	}
+}
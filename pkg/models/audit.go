@@ -0,0 +1,33 @@
+package models
+
+// 任務的觸發來源
+const (
+	SourceUI       = "ui"       // 網頁或直接呼叫 API
+	SourceWebhook  = "webhook"  // GitHub webhook
+	SourceSchedule = "schedule" // 排程
+	SourceCLI      = "cli"      // 命令列工具
+)
+
+// ValidSource 回傳 API 請求可以宣告的來源；webhook 與排程由伺服器內部設定
+func ValidSource(source string) bool {
+	return source == SourceUI || source == SourceCLI
+}
+
+// 稽核紀錄的動作
+const (
+	AuditEnqueue = "enqueue" // 任務加入佇列
+	AuditDelete  = "delete"  // 從佇列刪除任務
+	AuditCancel  = "cancel"  // 取消執行中的任務
+	AuditConfig  = "config"  // 設定或資料的變更（token、PR 快取、備份還原）
+)
+
+// AuditEntry 是一筆只會新增、不會修改的稽核紀錄
+type AuditEntry struct {
+	At     int64  `json:"at"`             // UTC Unix 秒
+	Time   string `json:"time,omitempty"` // 顯示用字串，由 API 依時區產生，不會儲存
+	Actor  string `json:"actor"`          // 與 Task.SubmittedBy 相同格式
+	Source string `json:"source"`
+	Action string `json:"action"`
+	Target string `json:"target"` // 例如 task:12、pr_cache:free5gc/amf、api_token:<id>
+	Detail string `json:"detail,omitempty"`
+}
@@ -0,0 +1,31 @@
+package models
+
+// API token 的角色，權限由低到高
+const (
+	RoleViewer    = "viewer"    // 只能查詢佇列、歷史紀錄、PR 與下載 log
+	RoleSubmitter = "submitter" // 另可加入、刪除佇列中的任務與更新 PR 快取
+	RoleAdmin     = "admin"     // 另可清除快取、備份還原與管理 token
+)
+
+var roleLevels = map[string]int{RoleViewer: 1, RoleSubmitter: 2, RoleAdmin: 3}
+
+// ValidRole 回傳 role 是否為已定義的角色
+func ValidRole(role string) bool {
+	_, ok := roleLevels[role]
+	return ok
+}
+
+// RoleAllows 回傳 role 是否具有 required 以上的權限
+func RoleAllows(role, required string) bool {
+	have, ok := roleLevels[role]
+	return ok && have >= roleLevels[required]
+}
+
+// APIToken 是一組 API token 的資訊；只儲存 token 的 SHA-256，明文只在建立時回傳一次
+type APIToken struct {
+	ID        string `json:"id"` // token SHA-256 的前 16 個十六進位字元
+	Name      string `json:"name"`
+	Role      string `json:"role"`
+	Hash      string `json:"hash,omitempty"` // 回傳給 API 前清空
+	CreatedAt int64  `json:"created_at"`     // UTC Unix 秒
+}
@@ -4,22 +4,51 @@ package models
 type TaskParams struct {
 	NF        string `json:"nf"`
 	PRVersion string `json:"pr_version"`
+	HeadSHA   string `json:"head_sha,omitempty"` // 加入佇列時的 PR head commit，fetch 階段確認取出的正是此 commit
 }
 
 // Task 定義從 Web Server 收到的任務
 type Task struct {
 	ID     string       `json:"id"`
 	Params []TaskParams `json:"params"`
+	// BaseRef 為 pull 階段取出的 free5gc release tag、分支或 commit，空值為預設分支
+	BaseRef string `json:"base_ref,omitempty"`
+	// Selection 為要執行的測試與環境，nil 代表完整測試
+	Selection *TestSelection `json:"selection,omitempty"`
+	// SubmittedBy 為加入任務的身分（token 名稱、github:<login> 或 anonymous@<IP>）
+	SubmittedBy string `json:"submitted_by,omitempty"`
+	Source      string `json:"source,omitempty"`    // 見 audit.go 的觸發來源
+	QueuedAt    int64  `json:"queued_at,omitempty"` // 加入佇列的時間 (UTC Unix 秒)
+}
+
+// TestSelection 是依 PR 變更檔案挑選（或使用者指定）的測試範圍
+type TestSelection struct {
+	Tests   []string `json:"tests,omitempty"` // run_task.sh 的 TEST_POOL，Full 時為空
+	Envs    []string `json:"envs,omitempty"`  // ULCL 環境，Full 時為空
+	Full    bool     `json:"full"`            // 執行完整測試
+	Manual  bool     `json:"manual,omitempty"`
+	Reasons []string `json:"reasons,omitempty"` // 挑選的依據
 }
 
 // TaskResult 定義回傳給 Web Server 的結果
 type TaskResult struct {
-	TaskID      string       `json:"task_id"`
-	Status      string       `json:"status"` // "success" or "failed or running" or "queueing"
-	Params      []TaskParams `json:"params"`
-	Logs        []string     `json:"logs"`
-	FailedTests []string     `json:"failed_tests,omitempty"` // 修改：多個失敗測試名稱
-	Timestamp   int64        `json:"timestamp"`
+	TaskID      string         `json:"task_id"`
+	Status      string         `json:"status"` // 見 status.go 的狀態定義
+	Params      []TaskParams   `json:"params"`
+	BaseRef     string         `json:"base_ref,omitempty"`  // 測試時的 free5gc 基準，空值為預設分支
+	Selection   *TestSelection `json:"selection,omitempty"` // 執行的測試範圍，nil 代表完整測試
+	SubmittedBy string         `json:"submitted_by,omitempty"`
+	Source      string         `json:"source,omitempty"`
+	Logs        []string       `json:"logs"`
+	FailedTests []string       `json:"failed_tests,omitempty"` // 修改：多個失敗測試名稱
+	FlakyTests  []string       `json:"flaky_tests,omitempty"`  // 失敗後重跑通過的測試
+	Verdict     string         `json:"verdict,omitempty"`      // 見 status.go 的判定結果
+	Timestamp   int64          `json:"timestamp"`              // 最後更新時間 (UTC Unix 秒)
+	StartedAt   int64          `json:"started_at,omitempty"`   // 開始執行時間 (UTC Unix 秒)
+	FinishedAt  int64          `json:"finished_at,omitempty"`  // 結束時間 (UTC Unix 秒)
+	Duration    int64          `json:"duration,omitempty"`     // 執行秒數
+	// Progress 只在佇列列表中由 API 填入，不會儲存
+	Progress *ProgressInfo `json:"progress,omitempty"`
 }
 
 type GitHubTask struct {
@@ -27,9 +56,26 @@ type GitHubTask struct {
 	Repo  string `json:"repo"`
 }
 
+// NFRepo 對應一個 NF 與其所在的 GitHub repo（例如 upf -> free5gc/go-upf）
+type NFRepo struct {
+	NF    string `json:"nf" yaml:"nf"`
+	Owner string `json:"owner" yaml:"owner"`
+	Repo  string `json:"repo" yaml:"repo"`
+}
+
 type PullRequest struct {
-	Number int    `json:"number"`
-	Title  string `json:"title"`
+	Number    int      `json:"number"`
+	Title     string   `json:"title"`
+	Author    string   `json:"author,omitempty"`
+	HeadSHA   string   `json:"head_sha,omitempty"` // 取得列表時的 head commit
+	BaseRef   string   `json:"base_ref,omitempty"` // PR 要合併進的分支
+	Labels    []string `json:"labels,omitempty"`
+	Draft     bool     `json:"draft,omitempty"`
+	UpdatedAt int64    `json:"updated_at,omitempty"` // UTC Unix 秒
+	// MergeableState 為 GitHub 的 mergeable_state（clean、dirty、blocked、unknown 等），只在 head commit 改變時重新取得；
+	// ChangedFiles 在挑選測試需要時才取得，head commit 改變後清空
+	MergeableState string   `json:"mergeable_state,omitempty"`
+	ChangedFiles   []string `json:"changed_files,omitempty"`
 }
 
 type Release struct {
@@ -38,15 +84,48 @@ type Release struct {
 }
 
 type WorkerResponse struct {
-	Summary string        `json:"summary"`
-	PRs     []PullRequest `json:"prs"`
+	Summary       string        `json:"summary"`
+	PRs           []PullRequest `json:"prs"`
+	LatestRelease *Release      `json:"latest_release,omitempty"`
+	ETag          string        `json:"-"` // GitHub 回傳的 ETag
+	NotModified   bool          `json:"-"` // 條件式請求回傳 304，PRs 為空
+}
+
+// PrCacheEntry 是單一 owner/repo 的 PR 快取
+type PrCacheEntry struct {
+	Owner     string        `json:"owner"`
+	Repo      string        `json:"repo"`
+	PRs       []PullRequest `json:"prs"`
+	ETag      string        `json:"etag,omitempty"` // 用於 If-None-Match 條件式更新
+	FetchedAt int64         `json:"fetched_at"`     // 最後確認內容的時間 (UTC Unix 秒)
+	ExpiresAt int64         `json:"expires_at"`     // 超過此時間需重新向 GitHub 確認
 }
 
+// PrCacheKey 回傳快取使用的 owner/repo 鍵值
+func PrCacheKey(owner, repo string) string {
+	return owner + "/" + repo
+}
+
+// Key 回傳此快取的 owner/repo 鍵值
+func (e *PrCacheEntry) Key() string {
+	return PrCacheKey(e.Owner, e.Repo)
+}
+
+// Expired 回報快取在 now 時是否已過期
+func (e *PrCacheEntry) Expired(now int64) bool {
+	return now >= e.ExpiresAt
+}
+
+// ProgressInfo 是執行中任務的進度，由 executor 依 run_task.sh 輸出的進度標記更新
 type ProgressInfo struct {
-	TaskID    int    `json:"task_id"`
-	TaskName  string `json:"task_name"`
-	Percent   int    `json:"percent"`
-	Remaining int    `json:"remaining"`
+	TaskID      string `json:"task_id"`
+	Stage       string `json:"stage,omitempty"`        // 見 progress.go 的執行階段
+	CurrentTest string `json:"current_test,omitempty"` // 正在執行的測試或 ULCL 環境（ulcl:<env>）
+	Completed   int    `json:"completed"`              // 已完成的測試與環境數
+	Total       int    `json:"total"`                  // 本次要執行的測試與環境總數
+	Percent     int    `json:"percent"`
+	Remaining   int64  `json:"remaining"`  // 依歷史測試時間預估的剩餘秒數，-1 表示無法預估
+	UpdatedAt   int64  `json:"updated_at"` // UTC Unix 秒
 }
 
 type GitHubRequest struct {
@@ -55,12 +134,24 @@ type GitHubRequest struct {
 }
 
 type RunPRRequest struct {
-	Params [][]string `json:"params"`
+	Params  [][]string `json:"params"`
+	BaseRef string     `json:"base_ref"` // free5gc release tag、分支或 commit，空值為預設分支
+	// Selection 不為 nil 時以使用者指定的測試範圍取代自動挑選
+	Selection *TestSelection `json:"selection,omitempty"`
 }
 
 type HistoryRecord struct {
-	Time     string       `json:"time"`
-	Params   []TaskParams `json:"params"`
-	TaskName string       `json:"task_name"`
-	Result   string       `json:"result"`
+	TaskID     string `json:"task_id,omitempty"`
+	StartedAt  int64  `json:"started_at,omitempty"`  // UTC Unix 秒
+	FinishedAt int64  `json:"finished_at,omitempty"` // UTC Unix 秒
+	Duration   int64  `json:"duration,omitempty"`    // 秒
+	// Time 為顯示用字串，由 API 依時區產生，不會儲存；
+	// 舊版資料以 Asia/Taipei 字串儲存於此，遷移後清空
+	Time        string       `json:"time,omitempty"`
+	Params      []TaskParams `json:"params"`
+	BaseRef     string       `json:"base_ref,omitempty"`
+	SubmittedBy string       `json:"submitted_by,omitempty"`
+	Source      string       `json:"source,omitempty"`
+	TaskName    string       `json:"task_name"`
+	Result      string       `json:"result"`
 }
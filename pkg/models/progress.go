@@ -0,0 +1,19 @@
+package models
+
+// run_task.sh 的執行階段，依序為 pull、fetch、test_all、build、ulcl、restore、collect；
+// 測試失敗時 retry 可能出現在 test_all 或 ulcl 之後
+const (
+	StagePull    = "pull"
+	StageFetch   = "fetch"
+	StageTestAll = "test_all"
+	StageRetry   = "retry"
+	StageBuild   = "build"
+	StageULCL    = "ulcl"
+	StageRestore = "restore"
+	StageCollect = "collect"
+)
+
+// ULCLTestName 回傳 ULCL 環境在進度與測試時間中使用的名稱
+func ULCLTestName(env string) string {
+	return "ulcl:" + env
+}
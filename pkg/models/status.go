@@ -0,0 +1,71 @@
+package models
+
+// 任務狀態
+const (
+	StatusQueueing = "queueing"
+	StatusRunning  = "running"
+	StatusSuccess  = "Success"
+	StatusFailed   = "Failed"
+)
+
+// 任務結束時的判定結果，依 run_task.sh 的結束碼決定
+const (
+	VerdictPass      = "pass"      // 所有測試通過
+	VerdictPR        = "pr"        // 測試在 release 版本通過，問題出在 PR (exit 3、4)
+	VerdictInfra     = "infra"     // release 版本也失敗，問題出在 CI 環境 (exit 2、5、6、8)
+	VerdictUnknown   = "unknown"   // 其他失敗，無法判斷原因
+	VerdictCancelled = "cancelled" // 執行中被使用者取消
+	VerdictStale     = "stale"     // 加入佇列時的 commit 已取不到，PR 可能被 force-push (exit 9)
+)
+
+// VerdictFromExitCode 將 run_task.sh 的結束碼轉成判定結果
+func VerdictFromExitCode(code int) string {
+	switch code {
+	case 0:
+		return VerdictPass
+	case 3, 4: // 3: PR 造成測試失敗, 4: PR 的 NF 編譯失敗
+		return VerdictPR
+	case 2, 5, 6, 8: // 2: release 也失敗, 5: 取得 release 失敗, 6: 測試目錄不存在, 8: release 的 NF 編譯失敗
+		return VerdictInfra
+	case 9:
+		return VerdictStale
+	default:
+		return VerdictUnknown
+	}
+}
+
+// transitions 定義合法的狀態轉移：queued → running → terminal
+// 空字串代表尚無任何結果
+var transitions = map[string][]string{
+	"":             {StatusQueueing, StatusRunning},
+	StatusQueueing: {StatusRunning},
+	StatusRunning:  {StatusSuccess, StatusFailed},
+}
+
+// IsTerminalStatus 回報狀態是否為最終狀態
+func IsTerminalStatus(status string) bool {
+	return status == StatusSuccess || status == StatusFailed
+}
+
+// CanTransition 回報是否允許從 from 轉移到 to
+func CanTransition(from, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowedPrevious 回傳可以轉移到 to 的所有前一個狀態
+func AllowedPrevious(to string) []string {
+	var prev []string
+	for from, nexts := range transitions {
+		for _, next := range nexts {
+			if next == to {
+				prev = append(prev, from)
+			}
+		}
+	}
+	return prev
+}
@@ -0,0 +1,24 @@
+package models
+
+import "testing"
+
+func TestVerdictFromExitCode(t *testing.T) {
+	for code, want := range map[int]string{
+		0:   VerdictPass,
+		1:   VerdictUnknown,
+		2:   VerdictInfra, // release 也失敗
+		3:   VerdictPR,    // PR 造成測試失敗
+		4:   VerdictPR,    // PR 的 NF 編譯失敗
+		5:   VerdictInfra, // 取得 release 失敗
+		6:   VerdictInfra, // 測試目錄不存在
+		7:   VerdictUnknown,
+		8:   VerdictInfra, // release 的 NF 編譯失敗
+		9:   VerdictStale, // 佇列中的 commit 已取不到
+		143: VerdictUnknown,
+		-1:  VerdictUnknown,
+	} {
+		if got := VerdictFromExitCode(code); got != want {
+			t.Errorf("VerdictFromExitCode(%d) = %q, want %q", code, got, want)
+		}
+	}
+}
@@ -14,16 +14,22 @@ type ListQueue struct {
 	notEmpty chan struct{} // 用於通知有新任務
 }
 
+// NewQueue 回傳全域共用的 ListQueue
 func NewQueue() *ListQueue {
 	if GlobalQueue == nil {
-		GlobalQueue = &ListQueue{
-			tasks:    make([]*models.Task, 0),
-			notEmpty: make(chan struct{}, 1), // 使用緩衝 channel 避免阻塞
-		}
+		GlobalQueue = NewListQueue()
 	}
 	return GlobalQueue.(*ListQueue)
 }
 
+// NewListQueue 建立獨立的 ListQueue（不影響 GlobalQueue）
+func NewListQueue() *ListQueue {
+	return &ListQueue{
+		tasks:    make([]*models.Task, 0),
+		notEmpty: make(chan struct{}, 1), // 使用緩衝 channel 避免阻塞
+	}
+}
+
 func (q *ListQueue) PushTask(ctx context.Context, task *models.Task) error {
 	if task == nil || task.ID == "" {
 		return errors.New("invalid task")
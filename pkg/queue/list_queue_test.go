@@ -0,0 +1,14 @@
+package queue_test
+
+import (
+	"testing"
+
+	"web_test/pkg/queue"
+	"web_test/pkg/queue/queuetest"
+)
+
+func TestListQueue(t *testing.T) {
+	queuetest.TestTaskQueue(t, func(t *testing.T) queue.TaskQueue {
+		return queue.NewListQueue()
+	})
+}
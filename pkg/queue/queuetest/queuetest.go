@@ -0,0 +1,241 @@
+// Package queuetest provides a conformance suite for queue.TaskQueue implementations.
+package queuetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"web_test/pkg/models"
+	"web_test/pkg/queue"
+)
+
+// NewQueueFunc returns an empty queue for a single subtest.
+type NewQueueFunc func(t *testing.T) queue.TaskQueue
+
+// TestTaskQueue runs every conformance check against queues produced by newQueue.
+// Each subtest receives its own empty queue.
+func TestTaskQueue(t *testing.T, newQueue NewQueueFunc) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, q queue.TaskQueue)
+	}{
+		{"RejectsInvalidTask", testRejectsInvalidTask},
+		{"FIFO", testFIFO},
+		{"GetTasksDoesNotConsume", testGetTasksDoesNotConsume},
+		{"RemoveTask", testRemoveTask},
+		{"PopBlocksUntilPush", testPopBlocksUntilPush},
+		{"PopHonoursContext", testPopHonoursContext},
+		{"ConcurrentPushPop", testConcurrentPushPop},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newQueue(t))
+		})
+	}
+}
+
+func testRejectsInvalidTask(t *testing.T, q queue.TaskQueue) {
+	ctx := context.Background()
+	if err := q.PushTask(ctx, nil); err == nil {
+		t.Error("PushTask(nil) must fail")
+	}
+	if err := q.PushTask(ctx, &models.Task{}); err == nil {
+		t.Error("PushTask with empty ID must fail")
+	}
+	if tasks := mustTasks(t, q); len(tasks) != 0 {
+		t.Fatalf("invalid tasks were queued: %v", tasks)
+	}
+}
+
+func testFIFO(t *testing.T, q queue.TaskQueue) {
+	ctx := context.Background()
+	for i := 1; i <= 3; i++ {
+		mustPush(t, q, fmt.Sprint(i))
+	}
+	for i := 1; i <= 3; i++ {
+		task, err := q.PopTask(ctx)
+		if err != nil {
+			t.Fatalf("PopTask: %v", err)
+		}
+		if task.ID != fmt.Sprint(i) {
+			t.Fatalf("PopTask = %s, want %d", task.ID, i)
+		}
+	}
+}
+
+func testGetTasksDoesNotConsume(t *testing.T, q queue.TaskQueue) {
+	mustPush(t, q, "a")
+	mustPush(t, q, "b")
+
+	if got := ids(mustTasks(t, q)); got != "[a b]" {
+		t.Fatalf("GetTasks = %s, want [a b]", got)
+	}
+	if got := ids(mustTasks(t, q)); got != "[a b]" {
+		t.Fatalf("second GetTasks = %s, want [a b]", got)
+	}
+}
+
+func testRemoveTask(t *testing.T, q queue.TaskQueue) {
+	ctx := context.Background()
+	mustPush(t, q, "a")
+	mustPush(t, q, "b")
+	mustPush(t, q, "c")
+
+	if err := q.RemoveTask(ctx, "b"); err != nil {
+		t.Fatalf("RemoveTask: %v", err)
+	}
+	if got := ids(mustTasks(t, q)); got != "[a c]" {
+		t.Fatalf("GetTasks after remove = %s, want [a c]", got)
+	}
+	if err := q.RemoveTask(ctx, "b"); err == nil {
+		t.Error("RemoveTask of a missing task must fail")
+	}
+	if err := q.RemoveTask(ctx, ""); err == nil {
+		t.Error("RemoveTask with empty ID must fail")
+	}
+}
+
+func testPopBlocksUntilPush(t *testing.T, q queue.TaskQueue) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	got := make(chan *models.Task, 1)
+	go func() {
+		task, err := q.PopTask(ctx)
+		if err != nil {
+			t.Errorf("PopTask: %v", err)
+		}
+		got <- task
+	}()
+
+	select {
+	case task := <-got:
+		t.Fatalf("PopTask returned %+v before anything was pushed", task)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	mustPush(t, q, "late")
+	select {
+	case task := <-got:
+		if task == nil || task.ID != "late" {
+			t.Fatalf("PopTask = %+v, want late", task)
+		}
+	case <-ctx.Done():
+		t.Fatal("PopTask did not wake up after PushTask")
+	}
+}
+
+func testPopHonoursContext(t *testing.T, q queue.TaskQueue) {
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := q.PopTask(ctx)
+		errc <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("PopTask after cancel = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("PopTask ignored context cancellation")
+	}
+}
+
+func testConcurrentPushPop(t *testing.T, q queue.TaskQueue) {
+	const producers, perProducer, consumers = 4, 50, 4
+	const total = producers * perProducer
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]int)
+		wg   sync.WaitGroup
+	)
+	popped := make(chan struct{}, total)
+
+	for c := 0; c < consumers; c++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				task, err := q.PopTask(ctx)
+				if err != nil {
+					return
+				}
+				mu.Lock()
+				seen[task.ID]++
+				mu.Unlock()
+				popped <- struct{}{}
+			}
+		}()
+	}
+
+	var pushers sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		pushers.Add(1)
+		go func(p int) {
+			defer pushers.Done()
+			for i := 0; i < perProducer; i++ {
+				task := &models.Task{ID: fmt.Sprintf("%d-%d", p, i)}
+				if err := q.PushTask(ctx, task); err != nil {
+					t.Errorf("PushTask: %v", err)
+				}
+			}
+		}(p)
+	}
+	pushers.Wait()
+
+	for i := 0; i < total; i++ {
+		select {
+		case <-popped:
+		case <-ctx.Done():
+			t.Fatalf("only %d of %d tasks were popped", i, total)
+		}
+	}
+	cancel()
+	wg.Wait()
+
+	if len(seen) != total {
+		t.Fatalf("popped %d distinct tasks, want %d", len(seen), total)
+	}
+	for id, n := range seen {
+		if n != 1 {
+			t.Errorf("task %s popped %d times", id, n)
+		}
+	}
+}
+
+func mustPush(t *testing.T, q queue.TaskQueue, id string) {
+	t.Helper()
+	if err := q.PushTask(context.Background(), &models.Task{ID: id}); err != nil {
+		t.Fatalf("PushTask(%s): %v", id, err)
+	}
+}
+
+func mustTasks(t *testing.T, q queue.TaskQueue) []*models.Task {
+	t.Helper()
+	tasks, err := q.GetTasks(context.Background())
+	if err != nil {
+		t.Fatalf("GetTasks: %v", err)
+	}
+	return tasks
+}
+
+func ids(tasks []*models.Task) string {
+	out := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		out = append(out, task.ID)
+	}
+	return fmt.Sprint(out)
+}
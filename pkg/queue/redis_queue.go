@@ -0,0 +1,118 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"web_test/pkg/models"
+)
+
+// redisQueueKey 為佇列的 Redis list，每個元素為 Task JSON，LPOP 取出最早的任務
+const redisQueueKey = "task_queue"
+
+// redisPopTimeout 為 BLPOP 每次等待的秒數，逾時後檢查 ctx 再繼續等待
+const redisPopTimeout = time.Second
+
+// RedisQueue 以 Redis list 實作 TaskQueue，供 web server 與 executor 在不同主機時共用佇列
+type RedisQueue struct {
+	client *redis.Client
+}
+
+// NewRedisQueue 建立使用 addr 上 Redis / KVRocks 的佇列
+func NewRedisQueue(addr, password string, db int) *RedisQueue {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:             addr,
+		Password:         password,
+		DB:               db,
+		DisableIndentity: true,
+		Protocol:         2,
+	})
+	return &RedisQueue{client: rdb}
+}
+
+func (q *RedisQueue) PushTask(ctx context.Context, task *models.Task) error {
+	if task == nil || task.ID == "" {
+		return errors.New("invalid task")
+	}
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	return q.client.RPush(ctx, redisQueueKey, data).Err()
+}
+
+// PopTask 以 BLPOP 等待任務；多個 executor 同時等待時每個任務只會被取出一次
+func (q *RedisQueue) PopTask(ctx context.Context) (*models.Task, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		values, err := q.client.BLPop(ctx, redisPopTimeout, redisQueueKey).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+		// values 為 [key, value]
+		var task models.Task
+		if err := json.Unmarshal([]byte(values[1]), &task); err != nil {
+			return nil, err
+		}
+		return &task, nil
+	}
+}
+
+func (q *RedisQueue) GetTasks(ctx context.Context) ([]*models.Task, error) {
+	values, err := q.client.LRange(ctx, redisQueueKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	tasks := make([]*models.Task, 0, len(values))
+	for _, value := range values {
+		var task models.Task
+		if err := json.Unmarshal([]byte(value), &task); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, &task)
+	}
+	return tasks, nil
+}
+
+// RemoveTask 以 LREM 刪除與佇列中完全相同的元素，已被取出時回傳錯誤
+func (q *RedisQueue) RemoveTask(ctx context.Context, taskID string) error {
+	if taskID == "" {
+		return errors.New("taskID is empty")
+	}
+	values, err := q.client.LRange(ctx, redisQueueKey, 0, -1).Result()
+	if err != nil {
+		return err
+	}
+	for _, value := range values {
+		var task models.Task
+		if err := json.Unmarshal([]byte(value), &task); err != nil || task.ID != taskID {
+			continue
+		}
+		removed, err := q.client.LRem(ctx, redisQueueKey, 1, value).Result()
+		if err != nil {
+			return err
+		}
+		if removed == 0 {
+			break
+		}
+		return nil
+	}
+	return errors.New("task not found")
+}
+
+// Ping 檢查 Redis 連線
+func (q *RedisQueue) Ping(ctx context.Context) error {
+	return q.client.Ping(ctx).Err()
+}
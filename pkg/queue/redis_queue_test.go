@@ -0,0 +1,37 @@
+package queue_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"web_test/pkg/models"
+	"web_test/pkg/queue"
+	"web_test/pkg/queue/queuetest"
+)
+
+func TestRedisQueue(t *testing.T) {
+	queuetest.TestTaskQueue(t, func(t *testing.T) queue.TaskQueue {
+		return queue.NewRedisQueue(miniredis.RunT(t).Addr(), "", 0)
+	})
+}
+
+// 不同主機的 web server 與 executor 各自建立 RedisQueue，需看到同一個佇列
+func TestRedisQueueShared(t *testing.T) {
+	ctx := context.Background()
+	addr := miniredis.RunT(t).Addr()
+	web, worker := queue.NewRedisQueue(addr, "", 0), queue.NewRedisQueue(addr, "", 0)
+
+	task := &models.Task{ID: "1", Params: []models.TaskParams{{NF: "amf", PRVersion: "12", HeadSHA: "abc"}}, QueuedAt: 100}
+	if err := web.PushTask(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+	got, err := worker.PopTask(ctx)
+	if err != nil || got.ID != "1" || got.Params[0].HeadSHA != "abc" || got.QueuedAt != 100 {
+		t.Fatalf("PopTask from another client = %+v, %v", got, err)
+	}
+	if tasks, err := web.GetTasks(ctx); err != nil || len(tasks) != 0 {
+		t.Fatalf("GetTasks after pop = %v, %v", tasks, err)
+	}
+}
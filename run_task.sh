@@ -11,6 +11,7 @@ SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
 DEFAULT_DIR="${CI_WORK_DIR:-$(cd "$SCRIPT_DIR" && pwd)/ci-test}"
 CI_TARGET_DIR="${CI_WORK_DIR:-$DEFAULT_DIR}"
 CI_SCRIPT_NAME="$CI_TARGET_DIR/ci-operation.sh"
+LOG_DIR="$SCRIPT_DIR/logs" # 收集的測試 log、failures.json 與 flaky.json，-l 指定時取代
 
 # 定義單獨測試腳本的路徑 (相對 CI_TARGET_DIR)
 SINGLE_TEST_DIR="base/free5gc"
@@ -22,9 +23,12 @@ TEST_POOL="TestRegistration|TestGUTIRegistration|TestServiceRequest|TestXnHandov
 # 初始化變數
 CURRENT_ENV=""
 PR_LIST=()
+BASE_REF="" # free5gc 的 release tag、分支或 commit，空值為預設分支
 VERBOSE=false
 REGRESS=true
+SELECTED_ENVS=() # -e 指定時取代 TEST_ENVS
 FAILED_LIST_FILE=$(mktemp)
+FLAKY_TESTS=()
 
 # 定義顏色
 GREEN='\033[0;32m'
@@ -38,6 +42,8 @@ CLEAR_LINE='\r\033[K'
 
 # 輔助函數: 帶時間戳的 Log
 log() { echo -e "[$(date +'%H:%M:%S')] $1"; }
+# 進度標記: 獨佔一行的 "##progress <kind> <value>"，由 executor 解析 (plan/stage/test/done)
+progress() { echo "##progress $1 $2"; }
 
 # ==============================================================================
 # 核心函數: 漂亮的測試執行器 (Pretty Test Runner)
@@ -147,6 +153,7 @@ process_line() {
 # ==============================================================================
 smart_failure_handler() {
     local step_name="$1"  
+    progress stage retry
     # 切換到測試目錄 (ci-test/base/free5gc)
     local test_dir="$CI_TARGET_DIR/$SINGLE_TEST_DIR"
     if [ ! -d "$test_dir" ]; then
@@ -155,14 +162,7 @@ smart_failure_handler() {
     fi
 
     for phase in 1 2; do
-        # 讀取 JSON 內容
-        json_content=$(cat "$SCRIPT_DIR/logs/failures.json")
-        array_part=$(echo "$json_content" | sed 's/.*"failed_tests": \[\([^]]*\)\].*/\1/')
-        if [ -z "$array_part" ]; then
-            failed_list=()
-        else
-            IFS=',' read -ra failed_list <<< "$(echo "$array_part" | tr -d '"' | tr -d ' ')"
-        fi
+        read_failed_list
         if [ $phase -eq 1 ]; then
                 echo -e "\n${CYAN}======================================================${RESET}"
                 echo -e "${CYAN}🤖 機器人啟動: 偵測到 ${#failed_list[@]} 個測試失敗${RESET}"
@@ -176,7 +176,7 @@ smart_failure_handler() {
             # ---------------------------------------------------------
             echo -e "\n${CYAN}⚠️  仍有 ${#failed_list[@]} 個測試失敗。${RESET}"
             echo -e "${CYAN}🔄 正在切換至 Release 版本進行交叉比對...${RESET}"
-            run_quiet $CI_SCRIPT_NAME pull || exit 5
+            run_quiet $CI_SCRIPT_NAME pull ${BASE_REF:+"$BASE_REF"} || exit 5
         fi
         pushd "$test_dir" > /dev/null || exit 6
         make all
@@ -210,6 +210,7 @@ smart_failure_handler() {
             local status1=$?
             if [ $status1 -eq 0 ]; then
                 echo -e "${GREEN}✨ 恭喜! 所有失敗項目經重跑後均通過 (Flaky)。繼續執行後續流程。${RESET}"
+                record_flaky "${failed_list[@]}"
                 popd > /dev/null || exit 6
                 return 0
             fi
@@ -231,6 +232,9 @@ smart_failure_handler() {
 
 smart_failure_handler_ulcl() {
     local env="$1"
+    progress stage retry
+    read_failed_list
+    local env_failed=("${failed_list[@]}")
     
     for phase in 1 2; do
         if [ $phase -eq 1 ]; then
@@ -259,6 +263,7 @@ smart_failure_handler_ulcl() {
             local status1=$?
             if [ $status1 -eq 0 ] ; then
                 log "${GREEN}✨ 恭喜! $env 環境測試經重試後通過。繼續執行後續流程。${RESET}"
+                record_flaky "${env_failed[@]}"
                 CURRENT_ENV=""
                 return 0
             else
@@ -304,6 +309,34 @@ smart_failure_handler_ulcl() {
     done
 }
 
+# 讀取 logs/failures.json 的失敗測試到 failed_list
+read_failed_list() {
+    local json_content array_part
+    json_content=$(cat "$LOG_DIR/failures.json" 2>/dev/null)
+    array_part=$(echo "$json_content" | sed 's/.*"failed_tests": \[\([^]]*\)\].*/\1/')
+    if [ -z "$array_part" ]; then
+        failed_list=()
+    else
+        IFS=',' read -ra failed_list <<< "$(echo "$array_part" | tr -d '"' | tr -d ' ')"
+    fi
+}
+
+# 記錄重跑後通過的測試 (flaky)，寫入 logs/flaky.json 供 executor 讀取
+record_flaky() {
+    local name i
+    for name in "$@"; do
+        FLAKY_TESTS+=("${name%.log}")
+    done
+    mkdir -p "$LOG_DIR"
+    local json_file="$LOG_DIR/flaky.json"
+    printf '{"flaky_tests": [' > "$json_file"
+    for i in "${!FLAKY_TESTS[@]}"; do
+        if [ "$i" -ne 0 ]; then printf ',' >> "$json_file"; fi
+        printf '"%s"' "$(printf '%s' "${FLAKY_TESTS[$i]}" | sed 's/"/\\"/g')" >> "$json_file"
+    done
+    printf ']}\n' >> "$json_file"
+}
+
 run_test_command() {
     local step_name="$1"
     shift
@@ -434,8 +467,8 @@ cleanup_on_failure() {
     fi
 
     log "📋 Collecting logs..."
-    mkdir -p "$SCRIPT_DIR/logs"
-    find "$CI_TARGET_DIR" -type f -iname "*.log" -exec cp {} "$SCRIPT_DIR/logs/" \; 2>/dev/null || true
+    mkdir -p "$LOG_DIR"
+    find "$CI_TARGET_DIR" -type f -iname "*.log" -exec cp {} "$LOG_DIR/" \; 2>/dev/null || true
     getlog
     restore_and_build
     
@@ -455,6 +488,7 @@ test_all() {
     local spin_len=${#spin_chars}
     local i=0
     for test_name in "${ADDR[@]}"; do
+        progress test "$test_name"
         exec $SINGLE_TEST_CMD "$test_name" &> "$test_dir/testing_output/$test_name.log" &
         local PID=$!
         while kill -0 $PID 2>/dev/null; do
@@ -464,6 +498,7 @@ test_all() {
         done
         wait $PID
         printf "${CLEAR_LINE}"
+        progress done "$test_name"
         if [[ "$test_name" == "TestTngf" || "$test_name" == "TestNon3GPP" ]]; then
             sudo killall -9 n3iwf tngf 2>/dev/null
             sleep 2
@@ -489,6 +524,8 @@ test_all() {
 
 ulcl_test_cycle() {
     CURRENT_ENV="$1"
+    progress stage ulcl
+    progress test "ulcl:$CURRENT_ENV"
     
     echo "------------------------------------------------"
     log "▶️  Testing Environment: $CURRENT_ENV"
@@ -511,28 +548,30 @@ ulcl_test_cycle() {
 
     log "🛑 Shutting down ($CURRENT_ENV)..."
     run_quiet $CI_SCRIPT_NAME down "$CURRENT_ENV" || cleanup_on_failure
+    progress done "ulcl:$CURRENT_ENV"
     CURRENT_ENV=""
     return $status
 }
 
 # 還原代碼並重新編譯，刪有發PR的NF的image
+# release 版本編譯失敗是 CI 環境問題，以 8 結束，與 PR 的 NF 編譯失敗 (4) 區分
 restore_and_build() {
-    run_quiet $CI_SCRIPT_NAME pull || { log "Release Pull 失敗"; exit 5; }
+    run_quiet $CI_SCRIPT_NAME pull ${BASE_REF:+"$BASE_REF"} || { log "Release Pull 失敗"; exit 5; }
     for pr_entry in "${PR_LIST[@]}"; do
-        IFS=':' read -r comp id <<< "$pr_entry"
-        run_quiet $CI_SCRIPT_NAME build-nf "$comp" || { log "Build $comp 失敗"; exit 4; }
+        IFS=':' read -r comp id _ <<< "$pr_entry"
+        run_quiet $CI_SCRIPT_NAME build-nf "$comp" || { log "Build release $comp 失敗"; exit 8; }
     done
 }
 
 getlog() {
     log "📋 Collecting logs..."
-    mkdir -p "$SCRIPT_DIR/logs"
-    find "$CI_TARGET_DIR" -type f -iname "*.log" -exec cp {} "$SCRIPT_DIR/logs/" \; 2>/dev/null || true
+    mkdir -p "$LOG_DIR"
+    find "$CI_TARGET_DIR" -type f -iname "*.log" -exec cp {} "$LOG_DIR/" \; 2>/dev/null || true
 }
 # 在 logs 裡掃描是否有 'exit status 1' 的測試紀錄，並輸出 JSON
 scan_logs() {
     local filter_type="$1"
-    local log_dir="${2:-$SCRIPT_DIR/logs}"
+    local log_dir="${2:-$LOG_DIR}"
     log "🔎 Scanning $log_dir for files containing 'exit status 1'..."
     
     if [ "$filter_type" = "" ]; then
@@ -579,16 +618,36 @@ scan_logs() {
 }
 
 # 2. 解析參數
-while getopts "e:p:d:nh:r" opt; do
+while getopts "e:t:p:b:d:l:nh:r" opt; do
     case $opt in
-        e) ;;
+        e) SELECTED_ENVS+=("$OPTARG") ;;
+        t) TEST_POOL="$OPTARG" ;;
         p) PR_LIST+=("$OPTARG") ;;
+        b) BASE_REF="$OPTARG" ;;
         d) CI_TARGET_DIR="$OPTARG" ;;
+        l) LOG_DIR="$OPTARG" ;;
         n) VERBOSE=true ;; 
         r) REGRESS=true ;;
-        *) echo "Usage: $0 -p <comp:id> [-n] [-d <dir>]"; exit 7 ;;
+        *) echo "Usage: $0 -p <comp:id[:sha]> [-b <base ref>] [-t <Test1|Test2>] [-e <env>]... [-n] [-d <dir>] [-l <log dir>]"; exit 7 ;;
     esac
 done
+if [ ${#SELECTED_ENVS[@]} -gt 0 ]; then TEST_ENVS=("${SELECTED_ENVS[@]}"); fi
+CI_SCRIPT_NAME="$CI_TARGET_DIR/ci-operation.sh" # -d 可能改變目標目錄
+
+# 取消任務時 executor 對整個 process group 送出 SIGTERM：關閉 compose 環境並結束背景行程
+on_terminate() {
+    trap - TERM INT
+    log "${YELLOW}🛑 收到終止訊號，正在清理...${RESET}"
+    local jobs_pids
+    jobs_pids=$(jobs -p)
+    if [ -n "$jobs_pids" ]; then kill -TERM $jobs_pids 2>/dev/null; fi
+    if [ -n "$CURRENT_ENV" ]; then
+        $CI_SCRIPT_NAME down "$CURRENT_ENV" >/dev/null 2>&1 || true
+    fi
+    rm -f "$FAILED_LIST_FILE"
+    exit 143
+}
+trap on_terminate TERM INT
 
 # if [ ${#PR_LIST[@]} -eq 0 ]; then echo -e "⚠️  未偵測到 PR，停止執行。"; exit 0; fi
 
@@ -596,31 +655,52 @@ echo "=========================================="
 echo "🤖 CI Smart Bot (Auto-Verification)"
 echo "📂 目標目錄: $CI_TARGET_DIR"
 echo "📦 待測 PR: ${PR_LIST[*]}"
+echo "🏷️  測試基準: ${BASE_REF:-預設分支}"
+echo "🧪 測試項目: $TEST_POOL"
+echo "🌐 測試環境: ${TEST_ENVS[*]}"
 echo "=========================================="
+PLAN="$TEST_POOL"
+for env in "${TEST_ENVS[@]}"; do PLAN="${PLAN:+$PLAN|}ulcl:$env"; done
+progress plan "$PLAN"
 
 if [ ! -d "$CI_TARGET_DIR" ]; then echo -e "❌ Dir not found"; exit 1; fi
 cd "$CI_TARGET_DIR" || exit 1
 
+# 清除上一個任務的結果，pull 或 fetch 失敗提早結束時 executor 才不會讀到舊的 failures.json
+rm -fv "$LOG_DIR"/failures.json
+rm -fv "$LOG_DIR"/flaky.json
+
 # ================= 準備階段 =================
+progress stage pull
 log "🔄 1. Pulling source..."
-run_quiet $CI_SCRIPT_NAME pull || exit 5
+run_quiet $CI_SCRIPT_NAME pull ${BASE_REF:+"$BASE_REF"} || exit 5
 
 #docker builder prune -a
 
+progress stage fetch
 log "📥 2. Fetching PRs..."
 for pr_entry in "${PR_LIST[@]}"; do
-    IFS=':' read -r comp id <<< "$pr_entry"
-    log "   -> Fetching $comp #$id"
-    run_quiet $CI_SCRIPT_NAME fetch "$comp" "$id" || exit 1
+    IFS=':' read -r comp id sha <<< "$pr_entry"
+    log "   -> Fetching $comp #$id ${sha:0:7}"
+    # 有 sha 時 ci-operation.sh 會確認取出的正是該 commit；該 commit 已取不到時 (PR 被 force-push) 結束碼為 2
+    run_quiet $CI_SCRIPT_NAME fetch "$comp" "$id" $sha
+    fetch_status=$?
+    if [ $fetch_status -eq 2 ]; then
+        log "${YELLOW}⛔ $comp #$id 已取不到 ${sha:0:7}，請以 PR 目前的 head 重新加入佇列${RESET}"
+        exit 9
+    elif [ $fetch_status -ne 0 ]; then
+        log "Fetch $comp #$id 失敗"
+        exit 1
+    fi
 done
 
 # ================= TestAll 階段 (含機器人邏輯) =================
 
 log "🧹 Cleaning up old logs..."
-rm -fv "$SCRIPT_DIR/logs"/*.log
-rm -fv "$SCRIPT_DIR/logs"/failures.json
+rm -fv "$LOG_DIR"/*.log
 rm -fv "$CI_TARGET_DIR/test"/*.log
 rm -fv "$CI_TARGET_DIR/test"/failures.json
+progress stage test_all
 log "🧪 3. Pre-build Tests (testAll)..."
 run_test_command "testAll" $CI_SCRIPT_NAME testAll
 final_status=$?
@@ -634,12 +714,13 @@ else
     log "${RED}⛔ Pre-build Tests Failed.${RESET}"
 fi
 
+progress stage build
 log "🏗️ 5. Building..."
 #run_quiet $CI_SCRIPT_NAME build || { log "Build 失敗"; exit 4; }
 
 #build有發PR的NF的image
 for pr_entry in "${PR_LIST[@]}"; do
-    IFS=':' read -r comp id <<< "$pr_entry"
+    IFS=':' read -r comp id _ <<< "$pr_entry"
     run_quiet $CI_SCRIPT_NAME build-nf "$comp" || { log "Build $comp 失敗"; exit 4; }
 done
 
@@ -652,9 +733,11 @@ for ENV in "${TEST_ENVS[@]}"; do
     ulcl_test_cycle "$ENV"
 done
 
+progress stage restore
 restore_and_build
 
 # ================= 完成階段 =================
+progress stage collect
 #取得ci-test 內的logs
 getlog
 scan_logs